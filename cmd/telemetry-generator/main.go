@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/inventory"
 	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/traces"
 	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/metrics"
 	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/logs"
@@ -18,6 +20,7 @@ func main() {
 	configPath := flag.String("config", "", "Path to configuration file (required)")
 	outputDir := flag.String("output-dir", "", "Output directory (overrides config)")
 	jsonOutput := flag.Bool("json", false, "Generate JSON output alongside protobuf for debugging")
+	seed := flag.Int64("seed", 0, "Random seed for reproducible output (overrides config; 0 = use config)")
 	flag.Parse()
 
 	if *configPath == "" {
@@ -38,6 +41,13 @@ func main() {
 		cfg.Output.Directory = *outputDir
 	}
 
+	// Override seed if specified, then seed the shared random source before
+	// any generator is constructed so the whole run is reproducible.
+	if *seed != 0 {
+		cfg.Seed = *seed
+	}
+	common.SetSeed(cfg.Seed)
+
 	fmt.Println("═══════════════════════════════════════════════════════════")
 	fmt.Println("  Telemetry Generator")
 	fmt.Println("═══════════════════════════════════════════════════════════")
@@ -45,6 +55,9 @@ func main() {
 	fmt.Printf("Output directory: %s\n", cfg.Output.Directory)
 	fmt.Printf("Output prefix: %s\n", cfg.Output.Prefix)
 	fmt.Printf("JSON output: %v\n", *jsonOutput)
+	if cfg.Seed != 0 {
+		fmt.Printf("Random seed: %d\n", cfg.Seed)
+	}
 
 	// Show estimated sender memory usage
 	estimatedMemory := cfg.EstimateMemoryUsage()
@@ -56,14 +69,52 @@ func main() {
 	fmt.Println()
 
 	startTime := time.Now()
+	if err := generate(cfg, *jsonOutput); err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(1)
+	}
+
+	elapsed := time.Since(startTime)
+	fmt.Println("═══════════════════════════════════════════════════════════")
+	fmt.Printf("✓ Generation complete in %s\n", elapsed.Round(time.Millisecond))
+	fmt.Println("═══════════════════════════════════════════════════════════")
+}
+
+// generate builds the inventory, writes every enabled signal and the metadata
+// file into cfg.Output.Directory. The shared random source must already be
+// seeded.
+func generate(cfg *config.GeneratorConfig, jsonOutput bool) error {
+	startTime := time.Now()
+	var err error
+
+	// Build the entity inventory every signal describes, then register the
+	// trace and log services in it before any signal is generated
+	inv := inventory.New(cfg.Inventory)
+
+	var traceGen *traces.Generator
+	if cfg.Traces.Count > 0 {
+		traceGen, err = traces.NewGenerator(&cfg.Traces, cfg.Output.Directory, cfg.Output.Prefix)
+		if err != nil {
+			return fmt.Errorf("generating traces: %w", err)
+		}
+		traceGen.UseInventory(inv)
+	}
+	metricGen := metrics.NewGenerator(&cfg.Metrics, cfg.Output.Directory, cfg.Output.Prefix)
+	metricGen.UseInventory(inv)
+	logGen := logs.NewGenerator(&cfg.Logs, cfg.Output.Directory, cfg.Output.Prefix)
+	if cfg.Logs.Count > 0 {
+		logGen.UseInventory(inv)
+	}
+
+	pods := len(inv.Entities(inventory.LevelPod))
+	hosts := len(inv.Entities(inventory.LevelHost))
+	fmt.Printf("Inventory: %d cluster(s), %d pods, %d hosts\n\n", len(inv.Clusters), pods, hosts)
 
 	// Generate traces
 	if cfg.Traces.Count > 0 {
 		fmt.Println("───────────────────────────────────────────────────────────")
-		traceGen := traces.NewGenerator(&cfg.Traces, cfg.Output.Directory, cfg.Output.Prefix)
-		if err := traceGen.Generate(*jsonOutput); err != nil {
-			fmt.Fprintf(os.Stderr, "Error generating traces: %v\n", err)
-			os.Exit(1)
+		if err := traceGen.Generate(jsonOutput); err != nil {
+			return fmt.Errorf("generating traces: %w", err)
 		}
 		fmt.Println()
 	}
@@ -71,10 +122,8 @@ func main() {
 	// Generate metrics
 	if cfg.Metrics.MetricCount > 0 {
 		fmt.Println("───────────────────────────────────────────────────────────")
-		metricGen := metrics.NewGenerator(&cfg.Metrics, cfg.Output.Directory, cfg.Output.Prefix)
-		if err := metricGen.Generate(*jsonOutput); err != nil {
-			fmt.Fprintf(os.Stderr, "Error generating metrics: %v\n", err)
-			os.Exit(1)
+		if err := metricGen.Generate(jsonOutput); err != nil {
+			return fmt.Errorf("generating metrics: %w", err)
 		}
 		fmt.Println()
 	}
@@ -82,10 +131,8 @@ func main() {
 	// Generate logs
 	if cfg.Logs.Count > 0 {
 		fmt.Println("───────────────────────────────────────────────────────────")
-		logGen := logs.NewGenerator(&cfg.Logs, cfg.Output.Directory, cfg.Output.Prefix)
-		if err := logGen.Generate(*jsonOutput); err != nil {
-			fmt.Fprintf(os.Stderr, "Error generating logs: %v\n", err)
-			os.Exit(1)
+		if err := logGen.Generate(jsonOutput); err != nil {
+			return fmt.Errorf("generating logs: %w", err)
 		}
 		fmt.Println()
 	}
@@ -95,16 +142,15 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Warning: failed to write metadata: %v\n", err)
 	}
 
-	elapsed := time.Since(startTime)
-	fmt.Println("═══════════════════════════════════════════════════════════")
-	fmt.Printf("✓ Generation complete in %s\n", elapsed.Round(time.Millisecond))
-	fmt.Println("═══════════════════════════════════════════════════════════")
+	return nil
 }
 
 // Metadata represents generation metadata
 type Metadata struct {
-	GeneratedAt  string                    `yaml:"generated_at"`
-	Duration     string                    `yaml:"duration"`
+	// GeneratedAt and Duration are left out of seeded runs, so the
+	// metadata is as reproducible as the data files.
+	GeneratedAt  string                    `yaml:"generated_at,omitempty"`
+	Duration     string                    `yaml:"duration,omitempty"`
 	Configuration map[string]interface{}   `yaml:"configuration"`
 	Files        map[string]string         `yaml:"files"`
 }
@@ -124,9 +170,8 @@ func writeMetadata(cfg *config.GeneratorConfig, outputDir, prefix string, startT
 	}
 
 	metadata := Metadata{
-		GeneratedAt: startTime.Format(time.RFC3339),
-		Duration:    time.Since(startTime).Round(time.Millisecond).String(),
 		Configuration: map[string]interface{}{
+			"seed": cfg.Seed,
 			"traces": map[string]interface{}{
 				"count":         cfg.Traces.Count,
 				"avg_spans":     cfg.Traces.Spans.AvgPerTrace,
@@ -142,6 +187,10 @@ func writeMetadata(cfg *config.GeneratorConfig, outputDir, prefix string, startT
 		},
 		Files: files,
 	}
+	if cfg.Seed == 0 {
+		metadata.GeneratedAt = startTime.Format(time.RFC3339)
+		metadata.Duration = time.Since(startTime).Round(time.Millisecond).String()
+	}
 
 	data, err := yaml.Marshal(metadata)
 	if err != nil {
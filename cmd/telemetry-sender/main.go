@@ -53,6 +53,19 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Error loading templates: %v\n", err)
 		os.Exit(1)
 	}
+
+	// Rewrite per-replica identity once, before any replay
+	identity, err := transformer.NewIdentityRewriter(cfg.Identity)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing identity rewriting: %v\n", err)
+		os.Exit(1)
+	}
+	if identity != nil {
+		identity.RewriteTraces(templates.Traces)
+		identity.RewriteMetrics(templates.Metrics)
+		identity.RewriteLogs(templates.Logs)
+		fmt.Printf("  Rewrote identity attributes for replica %q\n", identity.Replica())
+	}
 	fmt.Println()
 
 	// Initialize exporters
@@ -93,6 +106,12 @@ func main() {
 	// Initialize transformers
 	timestampInjector := transformer.NewTimestampInjector(cfg.Timestamps.JitterMs, cfg.Timestamps.BackdateMs)
 	idRegenerator := transformer.NewIDRegenerator()
+	cardinality := transformer.NewCardinalityInjector(cfg.Cardinality.Attributes)
+	anomalies := transformer.NewClockAnomalies(cfg.Timestamps.Anomalies)
+	series := transformer.NewSeriesTracker(cfg.Metrics.Cumulative)
+	patterns := transformer.NewValuePatterns(cfg.Metrics.Patterns)
+	churn := transformer.NewSeriesChurn(cfg.Metrics.Churn)
+	series.UseChurn(churn)
 
 	// Initialize rate limiter
 	rateLimiter := ratelimit.NewLimiter(cfg.Sending.RateLimit.EventsPerSecond)
@@ -113,6 +132,25 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Backfill ends the run once its window is covered
+	backfillWindow, err := cfg.GetBackfillWindow()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing backfill window: %v\n", err)
+		os.Exit(1)
+	}
+	if backfillWindow > 0 {
+		end := time.Now().Add(-time.Duration(cfg.Timestamps.BackdateMs) * time.Millisecond)
+		clock := transformer.NewBackfillClock(backfillWindow, cfg.Timestamps.Backfill.Speed, end)
+		timestampInjector.SetBackfill(clock)
+
+		start, _ := clock.Window()
+		fmt.Printf("\nBackfilling %s → %s at %gx real time\n",
+			start.Format(time.RFC3339), end.Format(time.RFC3339), cfg.Timestamps.Backfill.Speed)
+		if remaining := clock.Remaining(); duration == 0 || remaining < duration {
+			duration = remaining
+		}
+	}
+
 	if duration > 0 {
 		ctx, cancel = context.WithTimeout(ctx, duration)
 		defer cancel()
@@ -146,6 +184,11 @@ func main() {
 		logsExporter,
 		timestampInjector,
 		idRegenerator,
+		cardinality,
+		anomalies,
+		series,
+		patterns,
+		churn,
 		rateLimiter,
 		reporter,
 		cfg.Sending.BatchSize.Traces,
@@ -166,8 +209,16 @@ func main() {
 
 	// Show batch information
 	if templates.Traces != nil && len(templates.Traces.ResourceSpans) > 0 {
-		numBatches := (len(templates.Traces.ResourceSpans) + cfg.Sending.BatchSize.Traces - 1) / cfg.Sending.BatchSize.Traces
-		fmt.Printf("  Trace batches: %d (batch size: %d resource spans)\n", numBatches, cfg.Sending.BatchSize.Traces)
+		numBatches := (workers.TraceCount(templates.Traces.ResourceSpans) + cfg.Sending.BatchSize.Traces - 1) / cfg.Sending.BatchSize.Traces
+		fmt.Printf("  Trace batches: %d (batch size: %d traces)\n", numBatches, cfg.Sending.BatchSize.Traces)
+	}
+	if templates.Metrics != nil && len(templates.Metrics.ResourceMetrics) > 0 {
+		numBatches := workers.MetricBatchCount(templates.Metrics, cfg.Sending.BatchSize.Metrics)
+		fmt.Printf("  Metric batches: %d (batch size: %d data points)\n", numBatches, cfg.Sending.BatchSize.Metrics)
+	}
+	if templates.Logs != nil && len(templates.Logs.ResourceLogs) > 0 {
+		numBatches := workers.LogBatchCount(templates.Logs, cfg.Sending.BatchSize.Logs)
+		fmt.Printf("  Log batches: %d (batch size: %d records)\n", numBatches, cfg.Sending.BatchSize.Logs)
 	}
 
 	fmt.Println("Sending telemetry...")
@@ -1,20 +1,109 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
 	"maps"
 	"os"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 // GeneratorConfig represents the configuration for the telemetry generator
 type GeneratorConfig struct {
+	// Seed makes generation deterministic: the same config and seed produce
+	// byte-for-byte identical output files. 0 (the default) uses a time-based
+	// seed, so every run produces a different dataset.
+	Seed int64 `yaml:"seed"`
+
 	Output  OutputConfig  `yaml:"output"`
 	Traces  TracesConfig  `yaml:"traces"`
 	Metrics MetricsConfig `yaml:"metrics"`
 	Logs    LogsConfig    `yaml:"logs"`
 	Limits  LimitsConfig  `yaml:"limits"`
+
+	Inventory InventoryConfig `yaml:"inventory"`
+}
+
+// InventoryConfig sizes the entity inventory shared by every signal:
+// clusters of nodes, namespaces, deployments, pods and containers, plus
+// standalone hosts. Each cluster and host sits in a cloud region and
+// availability zone. Metric dimensions, trace resources and log resources
+// all describe entities from it, so a pod always runs on the same node in one
+// cluster, and the services in traces and logs are deployments with pods.
+type InventoryConfig struct {
+	Clusters        int `yaml:"clusters"`          // default 1
+	NodesPerCluster int `yaml:"nodes_per_cluster"` // default 5
+
+	// Namespaces in every cluster (default production, staging,
+	// monitoring). Trace services also get namespaces of their own.
+	Namespaces []string `yaml:"namespaces"`
+	// Deployments in each namespace besides the trace services' (default 2).
+	Deployments int `yaml:"deployments"`
+	// Replicas is the number of pods per deployment (default 3).
+	Replicas int `yaml:"replicas"`
+	// Containers in each pod (default app, proxy).
+	Containers []string `yaml:"containers"`
+
+	// Hosts outside any cluster, such as database and cache VMs (default 3).
+	Hosts int `yaml:"hosts"`
+}
+
+// ApplyDefaults fills in unset inventory sizes.
+func (c *InventoryConfig) ApplyDefaults() {
+	if c.Clusters == 0 {
+		c.Clusters = 1
+	}
+	if c.NodesPerCluster == 0 {
+		c.NodesPerCluster = 5
+	}
+	if len(c.Namespaces) == 0 {
+		c.Namespaces = []string{"production", "staging", "monitoring"}
+	}
+	if c.Deployments == 0 {
+		c.Deployments = 2
+	}
+	if c.Replicas == 0 {
+		c.Replicas = 3
+	}
+	if len(c.Containers) == 0 {
+		c.Containers = []string{"app", "proxy"}
+	}
+	if c.Hosts == 0 {
+		c.Hosts = 3
+	}
+}
+
+// validate checks inventory sizes and names.
+func (c InventoryConfig) validate() error {
+	for name, n := range map[string]int{
+		"clusters":          c.Clusters,
+		"nodes_per_cluster": c.NodesPerCluster,
+		"deployments":       c.Deployments,
+		"replicas":          c.Replicas,
+		"hosts":             c.Hosts,
+	} {
+		if n < 0 {
+			return fmt.Errorf("inventory.%s must be non-negative", name)
+		}
+	}
+	for field, names := range map[string][]string{"namespaces": c.Namespaces, "containers": c.Containers} {
+		seen := make(map[string]bool, len(names))
+		for _, name := range names {
+			if name == "" {
+				return fmt.Errorf("inventory.%s: names must be non-empty", field)
+			}
+			if seen[name] {
+				return fmt.Errorf("inventory.%s: duplicate name %q", field, name)
+			}
+			seen[name] = true
+		}
+	}
+	return nil
 }
 
 // LimitsConfig overrides the safety cap on estimated in-memory dataset size.
@@ -43,6 +132,251 @@ type TracesConfig struct {
 	Services         ServicesConfig         `yaml:"services"`
 	CustomAttributes CustomAttributesConfig `yaml:"custom_attributes"`
 	Root             RootConfig             `yaml:"root"`
+	Model            ModelConfig            `yaml:"model"`
+	Latency          LatencyConfig          `yaml:"latency"`
+	Concurrency      ConcurrencyConfig      `yaml:"concurrency"`
+	Errors           ErrorsConfig           `yaml:"errors"`
+	Events           SpanEventsConfig       `yaml:"events"`
+	Links            SpanLinksConfig        `yaml:"links"`
+	Messaging        MessagingConfig        `yaml:"messaging"`
+	RemoteCalls      RemoteCallsConfig      `yaml:"remote_calls"`
+	Resources        TraceResourcesConfig   `yaml:"resources"`
+}
+
+// TraceResourcesConfig controls the OTLP resources traces are written with.
+// By default each trace is one ResourceSpans whose resource is the root
+// service. With PerService, each trace is split into one ResourceSpans per
+// service, as the services' SDKs would export it, each with stable
+// service.version, service.instance.id, host.name, k8s.* and
+// deployment.environment (Environment, default "production") attributes.
+type TraceResourcesConfig struct {
+	PerService  bool   `yaml:"per_service"`
+	Environment string `yaml:"environment"`
+}
+
+// RemoteCallsConfig controls how calls between services are represented.
+// When Enabled, every cross-service call is a CLIENT span in the caller with
+// the callee's SERVER span under it, carrying peer attributes (server.address,
+// net.peer.name, peer.service) and, for gRPC, rpc.grpc.status_code. Disabled
+// (the default), a call is a single span owned by the callee.
+type RemoteCallsConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// MessagingConfig shapes calls made through a message broker. Each messaging
+// hop is a PRODUCER span in the caller and a CONSUMER span in the callee that
+// starts QueueLag after the message is published. Graph calls with protocol
+// "messaging" are always messaging hops; Percentage makes that share of
+// cross-service calls in the generated (non-graph) topology messaging hops
+// too. The zero value adds none to the generated topology.
+type MessagingConfig struct {
+	Percentage float64 `yaml:"percentage"`
+
+	// System is the messaging.system (default: kafka) and Partitions the
+	// number of partitions per topic (default 12).
+	System     string `yaml:"system"`
+	Partitions int    `yaml:"partitions"`
+
+	// QueueLag is the time between publish and the start of processing
+	// (default: uniform 1-50ms).
+	QueueLag LatencyDistribution `yaml:"queue_lag"`
+
+	// SeparateTracePercentage is the percent of consumers that start a new
+	// trace linked to the producer span instead of continuing its trace.
+	SeparateTracePercentage float64 `yaml:"separate_trace_percentage"`
+}
+
+// SpanEventsConfig adds span events to a percentage of spans. Each such span
+// gets Min..Max events (default 1..3) named from Names (default: a built-in
+// list), each with Attributes attributes (default 2). ValueBytes > 0 makes
+// every event attribute value that many bytes long, for size stress. The
+// zero value adds no events.
+type SpanEventsConfig struct {
+	Percentage float64  `yaml:"percentage"`
+	Min        int      `yaml:"min"`
+	Max        int      `yaml:"max"`
+	Names      []string `yaml:"names"`
+	Attributes int      `yaml:"attributes"`
+	ValueBytes int      `yaml:"value_bytes"`
+}
+
+// SpanLinksConfig adds span links. Link targets in other traces are rewritten
+// by the sender along with the target trace's own IDs, so they keep pointing
+// at spans it actually sends. The zero value adds no links.
+type SpanLinksConfig struct {
+	// CrossTracePercentage is the percent of traces whose root links to a
+	// span in an earlier trace of the dataset (follows-from).
+	CrossTracePercentage float64 `yaml:"cross_trace_percentage"`
+
+	// InTracePercentage is the percent of non-root spans linking to another
+	// span in the same trace.
+	InTracePercentage float64 `yaml:"in_trace_percentage"`
+
+	// BatchConsumer makes a percentage of traces batch consumers, whose root
+	// links to spans in many earlier (producer) traces.
+	BatchConsumer BatchConsumerConfig `yaml:"batch_consumer"`
+}
+
+// BatchConsumerConfig links a batch consumer's root to MinLinks..MaxLinks
+// (default 5..20) producer traces.
+type BatchConsumerConfig struct {
+	Percentage float64 `yaml:"percentage"`
+	MinLinks   int     `yaml:"min_links"`
+	MaxLinks   int     `yaml:"max_links"`
+}
+
+// ErrorsConfig injects failed spans: STATUS_CODE_ERROR with a message, an
+// error.type attribute and an OpenTelemetry "exception" span event. The most
+// specific rate matching a span wins (operations, then services, then
+// Percentage). A failed span fails its parent with PropagationPercentage
+// probability, so errors can climb the call chain to the root. The zero value
+// injects nothing; spans whose generated HTTP status is 5xx are always
+// marked as errors.
+type ErrorsConfig struct {
+	Percentage            float64            `yaml:"percentage"`
+	Services              map[string]float64 `yaml:"services"`
+	Operations            []OperationErrors  `yaml:"operations"`
+	PropagationPercentage float64            `yaml:"propagation_percentage"`
+}
+
+// OperationErrors sets the error percentage for an operation (span name). An
+// empty Service matches the operation in every service.
+type OperationErrors struct {
+	Service    string  `yaml:"service"`
+	Operation  string  `yaml:"operation"`
+	Percentage float64 `yaml:"percentage"`
+}
+
+// ConcurrencyConfig controls how a parent's child spans overlap in time. Each
+// parent with two or more children runs them in parallel, through a bounded
+// worker pool, or (otherwise) one after another; parent durations follow the
+// critical path. The zero value keeps every parent's children sequential.
+type ConcurrencyConfig struct {
+	// ParallelPercentage is the percent of parents whose children all start
+	// together.
+	ParallelPercentage float64 `yaml:"parallel_percentage"`
+
+	// WorkerPool runs a percentage of parents' children through a fixed
+	// number of concurrent workers.
+	WorkerPool WorkerPoolConfig `yaml:"worker_pool"`
+
+	// AsyncPercentage is the percent of child spans that are fire-and-forget:
+	// the parent doesn't wait for them, so they may end after it does.
+	AsyncPercentage float64 `yaml:"async_percentage"`
+}
+
+// WorkerPoolConfig is the bounded fan-out pattern: children are queued onto
+// MinWorkers..MaxWorkers workers (default 2..4), each taking the next child
+// as soon as it is free.
+type WorkerPoolConfig struct {
+	Percentage float64 `yaml:"percentage"`
+	MinWorkers int     `yaml:"min_workers"`
+	MaxWorkers int     `yaml:"max_workers"`
+}
+
+// LatencyConfig shapes span durations. Each span's own latency is drawn from
+// the most specific distribution that matches it (operations, then services,
+// then default); parents are still stretched to cover their children. The zero
+// value keeps the historic behavior: leaves uniform 1-100ms, parents the sum
+// of their children plus 0.5-5ms.
+type LatencyConfig struct {
+	Default      LatencyDistribution            `yaml:"default"`
+	Services     map[string]LatencyDistribution `yaml:"services"`
+	Operations   []OperationLatency             `yaml:"operations"`
+	SlowServices []SlowServiceConfig            `yaml:"slow_services"`
+	Outliers     OutlierConfig                  `yaml:"outliers"`
+}
+
+// LatencyDistribution describes one latency distribution, in milliseconds.
+// Which fields apply depends on Distribution:
+//
+//   - uniform: min_ms..max_ms
+//   - lognormal: median_ms and p99_ms
+//   - pareto: min_ms (scale) and alpha (shape); a heavy tail, capped by max_ms
+//   - bimodal: a lognormal fast mode (median_ms, p99_ms) plus a slow mode
+//     centred on slow_median_ms for slow_percentage of spans
+//   - percentiles: fixed percentile points, e.g. {p50: 12, p99: 250}, linearly
+//     interpolated from min_ms (p0) to the highest percentile given
+//
+// max_ms, when set, caps any distribution. An empty Distribution means "not
+// configured" and falls through to the next, less specific level.
+type LatencyDistribution struct {
+	Distribution   string             `yaml:"distribution"`
+	MinMs          float64            `yaml:"min_ms"`
+	MaxMs          float64            `yaml:"max_ms"`
+	MedianMs       float64            `yaml:"median_ms"`
+	P99Ms          float64            `yaml:"p99_ms"`
+	Alpha          float64            `yaml:"alpha"`
+	SlowMedianMs   float64            `yaml:"slow_median_ms"`
+	SlowPercentage float64            `yaml:"slow_percentage"`
+	Percentiles    map[string]float64 `yaml:"percentiles"`
+}
+
+// Configured reports whether the distribution is set.
+func (d LatencyDistribution) Configured() bool {
+	return d.Distribution != ""
+}
+
+// OperationLatency sets the distribution for an operation (span name). An
+// empty Service matches the operation in every service.
+type OperationLatency struct {
+	Service             string `yaml:"service"`
+	Operation           string `yaml:"operation"`
+	LatencyDistribution `yaml:",inline"`
+}
+
+// SlowServiceConfig slows Percentage of a service's spans by Factor, e.g. to
+// simulate a degraded dependency. The slowdown propagates to callers because
+// parents always cover their children.
+type SlowServiceConfig struct {
+	Service    string  `yaml:"service"`
+	Percentage float64 `yaml:"percentage"`
+	Factor     float64 `yaml:"factor"`
+}
+
+// OutlierConfig multiplies Percentage of all span latencies by a random factor
+// in MinFactor..MaxFactor (default 10..50) to produce a long tail.
+type OutlierConfig struct {
+	Percentage float64 `yaml:"percentage"`
+	MinFactor  float64 `yaml:"min_factor"`
+	MaxFactor  float64 `yaml:"max_factor"`
+}
+
+// ParsePercentileKey parses a latency percentile key such as "p50" or "p99.9".
+func ParsePercentileKey(key string) (float64, error) {
+	if !strings.HasPrefix(key, "p") {
+		return 0, fmt.Errorf("percentile key %q must look like p50 or p99.9", key)
+	}
+	p, err := strconv.ParseFloat(key[1:], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("percentile key %q must look like p50 or p99.9", key)
+	}
+	return p, nil
+}
+
+// ModelConfig points the trace generator at a file of captured real traces.
+// The generator learns the service call graph, span-count and depth
+// distributions, per-operation latencies and attribute cardinalities from it
+// and samples synthetic traces from that model instead of the configured
+// topology. Attribute values are replaced with synthetic ones, so the output
+// is anonymized. An empty Source (the default) keeps the synthetic topology.
+type ModelConfig struct {
+	// Source is an OTLP trace export: binary protobuf (ExportTraceServiceRequest
+	// or TracesData) or, for a .json extension, OTLP/JSON.
+	Source string `yaml:"source"`
+
+	// MaxSpans caps the span count of a sampled trace. 0 means the largest
+	// trace observed in the source.
+	MaxSpans int `yaml:"max_spans"`
+
+	// MaxDepth caps the depth of a sampled trace. 0 means the deepest trace
+	// observed in the source.
+	MaxDepth int `yaml:"max_depth"`
+}
+
+// ModelEnabled reports whether traces are sampled from a learned model.
+func (m ModelConfig) ModelEnabled() bool {
+	return m.Source != ""
 }
 
 // RootConfig controls "missing/late root" trace shapes used to stress a
@@ -93,11 +427,63 @@ type ServicesConfig struct {
 	Namespaces           []string          `yaml:"namespaces"`
 	NamespaceAssignments map[string]string `yaml:"namespace_assignments"`
 
+	// Graph declares the service call graph explicitly. When set it replaces
+	// the default wiring (each service calls 1-3 services after it in Names);
+	// services without an entry serve random operations and call nothing.
+	Graph []ServiceGraphNode `yaml:"graph"`
+
 	// ResolvedNamespaces is the final service-name → namespace map used during
 	// trace generation. Populated by ApplyDefaults; not unmarshaled from YAML.
 	ResolvedNamespaces map[string]string `yaml:"-"`
 }
 
+// ServiceGraphNode describes one service in traces.services.graph: the named
+// operations it serves and the downstream services it calls.
+type ServiceGraphNode struct {
+	Service    string           `yaml:"service"`
+	Operations []GraphOperation `yaml:"operations"`
+	Calls      []GraphEdge      `yaml:"calls"`
+}
+
+// GraphOperation is a named operation served by a graph service. Root spans
+// and incoming calls pick among a service's operations by Weight.
+type GraphOperation struct {
+	Name string `yaml:"name"`
+	// Type is one of http (default), grpc, messaging, db or internal. Incoming
+	// calls prefer operations whose type matches the edge protocol.
+	Type string `yaml:"type"`
+	// Weight is the relative selection weight (default 1).
+	Weight int `yaml:"weight"`
+
+	// HTTP operations: method defaults to GET, path to the operation name.
+	Method string `yaml:"method"`
+	Path   string `yaml:"path"`
+
+	// DB operations: system defaults to postgresql, statement to a random
+	// statement for that system.
+	DBSystem  string `yaml:"db_system"`
+	Statement string `yaml:"statement"`
+}
+
+// GraphEdge is a downstream call from a graph service to Service.
+type GraphEdge struct {
+	Service string `yaml:"service"`
+	// Probability that a span of the calling service makes this call, in
+	// [0, 1]. Omitted means always; 0 disables the edge.
+	Probability *float64 `yaml:"probability"`
+	// FanOut is how many calls are made when the edge fires (default 1..1).
+	FanOut FanOutConfig `yaml:"fan_out"`
+	// Protocol is one of http (default), grpc, messaging or db.
+	Protocol string `yaml:"protocol"`
+}
+
+// FanOutConfig is an inclusive [Min, Max] call count for a graph edge. Zero
+// values default to 1.
+type FanOutConfig struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
+
 // IngressConfig configures ingress service(s)
 type IngressConfig struct {
 	// Single selects one named entry point (Service). When false, every
@@ -132,6 +518,48 @@ type CustomAttributesConfig struct {
 	// keys create per-span column pressure in addition to raw byte size.
 	// Defaults to "custom.fat" when fat mode is enabled.
 	KeyPrefix string `yaml:"key_prefix"`
+
+	// Declared lists explicitly configured attributes, added on top of the
+	// legacy or fat attributes.
+	Declared []DeclaredAttribute `yaml:"declared"`
+}
+
+// DeclaredAttribute is an explicitly configured span attribute: its key, how
+// its values are generated, and which spans get it.
+type DeclaredAttribute struct {
+	Key string `yaml:"key"`
+
+	// Generator is enum, int_range, float_range, bool, uuid, pool, pattern,
+	// url or email.
+	Generator string `yaml:"generator"`
+
+	// Values and Weights (optional) are the enum values, parsed as Type:
+	// string (default), int, float or bool.
+	Values  []string `yaml:"values"`
+	Weights []int    `yaml:"weights"`
+	Type    string   `yaml:"type"`
+
+	// Min and Max bound int_range and float_range.
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+
+	// Prefix names pool values: <prefix>0 .. <prefix><cardinality-1>.
+	Prefix string `yaml:"prefix"`
+
+	// Pattern is a regular expression generated values match.
+	Pattern string `yaml:"pattern"`
+
+	// Cardinality caps the number of distinct values (0 = uncapped; required
+	// for pool). Enum and bool values are never capped.
+	Cardinality int64 `yaml:"cardinality"`
+
+	// Percentage of matching spans that get the attribute (0 = all).
+	Percentage float64 `yaml:"percentage"`
+
+	// Services and Operations (span names) restrict the attribute to those
+	// spans; empty matches every span.
+	Services   []string `yaml:"services"`
+	Operations []string `yaml:"operations"`
 }
 
 // FatSpansEnabled reports whether deterministic fat attributes should be
@@ -144,6 +572,142 @@ func (c CustomAttributesConfig) FatSpansEnabled() bool {
 type MetricsConfig struct {
 	MetricCount         int                      `yaml:"metric_count"`
 	TimeSeriesPerMetric TimeSeriesPerMetricConfig `yaml:"timeseries_per_metric"`
+	Temporality         TemporalityConfig         `yaml:"temporality"`
+	Histograms          HistogramsConfig          `yaml:"histograms"`
+
+	// DefinitionsFile is a YAML file of custom metric definitions, generated
+	// ahead of any built-in metrics.
+	DefinitionsFile string           `yaml:"definitions_file"`
+	Categories      CategoriesConfig `yaml:"categories"`
+
+	Synthetic SyntheticMetricsConfig `yaml:"synthetic"`
+	Streaming StreamingConfig        `yaml:"streaming"`
+	Resources MetricResourcesConfig  `yaml:"resources"`
+}
+
+// MetricResourcesConfig controls the OTLP resources metrics are written with.
+// By default every series is under one resource whose service.name is
+// telemetry-generator, with host and pod identity as data point attributes.
+// With PerEntity, series are grouped into one ResourceMetrics per inventory
+// entity they describe, as each host's or pod's collector would export them:
+// the entity's attributes (with its parents', e.g. a pod's node and cluster)
+// become resource attributes and leave the data points, which keep only
+// their other dimensions. Series that describe no entity stay under the
+// generator's resource.
+type MetricResourcesConfig struct {
+	PerEntity bool `yaml:"per_entity"`
+}
+
+// SyntheticMetricsConfig fills metric_count beyond the selected metrics with
+// synthetic ones: copies of the selected definitions, cycled in order, named
+// <prefix>.<n>.<name>. Without it metric_count is capped at the number of
+// metrics available.
+type SyntheticMetricsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Prefix names synthetic metrics (default "synthetic").
+	Prefix string `yaml:"prefix"`
+}
+
+// StreamingConfig writes the metrics template in chunks as it is generated,
+// instead of building the whole template in memory first. Chunks are
+// ResourceMetrics appended to one protobuf file, which still reads as a
+// single ExportMetricsServiceRequest; a metric with more series than a chunk
+// holds is split across chunks.
+type StreamingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// ChunkDataPoints is the number of data points per chunk (default
+	// 100000).
+	ChunkDataPoints int `yaml:"chunk_data_points"`
+}
+
+// CategoriesConfig picks built-in metric groups by MetricCategories key.
+// Without it (and without a definitions file) metric_count metrics are
+// sampled evenly across the whole catalogue; with it, every metric of the
+// chosen groups is generated in catalogue order, up to metric_count.
+type CategoriesConfig struct {
+	// Include lists the groups to use; empty means all of them, or none
+	// when a definitions file is set.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// Selected returns the chosen built-in groups in catalogue order.
+func (m MetricsConfig) Selected() []string {
+	var groups []string
+	for _, category := range MetricCategories {
+		if len(m.Categories.Include) == 0 && m.DefinitionsFile != "" {
+			break
+		}
+		if len(m.Categories.Include) > 0 && !slices.Contains(m.Categories.Include, category) {
+			continue
+		}
+		if slices.Contains(m.Categories.Exclude, category) {
+			continue
+		}
+		groups = append(groups, category)
+	}
+	return groups
+}
+
+// SamplesCatalogue reports whether metrics are sampled evenly across the
+// whole built-in catalogue, as when neither categories nor a definitions
+// file are configured.
+func (m MetricsConfig) SamplesCatalogue() bool {
+	return m.DefinitionsFile == "" && len(m.Categories.Include) == 0 && len(m.Categories.Exclude) == 0
+}
+
+// HistogramsConfig controls how the catalogue's histogram metrics are
+// encoded.
+type HistogramsConfig struct {
+	// Type is "explicit" (default), "exponential" or "summary".
+	Type string `yaml:"type"`
+	// Buckets replaces the default explicit bucket bounds; must ascend.
+	Buckets []float64 `yaml:"buckets"`
+	// Scale is the exponential histogram scale (-10..20); unset picks the
+	// largest scale whose buckets cover the metric's value range.
+	Scale *int32 `yaml:"scale"`
+	// NegativeBuckets also fills buckets for negative values.
+	NegativeBuckets bool `yaml:"negative_buckets"`
+	// Quantiles reported by summaries (default 0.5, 0.9, 0.95, 0.99).
+	Quantiles []float64 `yaml:"quantiles"`
+}
+
+// MetricCategories are the built-in metric groups, in catalogue order.
+var MetricCategories = []string{
+	"host_metrics",
+	"k8s_cluster",
+	"k8s_node",
+	"k8s_pod",
+	"k8s_container",
+	"jvm_metrics",
+	"http_metrics",
+	"application_metrics",
+	"database_metrics",
+	"rpc_metrics",
+	"runtime_metrics",
+	"messaging_metrics",
+	"otelcol_metrics",
+	"aspnet_metrics",
+	"aws_metrics",
+	"v8_metrics",
+	"cache_metrics",
+	"browser_metrics",
+}
+
+// TemporalityConfig picks the aggregation temporality of sums and
+// histograms: "cumulative" or "delta". Categories (keys of
+// MetricCategories) override Default, which is cumulative when empty.
+type TemporalityConfig struct {
+	Default    string            `yaml:"default"`
+	Categories map[string]string `yaml:"categories"`
+}
+
+// IsDelta reports whether metrics in category use delta temporality.
+func (t TemporalityConfig) IsDelta(category string) bool {
+	if v, ok := t.Categories[category]; ok {
+		return v == "delta"
+	}
+	return t.Default == "delta"
 }
 
 // TimeSeriesPerMetricConfig defines the range of time series per metric
@@ -241,6 +805,14 @@ func (c *GeneratorConfig) perSpanBytes() int64 {
 	if ca.FatSpansEnabled() {
 		base += int64(ca.PerSpanMax) * int64(ca.ValueBytes+fatAttrKeyOverhead)
 	}
+	// Fat events, averaged over the spans that carry them.
+	if ev := c.Traces.Events; ev.Percentage > 0 && ev.ValueBytes > 0 {
+		perSpan := int64(max(ev.Max, 3)) * int64(max(ev.Attributes, 2)) * int64(ev.ValueBytes+fatAttrKeyOverhead)
+		base += int64(float64(perSpan) * ev.Percentage / 100)
+	}
+	// Declared attributes, assuming each lands on every span with a short
+	// value.
+	base += int64(len(ca.Declared)) * (fatAttrKeyOverhead + 36)
 	return base
 }
 
@@ -272,6 +844,46 @@ func (c *GeneratorConfig) EstimateMemoryUsage() int64 {
 	return totalBytes
 }
 
+// validateTemporality checks metrics.temporality values and category names.
+func validateTemporality(t TemporalityConfig) error {
+	valid := func(v string) bool { return v == "" || v == "cumulative" || v == "delta" }
+	if !valid(t.Default) {
+		return fmt.Errorf("metrics.temporality.default must be cumulative or delta, got %q", t.Default)
+	}
+	for category, v := range t.Categories {
+		if !slices.Contains(MetricCategories, category) {
+			return fmt.Errorf("metrics.temporality.categories: unknown category %q (want one of %s)", category, strings.Join(MetricCategories, ", "))
+		}
+		if v == "" || !valid(v) {
+			return fmt.Errorf("metrics.temporality.categories.%s must be cumulative or delta, got %q", category, v)
+		}
+	}
+	return nil
+}
+
+// validateHistograms checks metrics.histograms.
+func validateHistograms(h HistogramsConfig) error {
+	switch h.Type {
+	case "", "explicit", "exponential", "summary":
+	default:
+		return fmt.Errorf("metrics.histograms.type must be explicit, exponential or summary, got %q", h.Type)
+	}
+	for i := 1; i < len(h.Buckets); i++ {
+		if h.Buckets[i] <= h.Buckets[i-1] {
+			return fmt.Errorf("metrics.histograms.buckets must be strictly ascending")
+		}
+	}
+	if h.Scale != nil && (*h.Scale < -10 || *h.Scale > 20) {
+		return fmt.Errorf("metrics.histograms.scale must be between -10 and 20, got %d", *h.Scale)
+	}
+	for _, q := range h.Quantiles {
+		if q < 0 || q > 1 {
+			return fmt.Errorf("metrics.histograms.quantiles must be between 0 and 1, got %v", q)
+		}
+	}
+	return nil
+}
+
 // Validate checks if the configuration is valid
 func (c *GeneratorConfig) Validate() error {
 	if c.Output.Directory == "" {
@@ -306,33 +918,60 @@ func (c *GeneratorConfig) Validate() error {
 			return fmt.Errorf("traces.spans.std_dev must be non-negative")
 		}
 
-		if c.Traces.Services.Count < 1 {
-			return fmt.Errorf("traces.services.count must be at least 1")
+		if c.Traces.Model.ModelEnabled() {
+			// Services come from the learned model.
+			if err := c.validateModelConfig(); err != nil {
+				return err
+			}
+		} else {
+			if c.Traces.Services.Count < 1 {
+				return fmt.Errorf("traces.services.count must be at least 1")
+			}
+
+			if len(c.Traces.Services.Names) > 0 && len(c.Traces.Services.Names) != c.Traces.Services.Count {
+				return fmt.Errorf("traces.services.names length must match traces.services.count")
+			}
+
+			if err := c.validateNamespaceConfig(); err != nil {
+				return err
+			}
+
+			if err := c.validateServiceGraph(); err != nil {
+				return err
+			}
 		}
 
-		if len(c.Traces.Services.Names) > 0 && len(c.Traces.Services.Names) != c.Traces.Services.Count {
-			return fmt.Errorf("traces.services.names length must match traces.services.count")
+		if err := c.validateCustomAttributes(); err != nil {
+			return err
 		}
 
-		if err := c.validateNamespaceConfig(); err != nil {
+		if err := c.validateRootConfig(); err != nil {
 			return err
 		}
 
-		if err := c.validateCustomAttributes(); err != nil {
+		if err := c.validateLatencyConfig(); err != nil {
 			return err
 		}
 
-		if err := c.validateRootConfig(); err != nil {
+		if err := c.validateConcurrencyConfig(); err != nil {
+			return err
+		}
+
+		if err := c.validateErrorsConfig(); err != nil {
+			return err
+		}
+
+		if err := c.validateEventsAndLinks(); err != nil {
+			return err
+		}
+
+		if err := c.validateMessagingConfig(); err != nil {
 			return err
 		}
 	}
 
 	// Only validate metrics configuration if metrics are enabled
 	if c.Metrics.MetricCount > 0 {
-		if c.Metrics.MetricCount > 200 {
-			return fmt.Errorf("metrics.metric_count must not exceed 200 (requested: %d)", c.Metrics.MetricCount)
-		}
-
 		if c.Metrics.TimeSeriesPerMetric.Min < 1 {
 			return fmt.Errorf("metrics.timeseries_per_metric.min must be at least 1")
 		}
@@ -340,6 +979,21 @@ func (c *GeneratorConfig) Validate() error {
 		if c.Metrics.TimeSeriesPerMetric.Max < c.Metrics.TimeSeriesPerMetric.Min {
 			return fmt.Errorf("metrics.timeseries_per_metric.max must be >= min")
 		}
+
+		if err := validateTemporality(c.Metrics.Temporality); err != nil {
+			return err
+		}
+		if err := validateHistograms(c.Metrics.Histograms); err != nil {
+			return err
+		}
+		for _, category := range slices.Concat(c.Metrics.Categories.Include, c.Metrics.Categories.Exclude) {
+			if !slices.Contains(MetricCategories, category) {
+				return fmt.Errorf("metrics.categories: unknown category %q (want one of %s)", category, strings.Join(MetricCategories, ", "))
+			}
+		}
+		if c.Metrics.Streaming.ChunkDataPoints < 0 {
+			return fmt.Errorf("metrics.streaming.chunk_data_points must be non-negative")
+		}
 	}
 
 	// Only validate log configuration if logs are enabled
@@ -352,6 +1006,10 @@ func (c *GeneratorConfig) Validate() error {
 		}
 	}
 
+	if err := c.Inventory.validate(); err != nil {
+		return err
+	}
+
 	// Validate estimated memory usage doesn't exceed the configured cap.
 	// limits.allow_unbounded disables the check entirely (for deliberate
 	// stress runs). limits.max_memory_gb (default 10) sets the ceiling.
@@ -390,9 +1048,115 @@ func (c *GeneratorConfig) validateCustomAttributes() error {
 	if ca.PerSpanMax > 0 && ca.ValueBytes == 0 {
 		return fmt.Errorf("traces.custom_attributes.value_bytes must be > 0 when per_span_max > 0")
 	}
+
+	keys := make(map[string]bool, len(ca.Declared))
+	for i, d := range ca.Declared {
+		field := fmt.Sprintf("traces.custom_attributes.declared[%d]", i)
+		if d.Key == "" {
+			return fmt.Errorf("%s.key is required", field)
+		}
+		if keys[d.Key] {
+			return fmt.Errorf("%s: duplicate key %q", field, d.Key)
+		}
+		keys[d.Key] = true
+		if err := c.validateDeclaredAttribute(d); err != nil {
+			return fmt.Errorf("%s (%s): %w", field, d.Key, err)
+		}
+	}
 	return nil
 }
 
+// validateDeclaredAttribute checks one traces.custom_attributes.declared entry.
+func (c *GeneratorConfig) validateDeclaredAttribute(d DeclaredAttribute) error {
+	if d.Cardinality < 0 {
+		return fmt.Errorf("cardinality must be non-negative")
+	}
+	if d.Percentage < 0 || d.Percentage > 100 {
+		return fmt.Errorf("percentage must be between 0 and 100")
+	}
+	for _, service := range d.Services {
+		if !c.Traces.Model.ModelEnabled() && !slices.Contains(c.Traces.Services.Names, service) {
+			return fmt.Errorf("unknown service %q", service)
+		}
+	}
+
+	switch d.Generator {
+	case "enum":
+		if len(d.Values) == 0 {
+			return fmt.Errorf("values are required for enum")
+		}
+		if len(d.Weights) > 0 && len(d.Weights) != len(d.Values) {
+			return fmt.Errorf("weights must match values")
+		}
+		for _, w := range d.Weights {
+			if w <= 0 {
+				return fmt.Errorf("weights must be > 0")
+			}
+		}
+		if _, err := ParseTypedValues(d.Type, d.Values); err != nil {
+			return err
+		}
+	case "int_range", "float_range":
+		if d.Max < d.Min {
+			return fmt.Errorf("max must be >= min")
+		}
+		if d.Generator == "int_range" && (d.Min != float64(int64(d.Min)) || d.Max != float64(int64(d.Max))) {
+			return fmt.Errorf("min and max must be integers for int_range")
+		}
+	case "pool":
+		if d.Cardinality == 0 {
+			return fmt.Errorf("cardinality is required for pool")
+		}
+	case "pattern":
+		if d.Pattern == "" {
+			return fmt.Errorf("pattern is required for pattern")
+		}
+		if _, err := syntax.Parse(d.Pattern, syntax.Perl); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	case "bool", "uuid", "url", "email":
+	default:
+		return fmt.Errorf("generator must be one of enum, int_range, float_range, bool, uuid, pool, pattern, url, email")
+	}
+	return nil
+}
+
+// ParseTypedValues parses enum values of the given attribute type.
+func ParseTypedValues(typ string, values []string) ([]any, error) {
+	out := make([]any, len(values))
+	for i, s := range values {
+		v, err := ParseTypedValue(typ, s)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// ParseTypedValue parses s as a value of the given attribute type: string
+// (or empty), int, float or bool.
+func ParseTypedValue(typ, s string) (any, error) {
+	var v any
+	var err error
+	switch typ {
+	case "", "string":
+		return s, nil
+	case "int":
+		v, err = strconv.ParseInt(s, 10, 64)
+	case "float":
+		v, err = strconv.ParseFloat(s, 64)
+	case "bool":
+		v, err = strconv.ParseBool(s)
+	default:
+		return nil, fmt.Errorf("type must be string, int, float or bool")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("value %q is not a valid %s", s, typ)
+	}
+	return v, nil
+}
+
 // validateRootConfig checks the missing/late root controls in traces.root.
 func (c *GeneratorConfig) validateRootConfig() error {
 	r := c.Traces.Root
@@ -413,6 +1177,277 @@ func (c *GeneratorConfig) validateRootConfig() error {
 	return nil
 }
 
+// validateLatencyConfig checks traces.latency. Service names are only checked
+// against traces.services.names when the topology comes from config; with a
+// learned model the service names aren't known until the source is read.
+func (c *GeneratorConfig) validateLatencyConfig() error {
+	l := c.Traces.Latency
+	knownService := func(name string) bool {
+		return c.Traces.Model.ModelEnabled() || slices.Contains(c.Traces.Services.Names, name)
+	}
+
+	if err := validateLatencyDistribution("traces.latency.default", l.Default); err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(l.Services)) {
+		if !knownService(name) {
+			return fmt.Errorf("traces.latency.services references unknown service %q (must appear in traces.services.names)", name)
+		}
+		d := l.Services[name]
+		if !d.Configured() {
+			return fmt.Errorf("traces.latency.services.%s.distribution is required", name)
+		}
+		if err := validateLatencyDistribution("traces.latency.services."+name, d); err != nil {
+			return err
+		}
+	}
+	for i, op := range l.Operations {
+		field := fmt.Sprintf("traces.latency.operations[%d]", i)
+		if op.Operation == "" {
+			return fmt.Errorf("%s.operation is required", field)
+		}
+		if op.Service != "" && !knownService(op.Service) {
+			return fmt.Errorf("%s references unknown service %q (must appear in traces.services.names)", field, op.Service)
+		}
+		if !op.Configured() {
+			return fmt.Errorf("%s.distribution is required", field)
+		}
+		if err := validateLatencyDistribution(field, op.LatencyDistribution); err != nil {
+			return err
+		}
+	}
+	for i, slow := range l.SlowServices {
+		field := fmt.Sprintf("traces.latency.slow_services[%d]", i)
+		if !knownService(slow.Service) {
+			return fmt.Errorf("%s references unknown service %q (must appear in traces.services.names)", field, slow.Service)
+		}
+		if slow.Percentage <= 0 || slow.Percentage > 100 {
+			return fmt.Errorf("%s.percentage must be > 0 and <= 100", field)
+		}
+		if slow.Factor < 1 {
+			return fmt.Errorf("%s.factor must be at least 1", field)
+		}
+	}
+
+	o := l.Outliers
+	if o.Percentage < 0 || o.Percentage > 100 {
+		return fmt.Errorf("traces.latency.outliers.percentage must be between 0 and 100")
+	}
+	if o.MinFactor < 0 || (o.MinFactor > 0 && o.MinFactor < 1) {
+		return fmt.Errorf("traces.latency.outliers.min_factor must be at least 1")
+	}
+	if o.MaxFactor > 0 && o.MaxFactor < max(o.MinFactor, 1) {
+		return fmt.Errorf("traces.latency.outliers.max_factor must be >= min_factor")
+	}
+	return nil
+}
+
+// validateLatencyDistribution checks the parameters a distribution needs.
+// An unconfigured distribution is valid (it falls through).
+func validateLatencyDistribution(field string, d LatencyDistribution) error {
+	if d.MinMs < 0 || d.MaxMs < 0 {
+		return fmt.Errorf("%s: min_ms/max_ms must be non-negative", field)
+	}
+	if d.MaxMs > 0 && d.MaxMs < d.MinMs {
+		return fmt.Errorf("%s.max_ms must be >= min_ms", field)
+	}
+
+	lognormal := func() error {
+		if d.MedianMs <= 0 {
+			return fmt.Errorf("%s.median_ms must be > 0 for %s", field, d.Distribution)
+		}
+		if d.P99Ms <= d.MedianMs {
+			return fmt.Errorf("%s.p99_ms must be > median_ms for %s", field, d.Distribution)
+		}
+		return nil
+	}
+
+	switch d.Distribution {
+	case "":
+		return nil
+	case "uniform":
+		if d.MaxMs <= d.MinMs {
+			return fmt.Errorf("%s.max_ms must be > min_ms for uniform", field)
+		}
+	case "lognormal":
+		return lognormal()
+	case "pareto":
+		if d.MinMs <= 0 {
+			return fmt.Errorf("%s.min_ms must be > 0 for pareto", field)
+		}
+		if d.Alpha <= 0 {
+			return fmt.Errorf("%s.alpha must be > 0 for pareto", field)
+		}
+	case "bimodal":
+		if err := lognormal(); err != nil {
+			return err
+		}
+		if d.SlowMedianMs <= d.MedianMs {
+			return fmt.Errorf("%s.slow_median_ms must be > median_ms for bimodal", field)
+		}
+		if d.SlowPercentage <= 0 || d.SlowPercentage >= 100 {
+			return fmt.Errorf("%s.slow_percentage must be between 0 and 100 (exclusive) for bimodal", field)
+		}
+	case "percentiles":
+		if len(d.Percentiles) == 0 {
+			return fmt.Errorf("%s.percentiles is required for percentiles", field)
+		}
+		type point struct{ p, ms float64 }
+		points := make([]point, 0, len(d.Percentiles))
+		for key, ms := range d.Percentiles {
+			p, err := ParsePercentileKey(key)
+			if err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+			points = append(points, point{p, ms})
+		}
+		slices.SortFunc(points, func(a, b point) int { return cmp.Compare(a.p, b.p) })
+		prev := d.MinMs
+		for _, pt := range points {
+			if pt.ms < prev {
+				return fmt.Errorf("%s.percentiles must not decrease (p%g = %gms is below %gms)", field, pt.p, pt.ms, prev)
+			}
+			prev = pt.ms
+		}
+	default:
+		return fmt.Errorf("%s.distribution %q must be one of uniform, lognormal, pareto, bimodal, percentiles", field, d.Distribution)
+	}
+	return nil
+}
+
+// validateConcurrencyConfig checks traces.concurrency.
+func (c *GeneratorConfig) validateConcurrencyConfig() error {
+	cc := c.Traces.Concurrency
+	for _, p := range []struct {
+		name string
+		val  float64
+	}{
+		{"parallel_percentage", cc.ParallelPercentage},
+		{"worker_pool.percentage", cc.WorkerPool.Percentage},
+		{"async_percentage", cc.AsyncPercentage},
+	} {
+		if p.val < 0 || p.val > 100 {
+			return fmt.Errorf("traces.concurrency.%s must be between 0 and 100", p.name)
+		}
+	}
+	if cc.ParallelPercentage+cc.WorkerPool.Percentage > 100 {
+		return fmt.Errorf("traces.concurrency.parallel_percentage + worker_pool.percentage must not exceed 100")
+	}
+	if cc.WorkerPool.MinWorkers < 0 || cc.WorkerPool.MaxWorkers < 0 {
+		return fmt.Errorf("traces.concurrency.worker_pool.min_workers/max_workers must be non-negative")
+	}
+	if cc.WorkerPool.MaxWorkers > 0 && cc.WorkerPool.MaxWorkers < cc.WorkerPool.MinWorkers {
+		return fmt.Errorf("traces.concurrency.worker_pool.max_workers must be >= min_workers")
+	}
+	return nil
+}
+
+// validateErrorsConfig checks traces.errors. As for latency, service names are
+// only checked when the topology comes from config.
+func (c *GeneratorConfig) validateErrorsConfig() error {
+	e := c.Traces.Errors
+	knownService := func(name string) bool {
+		return c.Traces.Model.ModelEnabled() || slices.Contains(c.Traces.Services.Names, name)
+	}
+	validPercentage := func(p float64) bool { return p >= 0 && p <= 100 }
+
+	if !validPercentage(e.Percentage) {
+		return fmt.Errorf("traces.errors.percentage must be between 0 and 100")
+	}
+	if !validPercentage(e.PropagationPercentage) {
+		return fmt.Errorf("traces.errors.propagation_percentage must be between 0 and 100")
+	}
+	for _, name := range slices.Sorted(maps.Keys(e.Services)) {
+		if !knownService(name) {
+			return fmt.Errorf("traces.errors.services references unknown service %q (must appear in traces.services.names)", name)
+		}
+		if !validPercentage(e.Services[name]) {
+			return fmt.Errorf("traces.errors.services.%s must be between 0 and 100", name)
+		}
+	}
+	for i, op := range e.Operations {
+		field := fmt.Sprintf("traces.errors.operations[%d]", i)
+		if op.Operation == "" {
+			return fmt.Errorf("%s.operation is required", field)
+		}
+		if op.Service != "" && !knownService(op.Service) {
+			return fmt.Errorf("%s references unknown service %q (must appear in traces.services.names)", field, op.Service)
+		}
+		if !validPercentage(op.Percentage) {
+			return fmt.Errorf("%s.percentage must be between 0 and 100", field)
+		}
+	}
+	return nil
+}
+
+// validateEventsAndLinks checks traces.events and traces.links.
+func (c *GeneratorConfig) validateEventsAndLinks() error {
+	ev, ln := c.Traces.Events, c.Traces.Links
+	for _, p := range []struct {
+		name string
+		val  float64
+	}{
+		{"events.percentage", ev.Percentage},
+		{"links.cross_trace_percentage", ln.CrossTracePercentage},
+		{"links.in_trace_percentage", ln.InTracePercentage},
+		{"links.batch_consumer.percentage", ln.BatchConsumer.Percentage},
+	} {
+		if p.val < 0 || p.val > 100 {
+			return fmt.Errorf("traces.%s must be between 0 and 100", p.name)
+		}
+	}
+	if ev.Min < 0 || ev.Max < 0 || ev.Attributes < 0 || ev.ValueBytes < 0 {
+		return fmt.Errorf("traces.events.min/max/attributes/value_bytes must be non-negative")
+	}
+	if ev.Max > 0 && ev.Max < ev.Min {
+		return fmt.Errorf("traces.events.max must be >= min")
+	}
+	for i, name := range ev.Names {
+		if name == "" {
+			return fmt.Errorf("traces.events.names[%d] must not be empty", i)
+		}
+	}
+	bc := ln.BatchConsumer
+	if bc.MinLinks < 0 || bc.MaxLinks < 0 {
+		return fmt.Errorf("traces.links.batch_consumer.min_links/max_links must be non-negative")
+	}
+	if bc.MaxLinks > 0 && bc.MaxLinks < bc.MinLinks {
+		return fmt.Errorf("traces.links.batch_consumer.max_links must be >= min_links")
+	}
+	return nil
+}
+
+// validateMessagingConfig checks traces.messaging.
+func (c *GeneratorConfig) validateMessagingConfig() error {
+	m := c.Traces.Messaging
+	if m.Percentage < 0 || m.Percentage > 100 {
+		return fmt.Errorf("traces.messaging.percentage must be between 0 and 100")
+	}
+	if m.SeparateTracePercentage < 0 || m.SeparateTracePercentage > 100 {
+		return fmt.Errorf("traces.messaging.separate_trace_percentage must be between 0 and 100")
+	}
+	if m.Partitions < 0 {
+		return fmt.Errorf("traces.messaging.partitions must be non-negative")
+	}
+	return validateLatencyDistribution("traces.messaging.queue_lag", m.QueueLag)
+}
+
+// validateModelConfig checks traces.model. The source file itself is read
+// (and rejected if unusable) when the trace generator is built.
+func (c *GeneratorConfig) validateModelConfig() error {
+	m := c.Traces.Model
+	if len(c.Traces.Services.Graph) > 0 {
+		return fmt.Errorf("traces.model.source and traces.services.graph are mutually exclusive")
+	}
+	if m.MaxSpans < 0 {
+		return fmt.Errorf("traces.model.max_spans must be non-negative")
+	}
+	if m.MaxDepth < 0 {
+		return fmt.Errorf("traces.model.max_depth must be non-negative")
+	}
+	return nil
+}
+
 // ApplyDefaults sets default values for optional fields
 func (c *GeneratorConfig) ApplyDefaults() {
 	if c.Output.Prefix == "" {
@@ -422,11 +1457,18 @@ func (c *GeneratorConfig) ApplyDefaults() {
 	if c.Limits.MaxMemoryGB == 0 {
 		c.Limits.MaxMemoryGB = defaultMaxMemoryGB
 	}
+	c.Inventory.ApplyDefaults()
 
 	// Only apply metrics defaults if metrics are enabled
 	if c.Metrics.MetricCount > 0 && c.Metrics.TimeSeriesPerMetric.Default == 0 {
 		c.Metrics.TimeSeriesPerMetric.Default = 300
 	}
+	if c.Metrics.Synthetic.Enabled && c.Metrics.Synthetic.Prefix == "" {
+		c.Metrics.Synthetic.Prefix = "synthetic"
+	}
+	if c.Metrics.Streaming.Enabled && c.Metrics.Streaming.ChunkDataPoints == 0 {
+		c.Metrics.Streaming.ChunkDataPoints = 100000
+	}
 
 	// Only apply trace defaults if traces are enabled
 	if c.Traces.Count > 0 {
@@ -485,6 +1527,103 @@ func (c *GeneratorConfig) validateNamespaceConfig() error {
 	return nil
 }
 
+// Valid values for traces.services.graph operation types and edge protocols.
+var (
+	graphOperationTypes = map[string]bool{"": true, "http": true, "grpc": true, "messaging": true, "db": true, "internal": true}
+	graphEdgeProtocols  = map[string]bool{"": true, "http": true, "grpc": true, "messaging": true, "db": true}
+)
+
+// validateServiceGraph checks traces.services.graph: every service it names
+// must appear in traces.services.names, each service may be declared once,
+// operation/edge fields must be in range, and the call graph must be acyclic
+// (a cycle would make trace generation recurse forever).
+func (c *GeneratorConfig) validateServiceGraph() error {
+	svc := c.Traces.Services
+	if len(svc.Graph) == 0 {
+		return nil
+	}
+
+	known := make(map[string]struct{}, len(svc.Names))
+	for _, name := range svc.Names {
+		known[name] = struct{}{}
+	}
+
+	calls := make(map[string][]string, len(svc.Graph))
+	for _, node := range svc.Graph {
+		if _, ok := known[node.Service]; !ok {
+			return fmt.Errorf("traces.services.graph references unknown service %q (must appear in traces.services.names)", node.Service)
+		}
+		if _, dup := calls[node.Service]; dup {
+			return fmt.Errorf("traces.services.graph declares service %q more than once", node.Service)
+		}
+		calls[node.Service] = []string{}
+
+		for _, op := range node.Operations {
+			if op.Name == "" {
+				return fmt.Errorf("traces.services.graph: service %q has an operation with no name", node.Service)
+			}
+			if !graphOperationTypes[op.Type] {
+				return fmt.Errorf("traces.services.graph: operation %q of service %q has invalid type %q (want http, grpc, messaging, db or internal)", op.Name, node.Service, op.Type)
+			}
+			if op.Weight < 0 {
+				return fmt.Errorf("traces.services.graph: operation %q of service %q has negative weight", op.Name, node.Service)
+			}
+		}
+
+		for _, edge := range node.Calls {
+			if _, ok := known[edge.Service]; !ok {
+				return fmt.Errorf("traces.services.graph: service %q calls unknown service %q (must appear in traces.services.names)", node.Service, edge.Service)
+			}
+			if p := edge.Probability; p != nil && (*p < 0 || *p > 1) {
+				return fmt.Errorf("traces.services.graph: call %s -> %s probability must be between 0 and 1", node.Service, edge.Service)
+			}
+			if edge.FanOut.Min < 0 || edge.FanOut.Max < 0 {
+				return fmt.Errorf("traces.services.graph: call %s -> %s fan_out must be non-negative", node.Service, edge.Service)
+			}
+			if edge.FanOut.Max > 0 && edge.FanOut.Max < edge.FanOut.Min {
+				return fmt.Errorf("traces.services.graph: call %s -> %s fan_out.max must be >= fan_out.min", node.Service, edge.Service)
+			}
+			if !graphEdgeProtocols[edge.Protocol] {
+				return fmt.Errorf("traces.services.graph: call %s -> %s has invalid protocol %q (want http, grpc, messaging or db)", node.Service, edge.Service, edge.Protocol)
+			}
+			calls[node.Service] = append(calls[node.Service], edge.Service)
+		}
+	}
+
+	// Depth-first search for a back edge. Services are visited in graph order
+	// so the reported cycle is stable for a given config.
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(calls))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("traces.services.graph contains a cycle: %s", strings.Join(append(path, name), " -> "))
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		for _, next := range calls[name] {
+			if err := visit(next, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		return nil
+	}
+	for _, node := range svc.Graph {
+		if err := visit(node.Service, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // resolveServiceNamespaces builds the final service-name → namespace map.
 // Explicit assignments win; remaining services are distributed round-robin
 // across the namespaces list in Names order so the same config always yields
@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"slices"
+	"testing"
+)
 
 func baseTracesCfg() *GeneratorConfig {
 	return &GeneratorConfig{
@@ -144,3 +147,464 @@ func TestApplyDefaultsNewFields(t *testing.T) {
 		t.Errorf("KeyPrefix default = %q, want custom.fat", c.Traces.CustomAttributes.KeyPrefix)
 	}
 }
+
+func TestValidateServiceGraph(t *testing.T) {
+	graphCfg := func(graph ...ServiceGraphNode) func(c *GeneratorConfig) {
+		return func(c *GeneratorConfig) {
+			c.Traces.Services = ServicesConfig{Count: 3, Names: []string{"api", "orders", "db"}, Graph: graph}
+		}
+	}
+	tests := []struct {
+		name    string
+		mutate  func(c *GeneratorConfig)
+		wantErr bool
+	}{
+		{"dag ok", graphCfg(
+			ServiceGraphNode{Service: "api", Calls: []GraphEdge{{Service: "orders", Probability: probability(0.8), FanOut: FanOutConfig{Min: 1, Max: 3}}}},
+			ServiceGraphNode{Service: "orders", Calls: []GraphEdge{{Service: "db", Protocol: "db"}}},
+		), false},
+		{"unknown service", graphCfg(
+			ServiceGraphNode{Service: "billing"},
+		), true},
+		{"unknown callee", graphCfg(
+			ServiceGraphNode{Service: "api", Calls: []GraphEdge{{Service: "billing"}}},
+		), true},
+		{"cycle", graphCfg(
+			ServiceGraphNode{Service: "api", Calls: []GraphEdge{{Service: "orders"}}},
+			ServiceGraphNode{Service: "orders", Calls: []GraphEdge{{Service: "db"}}},
+			ServiceGraphNode{Service: "db", Calls: []GraphEdge{{Service: "api"}}},
+		), true},
+		{"self call", graphCfg(
+			ServiceGraphNode{Service: "api", Calls: []GraphEdge{{Service: "api"}}},
+		), true},
+		{"bad protocol", graphCfg(
+			ServiceGraphNode{Service: "api", Calls: []GraphEdge{{Service: "orders", Protocol: "smtp"}}},
+		), true},
+		{"probability zero disables", graphCfg(
+			ServiceGraphNode{Service: "api", Calls: []GraphEdge{{Service: "orders", Probability: probability(0)}}},
+		), false},
+		{"probability out of range", graphCfg(
+			ServiceGraphNode{Service: "api", Calls: []GraphEdge{{Service: "orders", Probability: probability(1.5)}}},
+		), true},
+		{"fan_out max < min", graphCfg(
+			ServiceGraphNode{Service: "api", Calls: []GraphEdge{{Service: "orders", FanOut: FanOutConfig{Min: 3, Max: 2}}}},
+		), true},
+		{"bad operation type", graphCfg(
+			ServiceGraphNode{Service: "api", Operations: []GraphOperation{{Name: "x", Type: "soap"}}},
+		), true},
+		{"duplicate service", graphCfg(
+			ServiceGraphNode{Service: "api"},
+			ServiceGraphNode{Service: "api"},
+		), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseTracesCfg()
+			tt.mutate(c)
+			err := c.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateModelConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *GeneratorConfig)
+		wantErr bool
+	}{
+		{"model without services ok", func(c *GeneratorConfig) {
+			c.Traces.Services = ServicesConfig{}
+			c.Traces.Model = ModelConfig{Source: "capture.pb", MaxSpans: 500, MaxDepth: 12}
+		}, false},
+		{"model with graph", func(c *GeneratorConfig) {
+			c.Traces.Model.Source = "capture.pb"
+			c.Traces.Services.Graph = []ServiceGraphNode{{Service: "api"}}
+		}, true},
+		{"negative max_spans", func(c *GeneratorConfig) {
+			c.Traces.Model = ModelConfig{Source: "capture.pb", MaxSpans: -1}
+		}, true},
+		{"negative max_depth", func(c *GeneratorConfig) {
+			c.Traces.Model = ModelConfig{Source: "capture.pb", MaxDepth: -1}
+		}, true},
+		{"no model still needs services", func(c *GeneratorConfig) {
+			c.Traces.Services = ServicesConfig{}
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseTracesCfg()
+			tt.mutate(c)
+			err := c.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateLatencyConfig(t *testing.T) {
+	dist := func(d LatencyDistribution) func(c *GeneratorConfig) {
+		return func(c *GeneratorConfig) { c.Traces.Latency.Default = d }
+	}
+	tests := []struct {
+		name    string
+		mutate  func(c *GeneratorConfig)
+		wantErr bool
+	}{
+		{"empty ok", func(c *GeneratorConfig) {}, false},
+		{"uniform ok", dist(LatencyDistribution{Distribution: "uniform", MinMs: 1, MaxMs: 100}), false},
+		{"uniform max <= min", dist(LatencyDistribution{Distribution: "uniform", MinMs: 5, MaxMs: 5}), true},
+		{"lognormal ok", dist(LatencyDistribution{Distribution: "lognormal", MedianMs: 20, P99Ms: 400}), false},
+		{"lognormal p99 below median", dist(LatencyDistribution{Distribution: "lognormal", MedianMs: 20, P99Ms: 10}), true},
+		{"pareto ok", dist(LatencyDistribution{Distribution: "pareto", MinMs: 5, Alpha: 1.5, MaxMs: 5000}), false},
+		{"pareto no alpha", dist(LatencyDistribution{Distribution: "pareto", MinMs: 5}), true},
+		{"bimodal ok", dist(LatencyDistribution{Distribution: "bimodal", MedianMs: 10, P99Ms: 50, SlowMedianMs: 800, SlowPercentage: 5}), false},
+		{"bimodal slow below fast", dist(LatencyDistribution{Distribution: "bimodal", MedianMs: 10, P99Ms: 50, SlowMedianMs: 5, SlowPercentage: 5}), true},
+		{"percentiles ok", dist(LatencyDistribution{Distribution: "percentiles", Percentiles: map[string]float64{"p50": 10, "p99.9": 900}}), false},
+		{"percentiles decreasing", dist(LatencyDistribution{Distribution: "percentiles", Percentiles: map[string]float64{"p50": 10, "p90": 5}}), true},
+		{"percentiles bad key", dist(LatencyDistribution{Distribution: "percentiles", Percentiles: map[string]float64{"median": 10}}), true},
+		{"unknown distribution", dist(LatencyDistribution{Distribution: "gamma"}), true},
+		{"service ok", func(c *GeneratorConfig) {
+			c.Traces.Latency.Services = map[string]LatencyDistribution{"api": {Distribution: "lognormal", MedianMs: 5, P99Ms: 50}}
+		}, false},
+		{"unknown service", func(c *GeneratorConfig) {
+			c.Traces.Latency.Services = map[string]LatencyDistribution{"billing": {Distribution: "lognormal", MedianMs: 5, P99Ms: 50}}
+		}, true},
+		{"operation without name", func(c *GeneratorConfig) {
+			c.Traces.Latency.Operations = []OperationLatency{{LatencyDistribution: LatencyDistribution{Distribution: "uniform", MaxMs: 5}}}
+		}, true},
+		{"slow service ok", func(c *GeneratorConfig) {
+			c.Traces.Latency.SlowServices = []SlowServiceConfig{{Service: "api", Percentage: 10, Factor: 5}}
+		}, false},
+		{"slow service factor < 1", func(c *GeneratorConfig) {
+			c.Traces.Latency.SlowServices = []SlowServiceConfig{{Service: "api", Percentage: 10, Factor: 0.5}}
+		}, true},
+		{"outliers ok", func(c *GeneratorConfig) {
+			c.Traces.Latency.Outliers = OutlierConfig{Percentage: 0.5, MinFactor: 5, MaxFactor: 20}
+		}, false},
+		{"outliers max < min", func(c *GeneratorConfig) {
+			c.Traces.Latency.Outliers = OutlierConfig{Percentage: 0.5, MinFactor: 20, MaxFactor: 5}
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseTracesCfg()
+			tt.mutate(c)
+			err := c.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConcurrencyConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cc      ConcurrencyConfig
+		wantErr bool
+	}{
+		{"empty ok", ConcurrencyConfig{}, false},
+		{"mixed ok", ConcurrencyConfig{ParallelPercentage: 30, WorkerPool: WorkerPoolConfig{Percentage: 20, MinWorkers: 2, MaxWorkers: 8}, AsyncPercentage: 5}, false},
+		{"parallel > 100", ConcurrencyConfig{ParallelPercentage: 101}, true},
+		{"async negative", ConcurrencyConfig{AsyncPercentage: -1}, true},
+		{"modes exceed 100", ConcurrencyConfig{ParallelPercentage: 60, WorkerPool: WorkerPoolConfig{Percentage: 50}}, true},
+		{"workers max < min", ConcurrencyConfig{WorkerPool: WorkerPoolConfig{Percentage: 10, MinWorkers: 4, MaxWorkers: 2}}, true},
+		{"workers negative", ConcurrencyConfig{WorkerPool: WorkerPoolConfig{Percentage: 10, MinWorkers: -1}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseTracesCfg()
+			c.Traces.Concurrency = tt.cc
+			err := c.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateErrorsConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		errs    ErrorsConfig
+		wantErr bool
+	}{
+		{"empty ok", ErrorsConfig{}, false},
+		{"full ok", ErrorsConfig{
+			Percentage:            0.5,
+			Services:              map[string]float64{"api": 5},
+			Operations:            []OperationErrors{{Operation: "GET /x", Percentage: 50}},
+			PropagationPercentage: 60,
+		}, false},
+		{"percentage > 100", ErrorsConfig{Percentage: 120}, true},
+		{"propagation negative", ErrorsConfig{PropagationPercentage: -1}, true},
+		{"unknown service", ErrorsConfig{Services: map[string]float64{"billing": 5}}, true},
+		{"service rate > 100", ErrorsConfig{Services: map[string]float64{"api": 101}}, true},
+		{"operation without name", ErrorsConfig{Operations: []OperationErrors{{Percentage: 5}}}, true},
+		{"operation unknown service", ErrorsConfig{Operations: []OperationErrors{{Service: "billing", Operation: "x", Percentage: 5}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseTracesCfg()
+			c.Traces.Errors = tt.errs
+			err := c.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateEventsAndLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  SpanEventsConfig
+		links   SpanLinksConfig
+		wantErr bool
+	}{
+		{"empty ok", SpanEventsConfig{}, SpanLinksConfig{}, false},
+		{"full ok", SpanEventsConfig{Percentage: 20, Min: 1, Max: 5, Names: []string{"retry"}, Attributes: 3, ValueBytes: 1024},
+			SpanLinksConfig{CrossTracePercentage: 10, InTracePercentage: 5, BatchConsumer: BatchConsumerConfig{Percentage: 2, MinLinks: 10, MaxLinks: 50}}, false},
+		{"events percentage > 100", SpanEventsConfig{Percentage: 101}, SpanLinksConfig{}, true},
+		{"events max < min", SpanEventsConfig{Percentage: 10, Min: 4, Max: 2}, SpanLinksConfig{}, true},
+		{"negative value bytes", SpanEventsConfig{ValueBytes: -1}, SpanLinksConfig{}, true},
+		{"empty event name", SpanEventsConfig{Names: []string{"retry", ""}}, SpanLinksConfig{}, true},
+		{"cross trace negative", SpanEventsConfig{}, SpanLinksConfig{CrossTracePercentage: -5}, true},
+		{"batch max < min", SpanEventsConfig{}, SpanLinksConfig{BatchConsumer: BatchConsumerConfig{Percentage: 1, MinLinks: 10, MaxLinks: 5}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseTracesCfg()
+			c.Traces.Events = tt.events
+			c.Traces.Links = tt.links
+			err := c.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateMessagingConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       MessagingConfig
+		wantErr bool
+	}{
+		{"empty ok", MessagingConfig{}, false},
+		{"full ok", MessagingConfig{Percentage: 30, System: "kafka", Partitions: 6,
+			QueueLag: LatencyDistribution{Distribution: "lognormal", MedianMs: 20, P99Ms: 500}, SeparateTracePercentage: 50}, false},
+		{"percentage > 100", MessagingConfig{Percentage: 150}, true},
+		{"separate negative", MessagingConfig{SeparateTracePercentage: -1}, true},
+		{"negative partitions", MessagingConfig{Partitions: -2}, true},
+		{"bad queue lag", MessagingConfig{QueueLag: LatencyDistribution{Distribution: "uniform", MinMs: 10, MaxMs: 5}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseTracesCfg()
+			c.Traces.Messaging = tt.m
+			err := c.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDeclaredAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		decl    []DeclaredAttribute
+		wantErr bool
+	}{
+		{"none ok", nil, false},
+		{"full ok", []DeclaredAttribute{
+			{Key: "user.id", Generator: "pool", Prefix: "user-", Cardinality: 10_000_000},
+			{Key: "tier", Generator: "enum", Values: []string{"free", "pro"}, Weights: []int{9, 1}, Services: []string{"api"}},
+			{Key: "retries", Generator: "enum", Type: "int", Values: []string{"0", "1", "2"}},
+			{Key: "cart.items", Generator: "int_range", Min: 1, Max: 20},
+			{Key: "sku", Generator: "pattern", Pattern: `SKU-[A-Z]{3}-\d{4}`, Cardinality: 5000},
+			{Key: "request.id", Generator: "uuid", Percentage: 50, Operations: []string{"GET /x"}},
+		}, false},
+		{"missing key", []DeclaredAttribute{{Generator: "uuid"}}, true},
+		{"duplicate key", []DeclaredAttribute{{Key: "a", Generator: "uuid"}, {Key: "a", Generator: "url"}}, true},
+		{"unknown generator", []DeclaredAttribute{{Key: "a", Generator: "zipcode"}}, true},
+		{"enum without values", []DeclaredAttribute{{Key: "a", Generator: "enum"}}, true},
+		{"enum weights mismatch", []DeclaredAttribute{{Key: "a", Generator: "enum", Values: []string{"x", "y"}, Weights: []int{1}}}, true},
+		{"enum bad int", []DeclaredAttribute{{Key: "a", Generator: "enum", Type: "int", Values: []string{"x"}}}, true},
+		{"int_range max < min", []DeclaredAttribute{{Key: "a", Generator: "int_range", Min: 5, Max: 1}}, true},
+		{"int_range fractional", []DeclaredAttribute{{Key: "a", Generator: "int_range", Min: 0.5, Max: 1}}, true},
+		{"pool without cardinality", []DeclaredAttribute{{Key: "a", Generator: "pool"}}, true},
+		{"bad pattern", []DeclaredAttribute{{Key: "a", Generator: "pattern", Pattern: "[a-"}}, true},
+		{"unknown service", []DeclaredAttribute{{Key: "a", Generator: "uuid", Services: []string{"billing"}}}, true},
+		{"percentage > 100", []DeclaredAttribute{{Key: "a", Generator: "uuid", Percentage: 120}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseTracesCfg()
+			c.Traces.CustomAttributes.Declared = tt.decl
+			err := c.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTemporality(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *GeneratorConfig)
+		wantErr bool
+	}{
+		{"default delta ok", func(c *GeneratorConfig) { c.Metrics.Temporality.Default = "delta" }, false},
+		{"category override ok", func(c *GeneratorConfig) {
+			c.Metrics.Temporality.Categories = map[string]string{"http_metrics": "delta", "host_metrics": "cumulative"}
+		}, false},
+		{"bad default", func(c *GeneratorConfig) { c.Metrics.Temporality.Default = "gauge" }, true},
+		{"unknown category", func(c *GeneratorConfig) {
+			c.Metrics.Temporality.Categories = map[string]string{"nope": "delta"}
+		}, true},
+		{"empty category value", func(c *GeneratorConfig) {
+			c.Metrics.Temporality.Categories = map[string]string{"http_metrics": ""}
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseTracesCfg()
+			c.Metrics.MetricCount = 10
+			c.Metrics.TimeSeriesPerMetric = TimeSeriesPerMetricConfig{Min: 1, Max: 2}
+			tt.mutate(c)
+			err := c.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateHistograms(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *GeneratorConfig)
+		wantErr bool
+	}{
+		{"exponential ok", func(c *GeneratorConfig) {
+			c.Metrics.Histograms = HistogramsConfig{Type: "exponential", Scale: scale(4), NegativeBuckets: true}
+		}, false},
+		{"summary ok", func(c *GeneratorConfig) {
+			c.Metrics.Histograms = HistogramsConfig{Type: "summary", Quantiles: []float64{0, 0.5, 1}}
+		}, false},
+		{"custom buckets ok", func(c *GeneratorConfig) { c.Metrics.Histograms.Buckets = []float64{1, 10, 100} }, false},
+		{"unknown type", func(c *GeneratorConfig) { c.Metrics.Histograms.Type = "native" }, true},
+		{"buckets not ascending", func(c *GeneratorConfig) { c.Metrics.Histograms.Buckets = []float64{1, 1} }, true},
+		{"scale out of range", func(c *GeneratorConfig) { c.Metrics.Histograms.Scale = scale(21) }, true},
+		{"explicit scale 0", func(c *GeneratorConfig) { c.Metrics.Histograms.Scale = scale(0) }, false},
+		{"quantile out of range", func(c *GeneratorConfig) { c.Metrics.Histograms.Quantiles = []float64{1.5} }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseTracesCfg()
+			c.Metrics.MetricCount = 10
+			c.Metrics.TimeSeriesPerMetric = TimeSeriesPerMetricConfig{Min: 1, Max: 2}
+			tt.mutate(c)
+			err := c.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateMetricCategories(t *testing.T) {
+	c := baseTracesCfg()
+	c.Metrics.MetricCount = 10
+	c.Metrics.TimeSeriesPerMetric = TimeSeriesPerMetricConfig{Min: 1, Max: 2}
+	c.Metrics.Categories = CategoriesConfig{Include: []string{"http_metrics", "k8s_pod"}, Exclude: []string{"k8s_pod"}}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate() err = %v", err)
+	}
+	c.Metrics.Categories.Exclude = []string{"nope"}
+	if err := c.Validate(); err == nil {
+		t.Fatal("Validate() accepted an unknown category")
+	}
+}
+
+func TestMetricStressConfig(t *testing.T) {
+	c := baseTracesCfg()
+	c.Metrics.MetricCount = 20000
+	c.Metrics.TimeSeriesPerMetric = TimeSeriesPerMetricConfig{Min: 1, Max: 2, Default: 1}
+	c.Metrics.Synthetic.Enabled = true
+	c.Metrics.Streaming.Enabled = true
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate() err = %v", err)
+	}
+	c.ApplyDefaults()
+	if c.Metrics.Synthetic.Prefix != "synthetic" || c.Metrics.Streaming.ChunkDataPoints != 100000 {
+		t.Errorf("defaults = %q/%d, want synthetic/100000", c.Metrics.Synthetic.Prefix, c.Metrics.Streaming.ChunkDataPoints)
+	}
+
+	c.Metrics.Streaming.ChunkDataPoints = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("Validate() accepted a negative chunk size")
+	}
+}
+
+func TestSelectedCategories(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  MetricsConfig
+		want []string
+	}{
+		{"all", MetricsConfig{}, MetricCategories},
+		{"include in catalogue order", MetricsConfig{Categories: CategoriesConfig{Include: []string{"http_metrics", "host_metrics"}}},
+			[]string{"host_metrics", "http_metrics"}},
+		{"exclude", MetricsConfig{Categories: CategoriesConfig{Include: []string{"host_metrics", "http_metrics"}, Exclude: []string{"host_metrics"}}},
+			[]string{"http_metrics"}},
+		{"definitions only", MetricsConfig{DefinitionsFile: "defs.yaml"}, nil},
+		{"definitions and include", MetricsConfig{DefinitionsFile: "defs.yaml", Categories: CategoriesConfig{Include: []string{"jvm_metrics"}}},
+			[]string{"jvm_metrics"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.Selected(); !slices.Equal(got, tt.want) {
+				t.Fatalf("Selected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if !(MetricsConfig{}).SamplesCatalogue() {
+		t.Error("SamplesCatalogue() = false without categories or definitions")
+	}
+	if (MetricsConfig{Categories: CategoriesConfig{Exclude: []string{"aws_metrics"}}}).SamplesCatalogue() {
+		t.Error("SamplesCatalogue() = true with an exclude list")
+	}
+}
+
+func TestInventoryConfig(t *testing.T) {
+	c := baseTracesCfg()
+	c.ApplyDefaults()
+	inv := c.Inventory
+	if inv.Clusters != 1 || inv.NodesPerCluster != 5 || inv.Replicas != 3 || inv.Hosts != 3 || len(inv.Namespaces) != 3 {
+		t.Errorf("unexpected defaults: %+v", inv)
+	}
+
+	for name, bad := range map[string]InventoryConfig{
+		"negative replicas":   {Replicas: -1},
+		"empty namespace":     {Namespaces: []string{""}},
+		"duplicate container": {Containers: []string{"app", "app"}},
+	} {
+		c := baseTracesCfg()
+		c.Inventory = bad
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func scale(s int32) *int32 { return &s }
+
+func probability(p float64) *float64 { return &p }
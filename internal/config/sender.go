@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
+	"regexp"
 	"strings"
 	"time"
 
@@ -11,10 +13,133 @@ import (
 
 // SenderConfig represents the configuration for the telemetry sender
 type SenderConfig struct {
-	Input      InputConfig      `yaml:"input"`
-	OTLP       OTLPConfig       `yaml:"otlp"`
-	Sending    SendingConfig    `yaml:"sending"`
-	Timestamps TimestampsConfig `yaml:"timestamps"`
+	Input       InputConfig       `yaml:"input"`
+	OTLP        OTLPConfig        `yaml:"otlp"`
+	Sending     SendingConfig     `yaml:"sending"`
+	Timestamps  TimestampsConfig  `yaml:"timestamps"`
+	Cardinality CardinalityConfig `yaml:"cardinality"`
+	Identity    IdentityConfig    `yaml:"identity"`
+	Metrics     MetricsSendConfig `yaml:"metrics"`
+}
+
+// MetricsSendConfig configures how the sender evolves metric templates across
+// replays.
+type MetricsSendConfig struct {
+	Cumulative CumulativeConfig `yaml:"cumulative"`
+	Patterns   []ValuePattern   `yaml:"patterns"`
+	Churn      ChurnConfig      `yaml:"churn"`
+}
+
+// ChurnConfig replaces series with new ones over time, as pods and containers
+// come and go in a real cluster: every Every, Percentage of the series that
+// carry one of Attributes get fresh values for those attributes, which ends
+// the old series and starts a new one. Attributes are matched in data points
+// and in resources of metrics grouped per entity. Disabled when Percentage
+// is 0.
+type ChurnConfig struct {
+	Percentage float64  `yaml:"percentage"`
+	Every      string   `yaml:"every"`      // default 10m
+	Attributes []string `yaml:"attributes"` // default k8s.pod.name, container.id
+}
+
+// GetEvery parses the churn interval.
+func (c ChurnConfig) GetEvery() time.Duration { return parseOptionalDuration(c.Every) }
+
+// ValuePattern varies the values of selected gauge series at send time, so
+// charts show controlled signals instead of flat lines. Each component scales
+// the series' template value; a series matched by several patterns uses the
+// first.
+type ValuePattern struct {
+	// Metrics are metric name globs (path.Match syntax); empty matches
+	// every gauge.
+	Metrics []string `yaml:"metrics"`
+	// Percentage of the matching series to vary (default 100).
+	Percentage float64 `yaml:"percentage"`
+
+	Diurnal    DiurnalPattern    `yaml:"diurnal"`
+	Trend      TrendPattern      `yaml:"trend"`
+	RandomWalk RandomWalkPattern `yaml:"random_walk"`
+	Noise      NoisePattern      `yaml:"noise"`
+	Anomalies  []ValueAnomaly    `yaml:"anomalies"`
+}
+
+// DiurnalPattern is a cosine cycle of ±Amplitude (a fraction of the template
+// value) that peaks Peak into each Period, counted from the Unix epoch, so a
+// 24h period with peak 14h peaks at 14:00 UTC.
+type DiurnalPattern struct {
+	Amplitude float64 `yaml:"amplitude"`
+	Period    string  `yaml:"period"` // default 24h
+	Peak      string  `yaml:"peak"`
+}
+
+// GetPeriod parses the diurnal period.
+func (d DiurnalPattern) GetPeriod() time.Duration { return parseOptionalDuration(d.Period) }
+
+// GetPeak parses the diurnal peak offset.
+func (d DiurnalPattern) GetPeak() time.Duration { return parseOptionalDuration(d.Peak) }
+
+// TrendPattern changes the value linearly by PerHour (a fraction of the
+// template value) per hour since the first send.
+type TrendPattern struct {
+	PerHour float64 `yaml:"per_hour"`
+}
+
+// RandomWalkPattern moves the value by a Gaussian step with standard
+// deviation Step (a fraction of the template value) on every send.
+type RandomWalkPattern struct {
+	Step float64 `yaml:"step"`
+}
+
+// NoisePattern adds independent Gaussian noise with standard deviation
+// StdDev (a fraction of the template value) to every send.
+type NoisePattern struct {
+	StdDev float64 `yaml:"stddev"`
+}
+
+// ValueAnomaly is a scheduled change to the signal: a "spike" multiplies it
+// by Magnitude, a "step" shifts it by Magnitude × the template value
+// (negative for a drop), and a "flatline" holds the value it had when the
+// anomaly began. It starts Start after the first send and lasts Duration
+// (empty: until the sender stops), repeating Every period when set.
+type ValueAnomaly struct {
+	Type      string  `yaml:"type"`
+	Start     string  `yaml:"start"`
+	Duration  string  `yaml:"duration"`
+	Every     string  `yaml:"every"`
+	Magnitude float64 `yaml:"magnitude"` // default 5 for spikes, 1 for steps
+}
+
+// GetStart parses the anomaly's start offset.
+func (a ValueAnomaly) GetStart() time.Duration { return parseOptionalDuration(a.Start) }
+
+// GetDuration parses the anomaly's duration, 0 meaning open-ended.
+func (a ValueAnomaly) GetDuration() time.Duration { return parseOptionalDuration(a.Duration) }
+
+// GetEvery parses the anomaly's repeat period, 0 meaning it happens once.
+func (a ValueAnomaly) GetEvery() time.Duration { return parseOptionalDuration(a.Every) }
+
+// parseOptionalDuration parses a duration validated by Validate; empty is 0.
+func parseOptionalDuration(s string) time.Duration {
+	d, _ := time.ParseDuration(s)
+	return d
+}
+
+// CumulativeConfig makes cumulative series behave like real counters: the
+// sender keeps per-series state so monotonic sums and histograms grow on every
+// send and keep a stable start time. Disabled by default, which replays the
+// template values with start time equal to the point time.
+type CumulativeConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Rate is how much a series grows per send, as a fraction of its
+	// template value (histograms add that fraction of their template bucket
+	// counts). Default 0.1.
+	Rate float64 `yaml:"rate"`
+
+	// ResetPercentage is the chance, per send, that a series resets: its
+	// start time moves to the send time and it counts up from zero again,
+	// as after a process restart.
+	ResetPercentage float64 `yaml:"reset_percentage"`
 }
 
 // InputConfig configures where to load telemetry templates from
@@ -72,10 +197,130 @@ type BatchSizeConfig struct {
 
 // TimestampsConfig configures timestamp behavior
 type TimestampsConfig struct {
-	JitterMs   int `yaml:"jitter_ms"`
-	BackdateMs int `yaml:"backdate_ms"`
+	JitterMs   int             `yaml:"jitter_ms"`
+	BackdateMs int             `yaml:"backdate_ms"`
+	Backfill   BackfillConfig  `yaml:"backfill"`
+	Anomalies  AnomaliesConfig `yaml:"anomalies"`
+}
+
+// AnomaliesConfig injects the timestamp problems that receivers and sampling
+// proxies meet from misbehaving clocks. The zero value injects none.
+type AnomaliesConfig struct {
+	Skew ClockSkewConfig `yaml:"skew"`
+
+	// ChildBeforeParentPercentage is the percent of traces in which one
+	// child span is moved to start before its parent.
+	ChildBeforeParentPercentage float64 `yaml:"child_before_parent_percentage"`
+
+	Future FutureTimestampsConfig `yaml:"future"`
+
+	OutOfOrderBatches OutOfOrderBatchesConfig `yaml:"out_of_order_batches"`
+}
+
+// OutOfOrderBatchesConfig holds Percentage of trace batches back for DelayMs
+// (default 5000) through the deferred scheduler, so they arrive after
+// batches with later timestamps.
+type OutOfOrderBatchesConfig struct {
+	Percentage float64 `yaml:"percentage"`
+	DelayMs    int64   `yaml:"delay_ms"`
+}
+
+// ClockSkewConfig shifts the spans of one service in a trace by an offset
+// drawn uniformly from MinMs..MaxMs (either may be negative), as a host with
+// a wrong clock would. With Services set, those services' spans are skewed
+// in Percentage of traces (default every trace); otherwise one service is
+// picked per skewed trace.
+type ClockSkewConfig struct {
+	Percentage float64  `yaml:"percentage"`
+	Services   []string `yaml:"services"`
+	MinMs      float64  `yaml:"min_ms"`
+	MaxMs      float64  `yaml:"max_ms"`
+}
+
+// FutureTimestampsConfig moves Percentage of traces MinMs..MaxMs into the
+// future (default 1-10 minutes).
+type FutureTimestampsConfig struct {
+	Percentage float64 `yaml:"percentage"`
+	MinMs      float64 `yaml:"min_ms"`
+	MaxMs      float64 `yaml:"max_ms"`
 }
 
+// BackfillConfig replaces the wall clock with one that starts Window in the
+// past and runs Speed times faster than real time, so successive replays get
+// progressively later timestamps until the window is covered and the sender
+// stops.
+type BackfillConfig struct {
+	// Window is how far back to start, e.g. "168h" for the last 7 days.
+	// Empty disables backfill.
+	Window string `yaml:"window"`
+
+	// Speed is how much data time passes per second of sending: 144 covers
+	// a day of data in 10 minutes. Default 1 (real time).
+	Speed float64 `yaml:"speed"`
+}
+
+// CardinalityConfig configures send-time rewriting of span and log attribute
+// values. Templates are replayed verbatim, so without it every attribute's set
+// of values stops growing after the first pass.
+type CardinalityConfig struct {
+	Attributes []CardinalityAttribute `yaml:"attributes"`
+}
+
+// CardinalityAttribute rewrites one attribute key with fresh values.
+type CardinalityAttribute struct {
+	// Key is the attribute to rewrite.
+	Key string `yaml:"key"`
+
+	// Generator is "counter" (an increasing number), "uuid", "replay_hash" (a
+	// hash of the replay number and the template value, so spans sharing a
+	// value still share one within a replay) or "pool" (one of PoolSize
+	// values).
+	Generator string `yaml:"generator"`
+
+	// Per is "event" (default) for a new value on every span or log record,
+	// or "replay" for one value per pass over the templates.
+	Per string `yaml:"per"`
+
+	// PoolSize is the number of distinct values for the pool generator.
+	PoolSize int `yaml:"pool_size"`
+
+	// Prefix is prepended to every generated value.
+	Prefix string `yaml:"prefix"`
+
+	// Add sets the attribute on events that don't already have it; by
+	// default only events carrying Key are rewritten.
+	Add bool `yaml:"add"`
+}
+
+// IdentityConfig rewrites identifying attributes (service.name, host.name,
+// k8s.pod.name, ...) once when templates are loaded, so each sender replica
+// can present itself as distinct services or hosts.
+type IdentityConfig struct {
+	// Replica identifies this sender in templates as {{replica}}. Defaults
+	// to the hostname, which is the pod name under Kubernetes.
+	Replica string `yaml:"replica"`
+
+	Attributes []IdentityAttribute `yaml:"attributes"`
+}
+
+// IdentityAttribute rewrites one attribute key in resources, spans, data
+// points and log records. Templates may use {{value}} (the current value),
+// {{service}} (the service.name of the span or resource), {{replica}} and
+// {{hostname}}.
+type IdentityAttribute struct {
+	Key string `yaml:"key"`
+
+	// Template renders the new value, e.g. "{{value}}-{{replica}}".
+	Template string `yaml:"template"`
+
+	// Map gives templates for specific current values; values not in Map
+	// use Template, or are left alone when Template is empty.
+	Map map[string]string `yaml:"map"`
+}
+
+// IdentityPlaceholder matches a {{name}} placeholder in an identity template.
+var IdentityPlaceholder = regexp.MustCompile(`\{\{\s*([^}]*?)\s*\}\}`)
+
 // LoadSenderConfig loads and validates a sender configuration from a file
 func LoadSenderConfig(path string) (*SenderConfig, error) {
 	data, err := os.ReadFile(path)
@@ -110,6 +355,7 @@ func (c *SenderConfig) expandEnvVars() {
 	c.Input.Traces = os.ExpandEnv(c.Input.Traces)
 	c.Input.Metrics = os.ExpandEnv(c.Input.Metrics)
 	c.Input.Logs = os.ExpandEnv(c.Input.Logs)
+	c.Identity.Replica = os.ExpandEnv(c.Identity.Replica)
 }
 
 // Validate checks if the configuration is valid
@@ -150,6 +396,22 @@ func (c *SenderConfig) Validate() error {
 		return fmt.Errorf("timestamps.backdate_ms must be non-negative")
 	}
 
+	if c.Timestamps.Backfill.Window != "" {
+		if window, err := time.ParseDuration(c.Timestamps.Backfill.Window); err != nil {
+			return fmt.Errorf("invalid timestamps.backfill.window format: %w", err)
+		} else if window <= 0 {
+			return fmt.Errorf("timestamps.backfill.window must be positive")
+		}
+	}
+
+	if c.Timestamps.Backfill.Speed < 0 {
+		return fmt.Errorf("timestamps.backfill.speed must be non-negative")
+	}
+
+	if err := c.Timestamps.Anomalies.validate(); err != nil {
+		return err
+	}
+
 	if c.Sending.Deferred.DrainTimeout != "" {
 		if _, err := time.ParseDuration(c.Sending.Deferred.DrainTimeout); err != nil {
 			return fmt.Errorf("invalid sending.deferred.drain_timeout format: %w", err)
@@ -160,6 +422,188 @@ func (c *SenderConfig) Validate() error {
 		return fmt.Errorf("sending.deferred.max_pending must be non-negative")
 	}
 
+	if c.Metrics.Cumulative.Rate < 0 {
+		return fmt.Errorf("metrics.cumulative.rate must be non-negative")
+	}
+	if pct := c.Metrics.Cumulative.ResetPercentage; pct < 0 || pct > 100 {
+		return fmt.Errorf("metrics.cumulative.reset_percentage must be between 0 and 100")
+	}
+
+	for i, pattern := range c.Metrics.Patterns {
+		if err := pattern.validate(i); err != nil {
+			return err
+		}
+	}
+
+	if pct := c.Metrics.Churn.Percentage; pct < 0 || pct > 100 {
+		return fmt.Errorf("metrics.churn.percentage must be between 0 and 100")
+	}
+	if c.Metrics.Churn.Every != "" {
+		if d, err := time.ParseDuration(c.Metrics.Churn.Every); err != nil {
+			return fmt.Errorf("invalid metrics.churn.every format: %w", err)
+		} else if d <= 0 {
+			return fmt.Errorf("metrics.churn.every must be positive")
+		}
+	}
+	for i, key := range c.Metrics.Churn.Attributes {
+		if key == "" {
+			return fmt.Errorf("metrics.churn.attributes[%d] is empty", i)
+		}
+	}
+
+	if err := c.Cardinality.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Identity.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validate checks percentages and offset ranges.
+func (a AnomaliesConfig) validate() error {
+	for name, pct := range map[string]float64{
+		"skew.percentage":                 a.Skew.Percentage,
+		"child_before_parent_percentage":  a.ChildBeforeParentPercentage,
+		"future.percentage":               a.Future.Percentage,
+		"out_of_order_batches.percentage": a.OutOfOrderBatches.Percentage,
+	} {
+		if pct < 0 || pct > 100 {
+			return fmt.Errorf("timestamps.anomalies.%s must be between 0 and 100", name)
+		}
+	}
+	if a.Skew.MinMs > a.Skew.MaxMs {
+		return fmt.Errorf("timestamps.anomalies.skew.min_ms must not exceed max_ms")
+	}
+	if (a.Skew.Percentage > 0 || len(a.Skew.Services) > 0) && a.Skew.MinMs == 0 && a.Skew.MaxMs == 0 {
+		return fmt.Errorf("timestamps.anomalies.skew needs min_ms/max_ms")
+	}
+	if a.OutOfOrderBatches.DelayMs < 0 {
+		return fmt.Errorf("timestamps.anomalies.out_of_order_batches.delay_ms must be non-negative")
+	}
+	if a.Future.MinMs < 0 || a.Future.MaxMs < 0 {
+		return fmt.Errorf("timestamps.anomalies.future offsets must be non-negative")
+	}
+	if a.Future.MaxMs > 0 && a.Future.MinMs > a.Future.MaxMs {
+		return fmt.Errorf("timestamps.anomalies.future.min_ms must not exceed max_ms")
+	}
+	return nil
+}
+
+// validate checks the i-th metrics.patterns entry.
+func (p ValuePattern) validate(i int) error {
+	prefix := fmt.Sprintf("metrics.patterns[%d]", i)
+	for _, glob := range p.Metrics {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("%s: invalid metric glob %q: %w", prefix, glob, err)
+		}
+	}
+	if p.Percentage < 0 || p.Percentage > 100 {
+		return fmt.Errorf("%s.percentage must be between 0 and 100", prefix)
+	}
+	if p.Diurnal.Amplitude < 0 || p.RandomWalk.Step < 0 || p.Noise.StdDev < 0 {
+		return fmt.Errorf("%s: diurnal.amplitude, random_walk.step and noise.stddev must be non-negative", prefix)
+	}
+	durations := map[string]string{"diurnal.period": p.Diurnal.Period, "diurnal.peak": p.Diurnal.Peak}
+	for j, a := range p.Anomalies {
+		switch a.Type {
+		case "spike", "step", "flatline":
+		default:
+			return fmt.Errorf("%s.anomalies[%d]: unknown type %q (want spike, step or flatline)", prefix, j, a.Type)
+		}
+		if a.Type == "spike" && a.Magnitude < 0 {
+			return fmt.Errorf("%s.anomalies[%d].magnitude must be non-negative for spikes", prefix, j)
+		}
+		durations[fmt.Sprintf("anomalies[%d].start", j)] = a.Start
+		durations[fmt.Sprintf("anomalies[%d].duration", j)] = a.Duration
+		durations[fmt.Sprintf("anomalies[%d].every", j)] = a.Every
+	}
+	for name, s := range durations {
+		if s == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(s); err != nil {
+			return fmt.Errorf("invalid %s.%s format: %w", prefix, name, err)
+		} else if d < 0 {
+			return fmt.Errorf("%s.%s must be non-negative", prefix, name)
+		}
+	}
+	if p.Diurnal.Period != "" && p.Diurnal.GetPeriod() == 0 {
+		return fmt.Errorf("%s.diurnal.period must be positive", prefix)
+	}
+	for j, a := range p.Anomalies {
+		if every := a.GetEvery(); every > 0 && a.GetDuration() >= every {
+			return fmt.Errorf("%s.anomalies[%d].duration must be shorter than every", prefix, j)
+		}
+		if a.GetEvery() > 0 && a.Duration == "" {
+			return fmt.Errorf("%s.anomalies[%d]: a repeating anomaly needs a duration", prefix, j)
+		}
+	}
+	return nil
+}
+
+// validate checks each cardinality.attributes entry.
+func (c CardinalityConfig) validate() error {
+	seen := make(map[string]bool, len(c.Attributes))
+	for i, a := range c.Attributes {
+		if a.Key == "" {
+			return fmt.Errorf("cardinality.attributes[%d].key is required", i)
+		}
+		if seen[a.Key] {
+			return fmt.Errorf("cardinality.attributes[%d]: duplicate key %q", i, a.Key)
+		}
+		seen[a.Key] = true
+
+		switch a.Generator {
+		case "counter", "uuid", "replay_hash":
+		case "pool":
+			if a.PoolSize <= 0 {
+				return fmt.Errorf("cardinality.attributes[%d] (%s): pool_size must be positive for the pool generator", i, a.Key)
+			}
+		default:
+			return fmt.Errorf("cardinality.attributes[%d] (%s): unknown generator %q (want counter, uuid, replay_hash or pool)", i, a.Key, a.Generator)
+		}
+
+		switch a.Per {
+		case "", "event", "replay":
+		default:
+			return fmt.Errorf("cardinality.attributes[%d] (%s): per must be event or replay, got %q", i, a.Key, a.Per)
+		}
+	}
+	return nil
+}
+
+// validate checks each identity.attributes entry and its templates.
+func (c IdentityConfig) validate() error {
+	seen := make(map[string]bool, len(c.Attributes))
+	for i, a := range c.Attributes {
+		if a.Key == "" {
+			return fmt.Errorf("identity.attributes[%d].key is required", i)
+		}
+		if seen[a.Key] {
+			return fmt.Errorf("identity.attributes[%d]: duplicate key %q", i, a.Key)
+		}
+		seen[a.Key] = true
+
+		if a.Template == "" && len(a.Map) == 0 {
+			return fmt.Errorf("identity.attributes[%d] (%s): template or map is required", i, a.Key)
+		}
+		templates := []string{a.Template}
+		for _, t := range a.Map {
+			templates = append(templates, t)
+		}
+		for _, t := range templates {
+			for _, m := range IdentityPlaceholder.FindAllStringSubmatch(t, -1) {
+				switch m[1] {
+				case "value", "service", "replica", "hostname":
+				default:
+					return fmt.Errorf("identity.attributes[%d] (%s): unknown placeholder %q in %q (want value, service, replica or hostname)", i, a.Key, m[0], t)
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -183,6 +627,54 @@ func (c *SenderConfig) ApplyDefaults() {
 		c.Timestamps.JitterMs = 1000 // 1 second default
 	}
 
+	if c.Timestamps.Backfill.Window != "" && c.Timestamps.Backfill.Speed == 0 {
+		c.Timestamps.Backfill.Speed = 1
+	}
+
+	skew := &c.Timestamps.Anomalies.Skew
+	if len(skew.Services) > 0 && skew.Percentage == 0 {
+		skew.Percentage = 100
+	}
+	future := &c.Timestamps.Anomalies.Future
+	if future.Percentage > 0 && future.MaxMs == 0 {
+		future.MinMs, future.MaxMs = max(future.MinMs, 60_000), max(future.MinMs, 600_000)
+	}
+	if ooo := &c.Timestamps.Anomalies.OutOfOrderBatches; ooo.Percentage > 0 && ooo.DelayMs == 0 {
+		ooo.DelayMs = 5000
+	}
+
+	for i := range c.Metrics.Patterns {
+		p := &c.Metrics.Patterns[i]
+		if p.Percentage == 0 {
+			p.Percentage = 100
+		}
+		if p.Diurnal.Amplitude > 0 && p.Diurnal.Period == "" {
+			p.Diurnal.Period = "24h"
+		}
+		for j := range p.Anomalies {
+			a := &p.Anomalies[j]
+			if a.Magnitude == 0 {
+				switch a.Type {
+				case "spike":
+					a.Magnitude = 5
+				case "step":
+					a.Magnitude = 1
+				}
+			}
+		}
+	}
+	if c.Metrics.Cumulative.Enabled && c.Metrics.Cumulative.Rate == 0 {
+		c.Metrics.Cumulative.Rate = 0.1
+	}
+	if churn := &c.Metrics.Churn; churn.Percentage > 0 {
+		if churn.Every == "" {
+			churn.Every = "10m"
+		}
+		if len(churn.Attributes) == 0 {
+			churn.Attributes = []string{"k8s.pod.name", "container.id"}
+		}
+	}
+
 	if c.Sending.Deferred.DrainTimeout == "" {
 		c.Sending.Deferred.DrainTimeout = "120s"
 	}
@@ -197,6 +689,15 @@ func (c *SenderConfig) GetDeferredDrainTimeout() (time.Duration, error) {
 	return time.ParseDuration(c.Sending.Deferred.DrainTimeout)
 }
 
+// GetBackfillWindow parses and returns the backfill window, or 0 when
+// backfill is disabled.
+func (c *SenderConfig) GetBackfillWindow() (time.Duration, error) {
+	if c.Timestamps.Backfill.Window == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(c.Timestamps.Backfill.Window)
+}
+
 // GetDuration parses and returns the sending duration
 func (c *SenderConfig) GetDuration() (time.Duration, error) {
 	if c.Sending.Duration == "0" {
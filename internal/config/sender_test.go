@@ -1,6 +1,10 @@
 package config
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+)
 
 func baseSenderCfg() *SenderConfig {
 	return &SenderConfig{
@@ -52,3 +56,237 @@ func TestSenderDeferredValidation(t *testing.T) {
 		t.Errorf("valid deferred config rejected: %v", err)
 	}
 }
+
+func TestSenderCardinalityValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   []CardinalityAttribute
+		wantErr string
+	}{
+		{"none", nil, ""},
+		{"valid", []CardinalityAttribute{
+			{Key: "request.id", Generator: "counter"},
+			{Key: "tenant", Generator: "pool", PoolSize: 10, Per: "replay"},
+		}, ""},
+		{"missing key", []CardinalityAttribute{{Generator: "uuid"}}, "key is required"},
+		{"duplicate key", []CardinalityAttribute{
+			{Key: "a", Generator: "uuid"},
+			{Key: "a", Generator: "counter"},
+		}, "duplicate key"},
+		{"unknown generator", []CardinalityAttribute{{Key: "a", Generator: "random"}}, "unknown generator"},
+		{"pool without size", []CardinalityAttribute{{Key: "a", Generator: "pool"}}, "pool_size must be positive"},
+		{"bad per", []CardinalityAttribute{{Key: "a", Generator: "uuid", Per: "trace"}}, "per must be event or replay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseSenderCfg()
+			c.Cardinality.Attributes = tt.attrs
+			err := c.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSenderIdentityValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   []IdentityAttribute
+		wantErr string
+	}{
+		{"none", nil, ""},
+		{"valid", []IdentityAttribute{
+			{Key: "service.name", Template: "{{value}}-{{ replica }}"},
+			{Key: "host.name", Map: map[string]string{"node-1": "{{hostname}}"}},
+		}, ""},
+		{"missing key", []IdentityAttribute{{Template: "x"}}, "key is required"},
+		{"duplicate key", []IdentityAttribute{
+			{Key: "a", Template: "x"},
+			{Key: "a", Template: "y"},
+		}, "duplicate key"},
+		{"no template or map", []IdentityAttribute{{Key: "a"}}, "template or map is required"},
+		{"unknown placeholder", []IdentityAttribute{{Key: "a", Template: "{{pod}}"}}, "unknown placeholder"},
+		{"unknown placeholder in map", []IdentityAttribute{{Key: "a", Map: map[string]string{"x": "{{ index }}"}}}, "unknown placeholder"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := baseSenderCfg()
+			c.Identity.Attributes = tt.attrs
+			err := c.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSenderBackfill(t *testing.T) {
+	c := baseSenderCfg()
+	c.ApplyDefaults()
+	if w, err := c.GetBackfillWindow(); err != nil || w != 0 {
+		t.Fatalf("backfill window without config = %v, %v; want 0", w, err)
+	}
+	if c.Timestamps.Backfill.Speed != 0 {
+		t.Errorf("speed defaulted to %v without a window", c.Timestamps.Backfill.Speed)
+	}
+
+	c = baseSenderCfg()
+	c.Timestamps.Backfill.Window = "168h"
+	if err := c.Validate(); err != nil {
+		t.Fatalf("valid backfill rejected: %v", err)
+	}
+	c.ApplyDefaults()
+	if c.Timestamps.Backfill.Speed != 1 {
+		t.Errorf("speed default = %v, want 1", c.Timestamps.Backfill.Speed)
+	}
+	if w, _ := c.GetBackfillWindow(); w.Hours() != 168 {
+		t.Errorf("window = %v, want 168h", w)
+	}
+
+	for _, bad := range []BackfillConfig{
+		{Window: "a week"},
+		{Window: "-1h"},
+		{Window: "1h", Speed: -2},
+	} {
+		c := baseSenderCfg()
+		c.Timestamps.Backfill = bad
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestSenderAnomalies(t *testing.T) {
+	c := baseSenderCfg()
+	c.Timestamps.Anomalies = AnomaliesConfig{
+		Skew:              ClockSkewConfig{Services: []string{"orders"}, MinMs: -200, MaxMs: 200},
+		Future:            FutureTimestampsConfig{Percentage: 5},
+		OutOfOrderBatches: OutOfOrderBatchesConfig{Percentage: 10},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("valid anomalies rejected: %v", err)
+	}
+	c.ApplyDefaults()
+	a := c.Timestamps.Anomalies
+	if a.Skew.Percentage != 100 {
+		t.Errorf("skew percentage with services = %v, want 100", a.Skew.Percentage)
+	}
+	if a.Future.MinMs != 60_000 || a.Future.MaxMs != 600_000 {
+		t.Errorf("future range = %v..%v, want 60000..600000", a.Future.MinMs, a.Future.MaxMs)
+	}
+	if a.OutOfOrderBatches.DelayMs != 5000 {
+		t.Errorf("out-of-order delay = %d, want 5000", a.OutOfOrderBatches.DelayMs)
+	}
+
+	for name, bad := range map[string]AnomaliesConfig{
+		"percentage over 100": {ChildBeforeParentPercentage: 150},
+		"skew without range":  {Skew: ClockSkewConfig{Percentage: 10}},
+		"skew min over max":   {Skew: ClockSkewConfig{Percentage: 10, MinMs: 5, MaxMs: -5}},
+		"negative future":     {Future: FutureTimestampsConfig{Percentage: 10, MinMs: -1}},
+		"negative delay":      {OutOfOrderBatches: OutOfOrderBatchesConfig{Percentage: 10, DelayMs: -1}},
+	} {
+		c := baseSenderCfg()
+		c.Timestamps.Anomalies = bad
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestSenderCumulative(t *testing.T) {
+	c := baseSenderCfg()
+	c.Metrics.Cumulative.Enabled = true
+	c.ApplyDefaults()
+	if c.Metrics.Cumulative.Rate != 0.1 {
+		t.Errorf("rate default = %v, want 0.1", c.Metrics.Cumulative.Rate)
+	}
+
+	for _, bad := range []CumulativeConfig{
+		{Enabled: true, Rate: -1},
+		{Enabled: true, ResetPercentage: 101},
+	} {
+		c := baseSenderCfg()
+		c.Metrics.Cumulative = bad
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestSenderPatterns(t *testing.T) {
+	c := baseSenderCfg()
+	c.Metrics.Patterns = []ValuePattern{{
+		Diurnal:   DiurnalPattern{Amplitude: 0.3},
+		Anomalies: []ValueAnomaly{{Type: "spike", Start: "10m", Duration: "1m"}, {Type: "step", Start: "1h"}},
+	}}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	c.ApplyDefaults()
+	p := c.Metrics.Patterns[0]
+	if p.Percentage != 100 || p.Diurnal.Period != "24h" {
+		t.Errorf("defaults: percentage %v period %q, want 100, 24h", p.Percentage, p.Diurnal.Period)
+	}
+	if p.Anomalies[0].Magnitude != 5 || p.Anomalies[1].Magnitude != 1 {
+		t.Errorf("magnitude defaults = %v, %v, want 5, 1", p.Anomalies[0].Magnitude, p.Anomalies[1].Magnitude)
+	}
+
+	for _, bad := range []ValuePattern{
+		{Metrics: []string{"["}},
+		{Percentage: 101},
+		{Noise: NoisePattern{StdDev: -1}},
+		{Diurnal: DiurnalPattern{Amplitude: 0.1, Period: "0s"}},
+		{Anomalies: []ValueAnomaly{{Type: "dip"}}},
+		{Anomalies: []ValueAnomaly{{Type: "spike", Start: "soon"}}},
+		{Anomalies: []ValueAnomaly{{Type: "spike", Magnitude: -1}}},
+		{Anomalies: []ValueAnomaly{{Type: "flatline", Every: "1h"}}},
+		{Anomalies: []ValueAnomaly{{Type: "flatline", Duration: "2h", Every: "1h"}}},
+	} {
+		c := baseSenderCfg()
+		c.Metrics.Patterns = []ValuePattern{bad}
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestSenderChurn(t *testing.T) {
+	c := baseSenderCfg()
+	c.Metrics.Churn.Percentage = 10
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	c.ApplyDefaults()
+	if c.Metrics.Churn.GetEvery() != 10*time.Minute {
+		t.Errorf("every default = %q, want 10m", c.Metrics.Churn.Every)
+	}
+	if len(c.Metrics.Churn.Attributes) != 2 {
+		t.Errorf("attributes default = %v, want k8s.pod.name, container.id", c.Metrics.Churn.Attributes)
+	}
+
+	for _, bad := range []ChurnConfig{
+		{Percentage: 101},
+		{Percentage: 10, Every: "often"},
+		{Percentage: 10, Every: "0s"},
+		{Percentage: 10, Attributes: []string{""}},
+	} {
+		c := baseSenderCfg()
+		c.Metrics.Churn = bad
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
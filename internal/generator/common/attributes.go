@@ -162,6 +162,24 @@ func CreateDBAttributes(system, statement string) []*v1.KeyValue {
 	return attrs
 }
 
+// CreateRPCAttributes creates RPC semantic convention attributes
+func CreateRPCAttributes(system, service, method string) []*v1.KeyValue {
+	return []*v1.KeyValue{
+		CreateStringAttribute("rpc.system", system),
+		CreateStringAttribute("rpc.service", service),
+		CreateStringAttribute("rpc.method", method),
+	}
+}
+
+// CreateMessagingAttributes creates messaging semantic convention attributes
+func CreateMessagingAttributes(system, destination, operation string) []*v1.KeyValue {
+	return []*v1.KeyValue{
+		CreateStringAttribute("messaging.system", system),
+		CreateStringAttribute("messaging.destination.name", destination),
+		CreateStringAttribute("messaging.operation", operation),
+	}
+}
+
 // CreateServiceAttributes creates service resource attributes
 func CreateServiceAttributes(serviceName string) []*v1.KeyValue {
 	return []*v1.KeyValue{
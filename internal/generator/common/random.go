@@ -3,24 +3,108 @@ package common
 import (
 	"fmt"
 	"math/rand"
+	"sync"
+	"time"
 )
 
+// rng is the source behind every helper in this package (and, through them,
+// all of the generators). It is randomly seeded by default; SetSeed swaps in a
+// deterministic source so a run can be reproduced byte-for-byte.
+var rng = rand.New(newLockedSource(time.Now().UnixNano()))
+
+// SetSeed reseeds the package random source. A seed of 0 selects a
+// time-based seed, restoring the default non-reproducible behavior.
+func SetSeed(seed int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng = rand.New(newLockedSource(seed))
+}
+
+// lockedSource makes a rand.Source safe for concurrent use, matching the
+// guarantees of the math/rand top-level functions the helpers used to call.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func newLockedSource(seed int64) *lockedSource {
+	return &lockedSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
 // RandomString generates a random string of the specified length
 func RandomString(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = charset[rand.Intn(len(charset))]
+		b[i] = charset[rng.Intn(len(charset))]
+	}
+	return string(b)
+}
+
+// RandomBytes returns n random bytes from the package source. It is used for
+// template trace and span IDs so they are reproducible under SetSeed.
+func RandomBytes(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(rng.Intn(256))
+	}
+	return b
+}
+
+// podSuffixChars are the characters Kubernetes uses in generated name
+// suffixes.
+const podSuffixChars = "bcdfghjklmnpqrstvwxz2456789"
+
+// RandomPodSuffix returns a random generated-name suffix of length n.
+func RandomPodSuffix(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = podSuffixChars[rng.Intn(len(podSuffixChars))]
 	}
 	return string(b)
 }
 
+// RandomUUID returns a random version 4 UUID from the package source.
+func RandomUUID() string {
+	return randomUUID(rng)
+}
+
+// randomUUID returns a version 4 UUID drawn from r.
+func randomUUID(r *rand.Rand) string {
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(r.Intn(256))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
 // RandomInt returns a random integer between min and max (inclusive)
 func RandomInt(min, max int) int {
 	if min >= max {
 		return min
 	}
-	return min + rand.Intn(max-min+1)
+	return min + rng.Intn(max-min+1)
 }
 
 // RandomInt64 returns a random int64 between min and max (inclusive)
@@ -28,22 +112,28 @@ func RandomInt64(min, max int64) int64 {
 	if min >= max {
 		return min
 	}
-	return min + rand.Int63n(max-min+1)
+	return min + rng.Int63n(max-min+1)
 }
 
 // RandomFloat64 returns a random float64 between min and max
 func RandomFloat64(min, max float64) float64 {
-	return min + rand.Float64()*(max-min)
+	return min + rng.Float64()*(max-min)
+}
+
+// RandomNormFloat64 returns a standard normally distributed float64 (mean 0,
+// standard deviation 1)
+func RandomNormFloat64() float64 {
+	return rng.NormFloat64()
 }
 
 // RandomBool returns a random boolean
 func RandomBool() bool {
-	return rand.Intn(2) == 1
+	return rng.Intn(2) == 1
 }
 
 // RandomChoice returns a random element from the slice
 func RandomChoice[T any](choices []T) T {
-	return choices[rand.Intn(len(choices))]
+	return choices[rng.Intn(len(choices))]
 }
 
 // RandomChoiceWeighted returns a random element based on weights
@@ -58,7 +148,7 @@ func RandomChoiceWeighted[T any](choices []T, weights []int) T {
 		totalWeight += w
 	}
 
-	r := rand.Intn(totalWeight)
+	r := rng.Intn(totalWeight)
 	cumulative := 0
 	for i, w := range weights {
 		cumulative += w
@@ -75,7 +165,7 @@ func NormalInt(mean, stdDev int) int {
 	if stdDev <= 0 {
 		return mean
 	}
-	val := rand.NormFloat64()*float64(stdDev) + float64(mean)
+	val := rng.NormFloat64()*float64(stdDev) + float64(mean)
 	result := int(val)
 	if result < 1 {
 		return 1 // Ensure at least 1
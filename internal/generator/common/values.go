@@ -0,0 +1,238 @@
+package common
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// maxPatternRepeat bounds *, + and open-ended {n,} repeats in patterns.
+const maxPatternRepeat = 8
+
+// ValueSpec describes how a declared attribute's values are generated.
+type ValueSpec struct {
+	// Generator is one of enum, int_range, float_range, bool, uuid, pool,
+	// pattern, url or email.
+	Generator string
+
+	// Values and Weights are the enum values (string, int64, float64 or
+	// bool, as parsed by config.ParseTypedValues) and their relative weights.
+	Values  []any
+	Weights []int
+
+	// Min and Max bound int_range and float_range.
+	Min, Max float64
+
+	// Prefix names pool values: <prefix><n> for n below Cardinality.
+	Prefix string
+
+	// Pattern is the regular expression pattern values match.
+	Pattern string
+
+	// Cardinality, when > 0, caps the number of distinct values: each value
+	// is derived from one of Cardinality indexes, so the same index always
+	// yields the same value. Enum and bool values are never capped.
+	Cardinality int64
+}
+
+// ValueGenerator produces values for a declared attribute.
+type ValueGenerator struct {
+	gen         func(r *rand.Rand, n int64) *v1.AnyValue
+	cardinality int64
+	seed        uint64
+}
+
+// NewValueGenerator builds a generator for spec. key seeds the values of a
+// capped generator, so an attribute's value set is stable across runs.
+func NewValueGenerator(key string, spec ValueSpec) (*ValueGenerator, error) {
+	g := &ValueGenerator{cardinality: spec.Cardinality}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	g.seed = h.Sum64()
+
+	switch spec.Generator {
+	case "enum":
+		values := spec.Values
+		weights := spec.Weights
+		if len(weights) == 0 {
+			weights = make([]int, len(values))
+			for i := range weights {
+				weights[i] = 1
+			}
+		}
+		g.cardinality = 0
+		g.gen = func(*rand.Rand, int64) *v1.AnyValue {
+			return anyValue(RandomChoiceWeighted(values, weights))
+		}
+	case "int_range":
+		lo, hi := int64(spec.Min), int64(spec.Max)
+		g.gen = func(r *rand.Rand, _ int64) *v1.AnyValue {
+			return anyValue(lo + r.Int63n(hi-lo+1))
+		}
+	case "float_range":
+		g.gen = func(r *rand.Rand, _ int64) *v1.AnyValue {
+			return anyValue(spec.Min + r.Float64()*(spec.Max-spec.Min))
+		}
+	case "bool":
+		g.cardinality = 0
+		g.gen = func(r *rand.Rand, _ int64) *v1.AnyValue { return anyValue(r.Intn(2) == 1) }
+	case "uuid":
+		g.gen = func(r *rand.Rand, _ int64) *v1.AnyValue { return anyValue(randomUUID(r)) }
+	case "pool":
+		if spec.Cardinality <= 0 {
+			return nil, fmt.Errorf("pool requires cardinality > 0")
+		}
+		g.gen = func(_ *rand.Rand, n int64) *v1.AnyValue {
+			return anyValue(spec.Prefix + strconv.FormatInt(n, 10))
+		}
+	case "pattern":
+		re, err := syntax.Parse(spec.Pattern, syntax.Perl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		g.gen = func(r *rand.Rand, _ int64) *v1.AnyValue {
+			var b strings.Builder
+			generatePattern(r, re, &b)
+			return anyValue(b.String())
+		}
+	case "url":
+		g.gen = func(r *rand.Rand, _ int64) *v1.AnyValue { return anyValue(randomURL(r)) }
+	case "email":
+		g.gen = func(r *rand.Rand, _ int64) *v1.AnyValue { return anyValue(randomEmail(r)) }
+	default:
+		return nil, fmt.Errorf("unknown generator %q", spec.Generator)
+	}
+	return g, nil
+}
+
+// Next returns the next value.
+func (g *ValueGenerator) Next() *v1.AnyValue {
+	if g.cardinality <= 0 {
+		return g.gen(rng, 0)
+	}
+	n := rng.Int63n(g.cardinality)
+	return g.gen(rand.New(&splitMix64{state: g.seed ^ uint64(n)*0x9e3779b97f4a7c15}), n)
+}
+
+// anyValue wraps a string, int64, float64 or bool.
+func anyValue(v any) *v1.AnyValue {
+	switch v := v.(type) {
+	case int64:
+		return &v1.AnyValue{Value: &v1.AnyValue_IntValue{IntValue: v}}
+	case float64:
+		return &v1.AnyValue{Value: &v1.AnyValue_DoubleValue{DoubleValue: v}}
+	case bool:
+		return &v1.AnyValue{Value: &v1.AnyValue_BoolValue{BoolValue: v}}
+	default:
+		return &v1.AnyValue{Value: &v1.AnyValue_StringValue{StringValue: v.(string)}}
+	}
+}
+
+var (
+	urlHosts    = []string{"shop.example.com", "api.example.com", "cdn.example.net", "app.example.io"}
+	urlSegments = []string{"products", "users", "orders", "cart", "search", "items", "reviews", "checkout"}
+	firstNames  = []string{"alex", "sam", "jordan", "taylor", "morgan", "casey", "riley", "jamie"}
+	lastNames   = []string{"smith", "jones", "garcia", "chen", "patel", "kim", "nguyen", "brown"}
+	mailDomains = []string{"example.com", "example.org", "mail.example.net"}
+)
+
+// randomURL returns a plausible URL with an ID path segment and query.
+func randomURL(r *rand.Rand) string {
+	return fmt.Sprintf("https://%s/%s/%d?ref=%s",
+		urlHosts[r.Intn(len(urlHosts))], urlSegments[r.Intn(len(urlSegments))],
+		r.Intn(1000000), urlSegments[r.Intn(len(urlSegments))])
+}
+
+// randomEmail returns a plausible email address.
+func randomEmail(r *rand.Rand) string {
+	return fmt.Sprintf("%s.%s%d@%s", firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))],
+		r.Intn(10000), mailDomains[r.Intn(len(mailDomains))])
+}
+
+// generatePattern writes a random string matching re.
+func generatePattern(r *rand.Rand, re *syntax.Regexp, b *strings.Builder) {
+	repeat := func(lo, hi int) {
+		if hi < 0 {
+			hi = lo + maxPatternRepeat
+		}
+		for i := lo + r.Intn(hi-lo+1); i > 0; i-- {
+			generatePattern(r, re.Sub[0], b)
+		}
+	}
+
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		b.WriteRune(classRune(r, re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteRune(rune('a' + r.Intn(26)))
+	case syntax.OpCapture:
+		generatePattern(r, re.Sub[0], b)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			generatePattern(r, sub, b)
+		}
+	case syntax.OpAlternate:
+		generatePattern(r, re.Sub[r.Intn(len(re.Sub))], b)
+	case syntax.OpStar:
+		repeat(0, maxPatternRepeat)
+	case syntax.OpPlus:
+		repeat(1, 1+maxPatternRepeat)
+	case syntax.OpQuest:
+		repeat(0, 1)
+	case syntax.OpRepeat:
+		repeat(re.Min, re.Max)
+	}
+}
+
+// classRune picks a rune from a character class given as [lo, hi] pairs,
+// preferring printable ASCII so negated classes stay readable.
+func classRune(r *rand.Rand, ranges []rune) rune {
+	pick := func(clipLo, clipHi rune) (rune, bool) {
+		total := 0
+		for i := 0; i < len(ranges); i += 2 {
+			if lo, hi := max(ranges[i], clipLo), min(ranges[i+1], clipHi); lo <= hi {
+				total += int(hi - lo + 1)
+			}
+		}
+		if total == 0 {
+			return 0, false
+		}
+		k := r.Intn(total)
+		for i := 0; i < len(ranges); i += 2 {
+			if lo, hi := max(ranges[i], clipLo), min(ranges[i+1], clipHi); lo <= hi {
+				if k < int(hi-lo+1) {
+					return lo + rune(k), true
+				}
+				k -= int(hi - lo + 1)
+			}
+		}
+		return 0, false
+	}
+	if c, ok := pick(0x20, 0x7e); ok {
+		return c
+	}
+	c, _ := pick(0, 0x10ffff)
+	return c
+}
+
+// splitMix64 is a small rand.Source64 used to derive a capped generator's
+// value from its index.
+type splitMix64 struct{ state uint64 }
+
+func (s *splitMix64) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *splitMix64) Int63() int64    { return int64(s.Uint64() >> 1) }
+func (s *splitMix64) Seed(seed int64) { s.state = uint64(seed) }
@@ -0,0 +1,470 @@
+// Package inventory models the infrastructure generated telemetry comes
+// from: clusters of nodes running namespaces of deployments, pods and
+// containers, and standalone hosts, each in a cloud region and availability
+// zone. It is generated once per run and shared by the metrics, traces and
+// logs generators, so every signal describes the same entities.
+package inventory
+
+import (
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// cloudProvider is the cloud every host runs in.
+const cloudProvider = "aws"
+
+// Entity is something telemetry describes: a cluster, host, node, namespace,
+// deployment, pod or container.
+type Entity interface {
+	// Attributes returns the entity's identifying attributes, including
+	// those of the entities it belongs to (a pod's include its node's and
+	// namespace's). The map is shared; callers must not modify it.
+	Attributes() map[string]string
+}
+
+// Inventory is the set of entities of one run.
+type Inventory struct {
+	Clusters []*Cluster
+	// Hosts are the hosts outside any cluster; nodes' hosts are in
+	// Cluster.Nodes.
+	Hosts []*Host
+
+	cfg      config.InventoryConfig
+	services map[string]*Deployment
+	order    []*Deployment // services in registration order
+
+	// entities caches Entities per level until a service is added.
+	entities map[Level][]Entity
+}
+
+// Cluster is a Kubernetes cluster in one cloud region.
+type Cluster struct {
+	Name       string
+	Region     string
+	Nodes      []*Node
+	Namespaces []*Namespace
+
+	scheduled int // pods placed so far, for round-robin scheduling
+	attrs     map[string]string
+}
+
+// Host is a machine: a cluster node's or a standalone one.
+type Host struct {
+	Name   string
+	ID     string
+	OSType string
+	Region string
+	Zone   string
+
+	attrs map[string]string
+}
+
+// Node is a cluster node running on a host of the same name.
+type Node struct {
+	Cluster *Cluster
+	Host    *Host
+
+	attrs map[string]string
+}
+
+// Namespace is a Kubernetes namespace in one cluster.
+type Namespace struct {
+	Name        string
+	Cluster     *Cluster
+	Deployments []*Deployment
+
+	attrs map[string]string
+}
+
+// Deployment is a workload whose pods report as one service.
+type Deployment struct {
+	Name      string
+	Namespace *Namespace
+	Pods      []*Pod
+
+	attrs map[string]string
+}
+
+// Pod is one replica of a deployment, scheduled on a node.
+type Pod struct {
+	Name       string
+	UID        string
+	Deployment *Deployment
+	Node       *Node
+	Containers []*Container
+
+	attrs map[string]string
+}
+
+// Container is one container of a pod.
+type Container struct {
+	Name string
+	ID   string
+	Pod  *Pod
+
+	attrs map[string]string
+}
+
+// workloadNames name the deployments each namespace gets besides the trace
+// services, in turn across every namespace of every cluster so each name,
+// and the service.name it reports, is used once.
+var workloadNames = []string{"web", "api", "worker", "scheduler", "cache", "ingest", "notifier", "search"}
+
+// clusterEnvironments prefix cluster names in turn.
+var clusterEnvironments = []string{"prod", "staging", "dev"}
+
+// New generates an inventory sized by cfg; unset sizes use their defaults.
+func New(cfg config.InventoryConfig) *Inventory {
+	cfg.ApplyDefaults()
+	inv := &Inventory{cfg: cfg, services: make(map[string]*Deployment)}
+
+	regions := common.NewRegionGenerator()
+	workloads := 0 // default deployments so far
+	for i := 0; i < cfg.Clusters; i++ {
+		region := regions.Generate()
+		cluster := &Cluster{
+			Name:   fmt.Sprintf("%s-%s-cluster", clusterEnvironments[i%len(clusterEnvironments)], region),
+			Region: region,
+		}
+		nodeNames := common.NewNodeNameGenerator(cluster.Name)
+		for j := 0; j < cfg.NodesPerCluster; j++ {
+			cluster.Nodes = append(cluster.Nodes, &Node{
+				Cluster: cluster,
+				Host:    newHost(nodeNames.Generate(), "linux", region, j),
+			})
+		}
+		for _, name := range cfg.Namespaces {
+			namespace := cluster.namespace(name)
+			for k := 0; k < cfg.Deployments; k++ {
+				n := workloads
+				workloads++
+				inv.deploy(namespace, workloadNames[n%len(workloadNames)]+suffix(n, len(workloadNames)))
+			}
+		}
+		inv.Clusters = append(inv.Clusters, cluster)
+	}
+
+	hostNames := common.NewHostnameGenerator()
+	for i := 0; i < cfg.Hosts; i++ {
+		region := inv.Clusters[i%len(inv.Clusters)].Region
+		inv.Hosts = append(inv.Hosts, newHost(hostNames.Generate(), "linux", region, i))
+	}
+	return inv
+}
+
+// Default generates an inventory of the default size, for generators used
+// without a shared one.
+func Default() *Inventory {
+	return New(config.InventoryConfig{})
+}
+
+// suffix numbers the n-th repeat of a name from a list of size names.
+func suffix(k, names int) string {
+	if k < names {
+		return ""
+	}
+	return fmt.Sprintf("-%d", k/names+1)
+}
+
+// newHost creates a host in region, in the i-th zone in turn.
+func newHost(name, osType, region string, i int) *Host {
+	return &Host{
+		Name:   name,
+		ID:     "i-" + hex.EncodeToString(common.RandomBytes(9))[:17],
+		OSType: osType,
+		Region: region,
+		Zone:   region + string(rune('a'+i%3)),
+	}
+}
+
+// namespace returns the cluster's namespace with the given name, adding it
+// if needed.
+func (c *Cluster) namespace(name string) *Namespace {
+	for _, ns := range c.Namespaces {
+		if ns.Name == name {
+			return ns
+		}
+	}
+	ns := &Namespace{Name: name, Cluster: c}
+	c.Namespaces = append(c.Namespaces, ns)
+	return ns
+}
+
+// deploy adds a deployment to namespace with cfg.Replicas pods, scheduled on
+// the cluster's nodes in turn.
+func (inv *Inventory) deploy(namespace *Namespace, name string) *Deployment {
+	d := &Deployment{Name: name, Namespace: namespace}
+	cluster := namespace.Cluster
+	replicaSet := common.RandomPodSuffix(10)
+	for i := 0; i < inv.cfg.Replicas; i++ {
+		pod := &Pod{
+			Name:       fmt.Sprintf("%s-%s-%s", name, replicaSet, common.RandomPodSuffix(5)),
+			UID:        common.RandomUUID(),
+			Deployment: d,
+			Node:       cluster.Nodes[cluster.scheduled%len(cluster.Nodes)],
+		}
+		cluster.scheduled++
+		for _, container := range inv.cfg.Containers {
+			pod.Containers = append(pod.Containers, &Container{
+				Name: container,
+				ID:   hex.EncodeToString(common.RandomBytes(32)),
+				Pod:  pod,
+			})
+		}
+		d.Pods = append(d.Pods, pod)
+	}
+	namespace.Deployments = append(namespace.Deployments, d)
+	inv.entities = nil
+	return d
+}
+
+// Service returns the deployment running the named service, adding one in
+// namespace (default "default") if the service is new. New services are
+// placed on the clusters in turn.
+func (inv *Inventory) Service(name, namespace string) *Deployment {
+	if d, ok := inv.services[name]; ok {
+		return d
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	cluster := inv.Clusters[len(inv.order)%len(inv.Clusters)]
+	d := inv.deploy(cluster.namespace(namespace), name)
+	inv.services[name] = d
+	inv.order = append(inv.order, d)
+	return d
+}
+
+// Services returns the deployments added by Service, in order, or every
+// deployment when there are none.
+func (inv *Inventory) Services() []*Deployment {
+	if len(inv.order) > 0 {
+		return inv.order
+	}
+	var all []*Deployment
+	for _, cluster := range inv.Clusters {
+		for _, ns := range cluster.Namespaces {
+			all = append(all, ns.Deployments...)
+		}
+	}
+	return all
+}
+
+// Level is how specific an entity is.
+type Level int
+
+const (
+	LevelNone Level = iota
+	LevelCluster
+	LevelHost
+	LevelNode
+	LevelNamespace
+	LevelDeployment
+	LevelPod
+	LevelContainer
+)
+
+// levelKeys maps attribute keys to the least specific level that has them.
+var levelKeys = map[string]Level{
+	"k8s.cluster.name":        LevelCluster,
+	"host.name":               LevelHost,
+	"host.id":                 LevelHost,
+	"os.type":                 LevelHost,
+	"cloud.provider":          LevelHost,
+	"cloud.region":            LevelHost,
+	"cloud.availability_zone": LevelHost,
+	"k8s.node.name":           LevelNode,
+	"k8s.namespace.name":      LevelNamespace,
+	"k8s.deployment.name":     LevelDeployment,
+	"service.name":            LevelDeployment,
+	"k8s.pod.name":            LevelPod,
+	"k8s.pod.uid":             LevelPod,
+	"container.name":          LevelContainer,
+	"container.id":            LevelContainer,
+}
+
+// LevelFor returns the level of entity whose attributes cover every
+// inventory key among keys, or LevelNone if keys has none. Namespaced keys
+// combined with host or node keys need a pod, which has both.
+func LevelFor(keys []string) Level {
+	has := make(map[Level]bool)
+	for _, key := range keys {
+		if level, ok := levelKeys[key]; ok {
+			has[level] = true
+		}
+	}
+	switch {
+	case has[LevelContainer]:
+		return LevelContainer
+	case has[LevelPod], (has[LevelDeployment] || has[LevelNamespace]) && (has[LevelNode] || has[LevelHost]):
+		return LevelPod
+	case has[LevelDeployment]:
+		return LevelDeployment
+	case has[LevelNamespace]:
+		return LevelNamespace
+	case has[LevelNode], has[LevelCluster] && has[LevelHost]:
+		return LevelNode
+	case has[LevelHost]:
+		return LevelHost
+	case has[LevelCluster]:
+		return LevelCluster
+	default:
+		return LevelNone
+	}
+}
+
+// IsKey reports whether key is an attribute inventory entities provide.
+func IsKey(key string) bool {
+	_, ok := levelKeys[key]
+	return ok
+}
+
+// Entities returns every entity of the given level, in inventory order.
+// Hosts include the nodes' hosts.
+func (inv *Inventory) Entities(level Level) []Entity {
+	if entities, ok := inv.entities[level]; ok {
+		return entities
+	}
+	var entities []Entity
+	for _, cluster := range inv.Clusters {
+		switch level {
+		case LevelCluster:
+			entities = append(entities, cluster)
+		case LevelHost, LevelNode:
+			for _, node := range cluster.Nodes {
+				if level == LevelHost {
+					entities = append(entities, node.Host)
+				} else {
+					entities = append(entities, node)
+				}
+			}
+		}
+		for _, ns := range cluster.Namespaces {
+			if level == LevelNamespace {
+				entities = append(entities, ns)
+			}
+			for _, d := range ns.Deployments {
+				if level == LevelDeployment {
+					entities = append(entities, d)
+				}
+				for _, pod := range d.Pods {
+					if level == LevelPod {
+						entities = append(entities, pod)
+					}
+					if level == LevelContainer {
+						for _, c := range pod.Containers {
+							entities = append(entities, c)
+						}
+					}
+				}
+			}
+		}
+	}
+	if level == LevelHost {
+		for _, host := range inv.Hosts {
+			entities = append(entities, host)
+		}
+	}
+	if inv.entities == nil {
+		inv.entities = make(map[Level][]Entity)
+	}
+	inv.entities[level] = entities
+	return entities
+}
+
+// Attributes returns the cluster's attributes.
+func (c *Cluster) Attributes() map[string]string {
+	if c.attrs == nil {
+		c.attrs = map[string]string{
+			"k8s.cluster.name": c.Name,
+			"cloud.provider":   cloudProvider,
+			"cloud.region":     c.Region,
+		}
+	}
+	return c.attrs
+}
+
+// Attributes returns the host's attributes.
+func (h *Host) Attributes() map[string]string {
+	if h.attrs == nil {
+		h.attrs = map[string]string{
+			"host.name":               h.Name,
+			"host.id":                 h.ID,
+			"os.type":                 h.OSType,
+			"cloud.provider":          cloudProvider,
+			"cloud.region":            h.Region,
+			"cloud.availability_zone": h.Zone,
+		}
+	}
+	return h.attrs
+}
+
+// Attributes returns the node's and its host's attributes.
+func (n *Node) Attributes() map[string]string {
+	if n.attrs == nil {
+		n.attrs = with(n.Host.Attributes(),
+			"k8s.cluster.name", n.Cluster.Name,
+			"k8s.node.name", n.Host.Name)
+	}
+	return n.attrs
+}
+
+// Attributes returns the namespace's and its cluster's attributes.
+func (ns *Namespace) Attributes() map[string]string {
+	if ns.attrs == nil {
+		ns.attrs = with(ns.Cluster.Attributes(), "k8s.namespace.name", ns.Name)
+	}
+	return ns.attrs
+}
+
+// Attributes returns the deployment's and its namespace's attributes.
+func (d *Deployment) Attributes() map[string]string {
+	if d.attrs == nil {
+		d.attrs = with(d.Namespace.Attributes(),
+			"k8s.deployment.name", d.Name,
+			"service.name", d.Name)
+	}
+	return d.attrs
+}
+
+// Attributes returns the pod's, its deployment's and its node's attributes.
+func (p *Pod) Attributes() map[string]string {
+	if p.attrs == nil {
+		p.attrs = with(p.Node.Attributes(), "k8s.pod.name", p.Name, "k8s.pod.uid", p.UID)
+		maps.Copy(p.attrs, p.Deployment.Attributes())
+	}
+	return p.attrs
+}
+
+// Attributes returns the container's and its pod's attributes.
+func (c *Container) Attributes() map[string]string {
+	if c.attrs == nil {
+		c.attrs = with(c.Pod.Attributes(), "container.name", c.Name, "container.id", c.ID)
+	}
+	return c.attrs
+}
+
+// with returns a copy of attrs with the given key/value pairs added.
+func with(attrs map[string]string, kvs ...string) map[string]string {
+	out := maps.Clone(attrs)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		out[kvs[i]] = kvs[i+1]
+	}
+	return out
+}
+
+// KeyValues returns an entity's attributes as OTLP attributes, sorted by key.
+func KeyValues(e Entity) []*commonpb.KeyValue {
+	attrs := e.Attributes()
+	kvs := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, key := range slices.Sorted(maps.Keys(attrs)) {
+		kvs = append(kvs, common.CreateStringAttribute(key, attrs[key]))
+	}
+	return kvs
+}
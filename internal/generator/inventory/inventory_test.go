@@ -0,0 +1,100 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	inv := New(config.InventoryConfig{Clusters: 2, NodesPerCluster: 3, Namespaces: []string{"prod"}, Deployments: 2, Replicas: 2, Hosts: 4})
+	if len(inv.Clusters) != 2 || len(inv.Hosts) != 4 {
+		t.Fatalf("got %d clusters and %d hosts, want 2 and 4", len(inv.Clusters), len(inv.Hosts))
+	}
+	if got := len(inv.Entities(LevelPod)); got != 8 {
+		t.Fatalf("got %d pods, want 8", got)
+	}
+	if got := len(inv.Entities(LevelHost)); got != 10 {
+		t.Fatalf("got %d hosts, want 10 (6 nodes and 4 standalone)", got)
+	}
+
+	for _, e := range inv.Entities(LevelContainer) {
+		c := e.(*Container)
+		node := c.Pod.Node
+		if node.Cluster != c.Pod.Deployment.Namespace.Cluster {
+			t.Fatalf("pod %s runs on node %s of another cluster", c.Pod.Name, node.Host.Name)
+		}
+		attrs := c.Attributes()
+		if attrs["k8s.node.name"] != node.Host.Name || attrs["host.name"] != node.Host.Name {
+			t.Fatalf("container attributes name another node: %v", attrs)
+		}
+		if attrs["k8s.cluster.name"] != node.Cluster.Name || !strings.HasPrefix(attrs["cloud.availability_zone"], node.Cluster.Region) {
+			t.Fatalf("container attributes name another cluster or region: %v", attrs)
+		}
+		if !strings.HasPrefix(c.Pod.Name, c.Pod.Deployment.Name+"-") {
+			t.Fatalf("pod %s not named after deployment %s", c.Pod.Name, c.Pod.Deployment.Name)
+		}
+	}
+}
+
+func TestDeploymentNamesUnique(t *testing.T) {
+	inv := New(config.InventoryConfig{Clusters: 2, Deployments: 3})
+	seen := make(map[string]bool)
+	for _, d := range inv.Services() {
+		name := d.Attributes()["service.name"]
+		if seen[name] {
+			t.Fatalf("service.name %q used by two deployments", name)
+		}
+		seen[name] = true
+	}
+	if len(seen) != 18 {
+		t.Errorf("got %d deployments, want 18", len(seen))
+	}
+}
+
+func TestService(t *testing.T) {
+	inv := Default()
+	if got := len(inv.Services()); got != 6 {
+		t.Fatalf("without services, Services() = %d deployments, want all 6", got)
+	}
+	checkout := inv.Service("checkout", "shop")
+	if inv.Service("checkout", "other") != checkout {
+		t.Fatal("Service added a second deployment for a known service")
+	}
+	if checkout.Namespace.Name != "shop" || len(checkout.Pods) != 3 {
+		t.Fatalf("checkout: namespace %q, %d pods", checkout.Namespace.Name, len(checkout.Pods))
+	}
+	if services := inv.Services(); len(services) != 1 || services[0] != checkout {
+		t.Fatalf("Services() = %d deployments, want checkout", len(services))
+	}
+	if got := checkout.Pods[0].Attributes()["service.name"]; got != "checkout" {
+		t.Fatalf("pod service.name = %q", got)
+	}
+	if got := len(inv.Entities(LevelPod)); got != 21 {
+		t.Fatalf("got %d pods after adding a service, want 21", got)
+	}
+}
+
+func TestLevelFor(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want Level
+	}{
+		{[]string{"cpu"}, LevelNone},
+		{[]string{"k8s.cluster.name"}, LevelCluster},
+		{[]string{"host.name", "os.type", "cpu"}, LevelHost},
+		{[]string{"k8s.cluster.name", "host.name"}, LevelNode},
+		{[]string{"k8s.cluster.name", "k8s.node.name"}, LevelNode},
+		{[]string{"k8s.cluster.name", "k8s.namespace.name"}, LevelNamespace},
+		{[]string{"service.name", "success"}, LevelDeployment},
+		{[]string{"k8s.namespace.name", "k8s.node.name"}, LevelPod},
+		{[]string{"k8s.cluster.name", "k8s.namespace.name", "k8s.pod.name", "k8s.node.name"}, LevelPod},
+		{[]string{"k8s.pod.name", "container.name"}, LevelContainer},
+	}
+	for _, tt := range tests {
+		if got := LevelFor(tt.keys); got != tt.want {
+			t.Errorf("LevelFor(%v) = %d, want %d", tt.keys, got, tt.want)
+		}
+	}
+}
@@ -3,11 +3,14 @@ package logs
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
 	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/inventory"
 	otlpcollectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
 	otlplogs "go.opentelemetry.io/proto/otlp/logs/v1"
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
@@ -17,25 +20,37 @@ import (
 
 // Generator is the main logs generator
 type Generator struct {
-	config       *config.LogsConfig
-	serviceNames []string
-	outputDir    string
-	prefix       string
+	config    *config.LogsConfig
+	inventory *inventory.Inventory
+	services  []*inventory.Deployment // application services
+	outputDir string
+	prefix    string
 }
 
-// NewGenerator creates a new logs generator
+// NewGenerator creates a new logs generator. It uses a default inventory
+// unless UseInventory sets a shared one.
 func NewGenerator(cfg *config.LogsConfig, outputDir, prefix string) *Generator {
-	// Generate service names for application logs
-	serviceNames := make([]string, cfg.Types.Application.Services)
-	for i := 0; i < cfg.Types.Application.Services; i++ {
-		serviceNames[i] = fmt.Sprintf("app-service-%d", i+1)
+	return &Generator{
+		config:    cfg,
+		outputDir: outputDir,
+		prefix:    prefix,
 	}
+}
 
-	return &Generator{
-		config:       cfg,
-		serviceNames: serviceNames,
-		outputDir:    outputDir,
-		prefix:       prefix,
+// UseInventory makes logs come from entities of the inventory shared with
+// the other signals. Application and HTTP access logs are written by pods of
+// the first types.application.services services (the trace services when
+// traces are generated), adding app-service-N services to make up the count;
+// system logs are written by hosts.
+func (g *Generator) UseInventory(inv *inventory.Inventory) {
+	g.inventory = inv
+	g.services = inv.Services()
+	n := g.config.Types.Application.Services
+	if n > 0 && n < len(g.services) {
+		g.services = g.services[:n]
+	}
+	for i := len(g.services); i < n; i++ {
+		g.services = append(g.services, inv.Service(fmt.Sprintf("app-service-%d", i+1), ""))
 	}
 }
 
@@ -54,24 +69,39 @@ func (g *Generator) Generate(writeJSON bool) error {
 
 	logs := make([]*LogTemplate, 0, g.config.Count)
 
+	if g.inventory == nil {
+		g.UseInventory(inventory.Default())
+	}
+	// System logs come from cluster nodes and standalone hosts
+	hosts := slices.Clone(g.inventory.Entities(inventory.LevelNode))
+	for _, host := range g.inventory.Hosts {
+		hosts = append(hosts, host)
+	}
+
 	// Generate HTTP access logs
 	fmt.Printf("Generating %d HTTP access logs...\n", httpCount)
 	for i := 0; i < httpCount; i++ {
-		logs = append(logs, GenerateHTTPAccessLog())
+		log := GenerateHTTPAccessLog()
+		log.Entity = g.container(common.RandomChoice(g.services))
+		logs = append(logs, log)
 	}
 
 	// Generate application logs
 	fmt.Printf("Generating %d application logs...\n", appCount)
 	for i := 0; i < appCount; i++ {
-		service := common.RandomChoice(g.serviceNames)
+		service := common.RandomChoice(g.services)
 		severity := common.RandomLogLevel()
-		logs = append(logs, GenerateApplicationLog(service, severity))
+		log := GenerateApplicationLog(service.Name, severity)
+		log.Entity = g.container(service)
+		logs = append(logs, log)
 	}
 
 	// Generate system logs
 	fmt.Printf("Generating %d system logs...\n", sysCount)
 	for i := 0; i < sysCount; i++ {
-		logs = append(logs, GenerateSystemLog())
+		log := GenerateSystemLog()
+		log.Entity = common.RandomChoice(hosts)
+		logs = append(logs, log)
 	}
 
 	// Print statistics
@@ -96,6 +126,11 @@ func (g *Generator) Generate(writeJSON bool) error {
 	return nil
 }
 
+// container returns the application container of a random pod of service.
+func (g *Generator) container(service *inventory.Deployment) inventory.Entity {
+	return common.RandomChoice(service.Pods).Containers[0]
+}
+
 // calculateSeverityCounts counts logs by severity
 func (g *Generator) calculateSeverityCounts(logs []*LogTemplate) map[string]int {
 	counts := make(map[string]int)
@@ -135,43 +170,36 @@ func (g *Generator) writeLogs(logs []*LogTemplate, writeJSON bool) error {
 	return nil
 }
 
-// logsToOTLP converts log templates to OTLP ExportLogsServiceRequest
+// logsToOTLP converts log templates to OTLP ExportLogsServiceRequest, with
+// one ResourceLogs per entity that wrote logs, in order of each entity's
+// first log. Logs without an entity go under a telemetry-generator resource.
 func (g *Generator) logsToOTLP(logs []*LogTemplate) *otlpcollectorlogs.ExportLogsServiceRequest {
 	request := &otlpcollectorlogs.ExportLogsServiceRequest{
-		ResourceLogs: []*otlplogs.ResourceLogs{
-			{
-				Resource: &resourcepb.Resource{
-					Attributes: []*commonpb.KeyValue{
-						{
-							Key: "service.name",
-							Value: &commonpb.AnyValue{
-								Value: &commonpb.AnyValue_StringValue{
-									StringValue: "telemetry-generator",
-								},
-							},
-						},
-					},
-				},
-				ScopeLogs: []*otlplogs.ScopeLogs{
-					{
-						Scope: &commonpb.InstrumentationScope{
-							Name:    "telemetry-generator",
-							Version: "1.0.0",
-						},
-						LogRecords: make([]*otlplogs.LogRecord, 0),
-					},
-				},
-			},
-		},
+		ResourceLogs: make([]*otlplogs.ResourceLogs, 0),
 	}
 
-	// Convert each log template
+	byEntity := make(map[inventory.Entity]*otlplogs.ScopeLogs)
 	for _, logTemplate := range logs {
-		logRecord := g.templateToOTLP(logTemplate)
-		request.ResourceLogs[0].ScopeLogs[0].LogRecords = append(
-			request.ResourceLogs[0].ScopeLogs[0].LogRecords,
-			logRecord,
-		)
+		scopeLogs, ok := byEntity[logTemplate.Entity]
+		if !ok {
+			attrs := []*commonpb.KeyValue{common.CreateStringAttribute("service.name", "telemetry-generator")}
+			if logTemplate.Entity != nil {
+				attrs = inventory.KeyValues(logTemplate.Entity)
+			}
+			scopeLogs = &otlplogs.ScopeLogs{
+				Scope: &commonpb.InstrumentationScope{
+					Name:    "telemetry-generator",
+					Version: "1.0.0",
+				},
+				LogRecords: make([]*otlplogs.LogRecord, 0),
+			}
+			request.ResourceLogs = append(request.ResourceLogs, &otlplogs.ResourceLogs{
+				Resource:  &resourcepb.Resource{Attributes: attrs},
+				ScopeLogs: []*otlplogs.ScopeLogs{scopeLogs},
+			})
+			byEntity[logTemplate.Entity] = scopeLogs
+		}
+		scopeLogs.LogRecords = append(scopeLogs.LogRecords, g.templateToOTLP(logTemplate))
 	}
 
 	return request
@@ -192,9 +220,9 @@ func (g *Generator) templateToOTLP(template *LogTemplate) *otlplogs.LogRecord {
 		Attributes: make([]*commonpb.KeyValue, 0),
 	}
 
-	// Add attributes
-	for key, value := range template.Attributes {
-		record.Attributes = append(record.Attributes, g.createAttribute(key, value))
+	// Add attributes in sorted key order so output is reproducible under a seed
+	for _, key := range slices.Sorted(maps.Keys(template.Attributes)) {
+		record.Attributes = append(record.Attributes, g.createAttribute(key, template.Attributes[key]))
 	}
 
 	// Add log type attribute
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/inventory"
 )
 
 // LogType represents the type of log
@@ -21,6 +22,10 @@ type LogTemplate struct {
 	Severity   string
 	Body       string
 	Attributes map[string]interface{}
+
+	// Entity is the container or host that wrote the log; it becomes the
+	// log's resource.
+	Entity inventory.Entity
 }
 
 // GenerateHTTPAccessLog generates an HTTP access log
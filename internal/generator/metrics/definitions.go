@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	"gopkg.in/yaml.v3"
+)
+
+// CustomCategory is the category of metrics loaded from a definitions file.
+// It isn't a config.MetricCategories key, so metrics.temporality.default
+// applies to custom metrics that don't set their own temporality.
+const CustomCategory = "custom"
+
+// definitionsFile is the layout of metrics.definitions_file.
+type definitionsFile struct {
+	Metrics []definitionSpec `yaml:"metrics"`
+}
+
+// definitionSpec is one custom metric in a definitions file.
+type definitionSpec struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Unit        string `yaml:"unit"`
+
+	// Type is gauge, sum, histogram, exponential_histogram or summary.
+	Type string `yaml:"type"`
+	// Temporality of sums and histograms: cumulative or delta (default:
+	// metrics.temporality.default).
+	Temporality string `yaml:"temporality"`
+
+	// Value bounds the template values; unset uses the unit's default range.
+	Value struct {
+		Min float64 `yaml:"min"`
+		Max float64 `yaml:"max"`
+	} `yaml:"value"`
+
+	// Histogram shape; unset fields use metrics.histograms.
+	Buckets   []float64 `yaml:"buckets"`
+	Scale     *int32    `yaml:"scale"`
+	Quantiles []float64 `yaml:"quantiles"`
+
+	Dimensions []dimensionSpec `yaml:"dimensions"`
+}
+
+// dimensionSpec is a dimension key and, optionally, how its values are
+// generated. Without a generator the key gets the built-in values for
+// well-known keys (host.name, k8s.pod.name, ...) or a generic value.
+type dimensionSpec struct {
+	Key         string   `yaml:"key"`
+	Generator   string   `yaml:"generator"`
+	Values      []string `yaml:"values"`
+	Weights     []int    `yaml:"weights"`
+	Type        string   `yaml:"type"`
+	Min         float64  `yaml:"min"`
+	Max         float64  `yaml:"max"`
+	Prefix      string   `yaml:"prefix"`
+	Pattern     string   `yaml:"pattern"`
+	Cardinality int64    `yaml:"cardinality"`
+}
+
+var metricTypes = map[string]MetricType{
+	"gauge":                 MetricTypeGauge,
+	"sum":                   MetricTypeSum,
+	"histogram":             MetricTypeHistogram,
+	"exponential_histogram": MetricTypeExponentialHistogram,
+	"summary":               MetricTypeSummary,
+}
+
+// LoadDefinitions reads custom metric definitions from a YAML file.
+func LoadDefinitions(path string) ([]MetricDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var file definitionsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	defs := make([]MetricDefinition, 0, len(file.Metrics))
+	names := make(map[string]bool, len(file.Metrics))
+	for i, spec := range file.Metrics {
+		def, err := spec.definition()
+		if err != nil {
+			return nil, fmt.Errorf("%s: metrics[%d]: %w", path, i, err)
+		}
+		if names[def.Name] {
+			return nil, fmt.Errorf("%s: metrics[%d]: duplicate name %q", path, i, def.Name)
+		}
+		names[def.Name] = true
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+// definition validates the spec and converts it to a MetricDefinition.
+func (s definitionSpec) definition() (MetricDefinition, error) {
+	if s.Name == "" {
+		return MetricDefinition{}, fmt.Errorf("name is required")
+	}
+	metricType, ok := metricTypes[s.Type]
+	if !ok {
+		return MetricDefinition{}, fmt.Errorf("%s: type must be gauge, sum, histogram, exponential_histogram or summary, got %q", s.Name, s.Type)
+	}
+	switch s.Temporality {
+	case "", "cumulative", "delta":
+	default:
+		return MetricDefinition{}, fmt.Errorf("%s: temporality must be cumulative or delta, got %q", s.Name, s.Temporality)
+	}
+	if s.Value.Max < s.Value.Min {
+		return MetricDefinition{}, fmt.Errorf("%s: value.max must be >= value.min", s.Name)
+	}
+	for i := 1; i < len(s.Buckets); i++ {
+		if s.Buckets[i] <= s.Buckets[i-1] {
+			return MetricDefinition{}, fmt.Errorf("%s: buckets must be strictly ascending", s.Name)
+		}
+	}
+	if s.Scale != nil && (*s.Scale < -10 || *s.Scale > 20) {
+		return MetricDefinition{}, fmt.Errorf("%s: scale must be between -10 and 20, got %d", s.Name, *s.Scale)
+	}
+	if metricType == MetricTypeExponentialHistogram && s.Value.Max > s.Value.Min && s.Value.Max <= 0 {
+		// Exponential buckets cover magnitudes; the range sizes them.
+		return MetricDefinition{}, fmt.Errorf("%s: exponential histograms need a positive value.max, got %v", s.Name, s.Value.Max)
+	}
+	for _, q := range s.Quantiles {
+		if q < 0 || q > 1 {
+			return MetricDefinition{}, fmt.Errorf("%s: quantiles must be between 0 and 1, got %v", s.Name, q)
+		}
+	}
+
+	def := MetricDefinition{
+		Name:        s.Name,
+		Description: s.Description,
+		Unit:        s.Unit,
+		Type:        metricType,
+		Category:    CustomCategory,
+		Temporality: s.Temporality,
+		ValueMin:    s.Value.Min,
+		ValueMax:    s.Value.Max,
+		Buckets:     s.Buckets,
+		Scale:       s.Scale,
+		Quantiles:   s.Quantiles,
+	}
+	for _, d := range s.Dimensions {
+		if d.Key == "" {
+			return MetricDefinition{}, fmt.Errorf("%s: dimension key is required", s.Name)
+		}
+		if slices.Contains(def.Dimensions, d.Key) {
+			return MetricDefinition{}, fmt.Errorf("%s: duplicate dimension %q", s.Name, d.Key)
+		}
+		def.Dimensions = append(def.Dimensions, d.Key)
+		if d.Generator == "" {
+			continue
+		}
+		values, err := d.valueGenerator(s.Name)
+		if err != nil {
+			return MetricDefinition{}, fmt.Errorf("%s: dimension %s: %w", s.Name, d.Key, err)
+		}
+		if def.DimensionValues == nil {
+			def.DimensionValues = make(map[string]*common.ValueGenerator)
+		}
+		def.DimensionValues[d.Key] = values
+	}
+	return def, nil
+}
+
+// valueGenerator builds the dimension's value generator, seeded by metric
+// and key so each metric's value set is stable across runs.
+func (d dimensionSpec) valueGenerator(metric string) (*common.ValueGenerator, error) {
+	if d.Cardinality < 0 {
+		return nil, fmt.Errorf("cardinality must be non-negative")
+	}
+	switch d.Generator {
+	case "enum":
+		if len(d.Values) == 0 {
+			return nil, fmt.Errorf("values are required for enum")
+		}
+		if len(d.Weights) > 0 && len(d.Weights) != len(d.Values) {
+			return nil, fmt.Errorf("weights must match values")
+		}
+		for _, w := range d.Weights {
+			if w <= 0 {
+				return nil, fmt.Errorf("weights must be > 0")
+			}
+		}
+	case "int_range", "float_range":
+		if d.Max < d.Min {
+			return nil, fmt.Errorf("max must be >= min")
+		}
+	}
+	enum, err := config.ParseTypedValues(d.Type, d.Values)
+	if err != nil {
+		return nil, err
+	}
+	return common.NewValueGenerator(metric+"/"+d.Key, common.ValueSpec{
+		Generator:   d.Generator,
+		Values:      enum,
+		Weights:     d.Weights,
+		Min:         d.Min,
+		Max:         d.Max,
+		Prefix:      d.Prefix,
+		Pattern:     d.Pattern,
+		Cardinality: d.Cardinality,
+	})
+}
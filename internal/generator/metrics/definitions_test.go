@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/inventory"
+)
+
+const testDefinitions = `
+metrics:
+  - name: checkout.cart.value
+    unit: USD
+    type: gauge
+    value: {min: 5, max: 50}
+    dimensions:
+      - key: host.name
+      - key: tier
+        generator: enum
+        values: [free, pro]
+      - key: shard
+        generator: int_range
+        min: 1
+        max: 4
+  - name: checkout.orders
+    type: sum
+    temporality: delta
+  - name: checkout.latency
+    type: histogram
+    buckets: [10, 100, 1000]
+`
+
+func writeDefinitions(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "defs.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	defs, err := LoadDefinitions(writeDefinitions(t, testDefinitions))
+	if err != nil {
+		t.Fatalf("LoadDefinitions: %v", err)
+	}
+	if len(defs) != 3 {
+		t.Fatalf("got %d definitions, want 3", len(defs))
+	}
+
+	gauge := defs[0]
+	if lo, hi := gauge.GetValueRange(); lo != 5 || hi != 50 {
+		t.Errorf("value range = %v..%v, want 5..50", lo, hi)
+	}
+	sets := NewDimensionGenerator().GenerateDimensionSets(gauge, 50)
+	for _, set := range sets {
+		if set["host.name"] == "" {
+			t.Fatalf("built-in dimension missing: %v", set)
+		}
+		if tier := set["tier"]; tier != "free" && tier != "pro" {
+			t.Fatalf("tier = %q, want free or pro", tier)
+		}
+		if shard, err := strconv.Atoi(set["shard"]); err != nil || shard < 1 || shard > 4 {
+			t.Fatalf("shard = %q, want 1..4", set["shard"])
+		}
+	}
+
+	if !isDelta(defs[1], config.TemporalityConfig{}) {
+		t.Error("definition temporality delta not honored")
+	}
+	if !isDelta(defs[2], config.TemporalityConfig{Default: "delta"}) {
+		t.Error("metrics.temporality.default not applied to custom metrics")
+	}
+}
+
+func TestLoadDefinitionsErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing name":       "metrics: [{type: gauge}]",
+		"unknown type":       "metrics: [{name: a, type: counter}]",
+		"bad range":          "metrics: [{name: a, type: gauge, value: {min: 10, max: 1}}]",
+		"duplicate name":     "metrics: [{name: a, type: gauge}, {name: a, type: sum}]",
+		"duplicate key":      "metrics: [{name: a, type: gauge, dimensions: [{key: k}, {key: k}]}]",
+		"bad generator":      "metrics: [{name: a, type: gauge, dimensions: [{key: k, generator: nope}]}]",
+		"enum no values":     "metrics: [{name: a, type: gauge, dimensions: [{key: k, generator: enum}]}]",
+		"exponential <= 0":   "metrics: [{name: a, type: exponential_histogram, value: {min: -10, max: 0}}]",
+		"scale out of range": "metrics: [{name: a, type: exponential_histogram, scale: 21}]",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadDefinitions(writeDefinitions(t, content)); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestExplicitScaleZero(t *testing.T) {
+	defs, err := LoadDefinitions(writeDefinitions(t, "metrics: [{name: a, unit: ms, type: exponential_histogram, scale: 0}, {name: b, unit: ms, type: exponential_histogram}]"))
+	if err != nil {
+		t.Fatalf("LoadDefinitions: %v", err)
+	}
+	w := NewMetricsWriter(t.TempDir(), "test")
+	scaleOf := func(def MetricDefinition) int32 {
+		template := &MetricTemplate{Definition: def, DimensionSets: []DimensionSet{{}}}
+		return w.templateToOTLP(template).GetExponentialHistogram().DataPoints[0].Scale
+	}
+	if got := scaleOf(defs[0]); got != 0 {
+		t.Errorf("scale: 0 gave scale %d", got)
+	}
+	if got := scaleOf(defs[1]); got == 0 {
+		t.Error("unset scale should fit the value range, not use 0")
+	}
+}
+
+func TestSelectMetricsWithDefinitions(t *testing.T) {
+	cfg := &config.MetricsConfig{
+		MetricCount:     10,
+		DefinitionsFile: writeDefinitions(t, testDefinitions),
+		Categories:      config.CategoriesConfig{Include: []string{"k8s_cluster", "host_metrics"}},
+	}
+	selected, err := NewGenerator(cfg, "", "").selectMetrics()
+	if err != nil {
+		t.Fatalf("selectMetrics: %v", err)
+	}
+	if len(selected) != 10 {
+		t.Fatalf("selected %d metrics, want 10", len(selected))
+	}
+	if selected[0].Name != "checkout.cart.value" || selected[3].Category != "host_metrics" {
+		t.Fatalf("custom metrics should come first, then categories in catalogue order: %s, %s", selected[0].Name, selected[3].Category)
+	}
+
+	cfg.Categories = config.CategoriesConfig{}
+	selected, err = NewGenerator(cfg, "", "").selectMetrics()
+	if err != nil {
+		t.Fatalf("selectMetrics: %v", err)
+	}
+	if len(selected) != 3 {
+		t.Fatalf("definitions only: selected %d metrics, want 3", len(selected))
+	}
+}
+
+func TestSyntheticMetrics(t *testing.T) {
+	defs := GetK8sClusterMetrics()
+	metrics := SyntheticMetrics(defs, "synthetic", 5)
+	if len(metrics) != 5 {
+		t.Fatalf("got %d metrics, want 5", len(metrics))
+	}
+	want := []string{"k8s.cluster.node.count", "k8s.cluster.pod.count",
+		"synthetic.0.k8s.cluster.node.count", "synthetic.1.k8s.cluster.pod.count", "synthetic.2.k8s.cluster.node.count"}
+	for i, m := range metrics {
+		if m.Name != want[i] {
+			t.Errorf("metrics[%d] = %q, want %q", i, m.Name, want[i])
+		}
+	}
+	if defs[0].Name != "k8s.cluster.node.count" {
+		t.Error("SyntheticMetrics renamed a source definition")
+	}
+}
+
+func TestDimensionSetsDescribeEntities(t *testing.T) {
+	inv := inventory.Default()
+	nodes := make(map[string]string) // pod → node
+	for _, e := range inv.Entities(inventory.LevelPod) {
+		attrs := e.Attributes()
+		nodes[attrs["k8s.pod.name"]] = attrs["k8s.node.name"]
+	}
+
+	gen := NewDimensionGenerator()
+	gen.UseInventory(inv)
+	def := MetricDefinition{Name: "pod.cpu", Dimensions: []string{"k8s.pod.name", "k8s.node.name", "cpu"}}
+	for _, set := range gen.GenerateDimensionSets(def, 40) {
+		node, ok := nodes[set["k8s.pod.name"]]
+		if !ok || node != set["k8s.node.name"] {
+			t.Fatalf("set %v names a pod and node that don't belong together", set)
+		}
+	}
+}
+
+func TestDimensionSetsUnique(t *testing.T) {
+	inv := inventory.Default()
+	hosts := len(inv.Entities(inventory.LevelHost))
+
+	gen := NewDimensionGenerator()
+	gen.UseInventory(inv)
+	for _, def := range []MetricDefinition{
+		{Name: "host.up", Dimensions: []string{"host.name", "os.type"}},
+		{Name: "host.cpu", Dimensions: []string{"host.name", "cpu"}},
+		{Name: "pod.network", Dimensions: []string{"k8s.pod.name", "k8s.namespace.name", "direction"}},
+		{Name: "namespace.pods", Dimensions: []string{"k8s.namespace.name"}},
+	} {
+		sets := gen.GenerateDimensionSets(def, 300)
+		seen := make(map[string]bool)
+		for _, set := range sets {
+			if seen[set.String()] {
+				t.Fatalf("%s repeats series %v", def.Name, set)
+			}
+			seen[set.String()] = true
+		}
+		if def.Name == "host.up" && len(sets) != hosts {
+			t.Errorf("host.up has %d series, want one per host (%d)", len(sets), hosts)
+		}
+	}
+}
@@ -2,74 +2,147 @@ package metrics
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 
 	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/inventory"
 	"go.opentelemetry.io/proto/otlp/common/v1"
 )
 
 // DimensionSet represents a unique combination of dimension values
 type DimensionSet map[string]string
 
-// DimensionGenerator generates dimension combinations for metrics
+// DimensionGenerator generates dimension combinations for metrics. Entity
+// dimensions (host.name, k8s.pod.name, container.name, ...) come from the
+// entity inventory, so a set describes one coherent entity: a pod together
+// with its own namespace, node and cluster.
 type DimensionGenerator struct {
-	hostnameGen    *common.HostnameGenerator
-	podGen         map[string]*common.PodNameGenerator
-	containerGen   *common.ContainerNameGenerator
-	nodeGen        *common.NodeNameGenerator
-	namespaceGen   *common.NamespaceGenerator
-	regionGen      *common.RegionGenerator
-	osTypeGen      *common.OSTypeGenerator
-	clusterName    string
+	inventory *inventory.Inventory
 }
 
-// NewDimensionGenerator creates a new dimension generator
+// NewDimensionGenerator creates a new dimension generator. It uses a default
+// inventory unless UseInventory sets a shared one.
 func NewDimensionGenerator() *DimensionGenerator {
-	clusterName := common.GenerateClusterName()
-
-	return &DimensionGenerator{
-		hostnameGen:  common.NewHostnameGenerator(),
-		podGen:       make(map[string]*common.PodNameGenerator),
-		containerGen: common.NewContainerNameGenerator(),
-		nodeGen:      common.NewNodeNameGenerator(clusterName),
-		namespaceGen: common.NewNamespaceGenerator(),
-		regionGen:    common.NewRegionGenerator(),
-		osTypeGen:    common.NewOSTypeGenerator(),
-		clusterName:  clusterName,
-	}
+	return &DimensionGenerator{}
+}
+
+// UseInventory makes dimension sets describe entities from inv.
+func (g *DimensionGenerator) UseInventory(inv *inventory.Inventory) {
+	g.inventory = inv
 }
 
-// GenerateDimensionSets generates N unique dimension sets for a metric
+// GenerateDimensionSets generates up to N distinct dimension sets for a
+// metric. Each set takes its entity dimensions from the next entity of the
+// level they need, starting at a random one; metrics with more series than
+// entities repeat entities with other values for their other dimensions.
+// It returns fewer than N sets when it runs out of distinct ones, as for a
+// metric whose only dimensions are entity attributes.
 func (g *DimensionGenerator) GenerateDimensionSets(metric MetricDefinition, count int) []DimensionSet {
-	sets := make([]DimensionSet, 0, count)
+	sets, _ := g.generate(metric, count, false)
+	return sets
+}
+
+// GenerateEntitySets generates N dimension sets for a metric like
+// GenerateDimensionSets, but leaves the attributes the entity provides out
+// of each set, and returns the entity each set describes alongside (nil
+// when the metric has no entity dimensions) for use as its resource.
+func (g *DimensionGenerator) GenerateEntitySets(metric MetricDefinition, count int) ([]DimensionSet, []inventory.Entity) {
+	return g.generate(metric, count, true)
+}
 
+// generate generates count dimension sets and their entities, leaving the
+// entity's attributes out of the sets when split is set.
+func (g *DimensionGenerator) generate(metric MetricDefinition, count int, split bool) ([]DimensionSet, []inventory.Entity) {
+	if g.inventory == nil {
+		g.inventory = inventory.Default()
+	}
+	var keys []string
+	for _, key := range metric.Dimensions {
+		if _, custom := metric.DimensionValues[key]; !custom {
+			keys = append(keys, key)
+		}
+	}
+	entities := g.inventory.Entities(inventory.LevelFor(keys))
+	offset := 0
+	if len(entities) > 0 {
+		offset = common.RandomInt(0, len(entities)-1)
+	}
+
+	// A set that repeats an earlier one is regenerated, moving on to the
+	// next entity each time; after maxAttempts repeats in a row the metric
+	// has run out of distinct sets.
+	maxAttempts := distinctSetAttempts + len(entities)
+	seen := make(map[seriesKey]bool, count)
+	sets := make([]DimensionSet, 0, count)
+	setEntities := make([]inventory.Entity, 0, count)
 	for i := 0; i < count; i++ {
-		set := g.generateSingleSet(metric)
-		sets = append(sets, set)
+		found := false
+		for attempt := 0; attempt < maxAttempts && !found; attempt++ {
+			var entity inventory.Entity
+			if len(entities) > 0 {
+				entity = entities[(offset+i+attempt)%len(entities)]
+			}
+			set := g.generateSingleSet(metric, entity, split)
+			key := seriesKey{set: set.String()}
+			if split {
+				key.entity = entity
+			}
+			if !seen[key] {
+				seen[key] = true
+				sets = append(sets, set)
+				setEntities = append(setEntities, entity)
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
 	}
 
-	return sets
+	return sets, setEntities
+}
+
+// seriesKey identifies a series: its dimension set, and its entity when the
+// entity's attributes are in its resource instead.
+type seriesKey struct {
+	entity inventory.Entity
+	set    string
 }
 
-// generateSingleSet generates a single dimension set
-func (g *DimensionGenerator) generateSingleSet(metric MetricDefinition) DimensionSet {
+// distinctSetAttempts is how many times a repeated dimension set is
+// regenerated, besides once per entity, before giving up on the metric.
+const distinctSetAttempts = 32
+
+// generateSingleSet generates a single dimension set describing entity,
+// without the entity's own attributes when split is set
+func (g *DimensionGenerator) generateSingleSet(metric MetricDefinition, entity inventory.Entity, split bool) DimensionSet {
 	set := make(DimensionSet)
 
 	for _, dimKey := range metric.Dimensions {
+		if values, ok := metric.DimensionValues[dimKey]; ok {
+			set[dimKey] = dimensionString(values.Next())
+			continue
+		}
+		if entity != nil {
+			if value, ok := entity.Attributes()[dimKey]; ok {
+				if !split {
+					set[dimKey] = value
+				}
+				continue
+			}
+		}
 		set[dimKey] = g.generateDimensionValue(dimKey)
 	}
 
 	return set
 }
 
-// generateDimensionValue generates a value for a specific dimension key
+// generateDimensionValue generates a value for a dimension key the entity
+// inventory doesn't provide
 func (g *DimensionGenerator) generateDimensionValue(key string) string {
 	switch key {
-	case "host.name":
-		return g.hostnameGen.Generate()
-
-	case "os.type":
-		return g.osTypeGen.Generate()
-
 	case "cpu":
 		return fmt.Sprintf("cpu%d", common.RandomInt(0, 7))
 
@@ -87,58 +160,39 @@ func (g *DimensionGenerator) generateDimensionValue(key string) string {
 		directions := []string{"read", "write", "transmit", "receive"}
 		return common.RandomChoice(directions)
 
-	case "k8s.cluster.name":
-		return g.clusterName
-
-	case "k8s.namespace.name":
-		return g.namespaceGen.Generate()
-
-	case "k8s.pod.name":
-		// Use namespace as key to generate consistent pod names per namespace
-		namespace := "default"
-		if podGen, ok := g.podGen[namespace]; ok {
-			return podGen.Generate()
-		}
-		// Create new pod generator for this namespace
-		deployment := common.GenerateDeploymentName(fmt.Sprintf("app-%d", len(g.podGen)))
-		podGen := common.NewPodNameGenerator(deployment)
-		g.podGen[namespace] = podGen
-		return podGen.Generate()
-
-	case "k8s.node.name":
-		return g.nodeGen.Generate()
-
-	case "container.name":
-		return g.containerGen.Generate()
-
-	case "cloud.provider":
-		providers := []string{"aws", "gcp", "azure"}
-		return common.RandomChoice(providers)
-
-	case "cloud.region":
-		return g.regionGen.Generate()
-
-	case "cloud.availability_zone":
-		region := g.regionGen.Generate()
-		azGen := common.NewAvailabilityZoneGenerator(region)
-		return azGen.Generate()
-
 	default:
 		// Unknown dimension, generate generic value
 		return fmt.Sprintf("value-%d", common.RandomInt(1, 100))
 	}
 }
 
-// ToAttributes converts a DimensionSet to OTLP attributes
+// dimensionString renders a generated value as a dimension value.
+func dimensionString(v *v1.AnyValue) string {
+	switch x := v.GetValue().(type) {
+	case *v1.AnyValue_StringValue:
+		return x.StringValue
+	case *v1.AnyValue_IntValue:
+		return strconv.FormatInt(x.IntValue, 10)
+	case *v1.AnyValue_DoubleValue:
+		return strconv.FormatFloat(x.DoubleValue, 'g', -1, 64)
+	case *v1.AnyValue_BoolValue:
+		return strconv.FormatBool(x.BoolValue)
+	default:
+		return ""
+	}
+}
+
+// ToAttributes converts a DimensionSet to OTLP attributes. Keys are emitted in
+// sorted order so the template bytes don't depend on map iteration order.
 func (ds DimensionSet) ToAttributes() []*v1.KeyValue {
 	attrs := make([]*v1.KeyValue, 0, len(ds))
 
-	for key, value := range ds {
+	for _, key := range slices.Sorted(maps.Keys(ds)) {
 		attrs = append(attrs, &v1.KeyValue{
 			Key: key,
 			Value: &v1.AnyValue{
 				Value: &v1.AnyValue_StringValue{
-					StringValue: value,
+					StringValue: ds[key],
 				},
 			},
 		})
@@ -151,11 +205,11 @@ func (ds DimensionSet) ToAttributes() []*v1.KeyValue {
 func (ds DimensionSet) String() string {
 	result := "{"
 	first := true
-	for key, value := range ds {
+	for _, key := range slices.Sorted(maps.Keys(ds)) {
 		if !first {
 			result += ", "
 		}
-		result += fmt.Sprintf("%s=%s", key, value)
+		result += fmt.Sprintf("%s=%s", key, ds[key])
 		first = false
 	}
 	result += "}"
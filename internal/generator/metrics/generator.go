@@ -2,9 +2,11 @@ package metrics
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
 	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/inventory"
 )
 
 // Generator is the main metrics generator
@@ -23,6 +25,12 @@ func NewGenerator(cfg *config.MetricsConfig, outputDir, prefix string) *Generato
 	}
 }
 
+// UseInventory makes the metrics' entity dimensions describe entities from
+// the inventory shared with the other signals.
+func (g *Generator) UseInventory(inv *inventory.Inventory) {
+	g.dimGen.UseInventory(inv)
+}
+
 // Generate generates all metrics according to configuration
 func (g *Generator) Generate(writeJSON bool) error {
 	fmt.Println("Generating metrics...")
@@ -32,32 +40,69 @@ func (g *Generator) Generate(writeJSON bool) error {
 		g.config.TimeSeriesPerMetric.Max,
 		g.config.TimeSeriesPerMetric.Default)
 
-	// Get all available metrics from all types
-	allMetrics := GetAllAvailableMetrics()
-	fmt.Printf("  Available metrics: %d\n", len(allMetrics))
-
-	// Select metrics to generate (randomly selected from all available)
-	selectedMetrics := SelectMetrics(allMetrics, g.config.MetricCount)
+	selectedMetrics, err := g.selectMetrics()
+	if err != nil {
+		return err
+	}
 	fmt.Printf("  Selected metrics: %d\n", len(selectedMetrics))
 
+	// When streaming, templates go to disk as they are generated instead of
+	// being collected first.
+	var stream *MetricsStream
+	if g.config.Streaming.Enabled {
+		if writeJSON {
+			fmt.Println("  Streaming: JSON output is skipped")
+		}
+		stream, err = g.writer.OpenStream(g.config.Streaming.ChunkDataPoints)
+		if err != nil {
+			return fmt.Errorf("failed to write metrics: %w", err)
+		}
+		defer stream.Abort()
+	}
+
 	// Generate dimension sets for each metric
-	metricTemplates := make([]*MetricTemplate, 0, len(selectedMetrics))
+	var metricTemplates []*MetricTemplate
+	if stream == nil {
+		metricTemplates = make([]*MetricTemplate, 0, len(selectedMetrics))
+	}
 	totalTimeSeries := 0
+	short, shortfall := 0, 0 // metrics that ran out of distinct series, and series missing
 
 	for i, metricDef := range selectedMetrics {
+		metricDef = g.applyHistogramConfig(metricDef)
+
 		// Determine number of time series for this metric
 		timeSeriesCount := g.determineTimeSeriesCount()
 
-		// Generate dimension sets
-		dimSets := g.dimGen.GenerateDimensionSets(metricDef, timeSeriesCount)
+		// Generate dimension sets, keeping their entities apart when they
+		// become resources
+		var dimSets []DimensionSet
+		var entities []inventory.Entity
+		if g.config.Resources.PerEntity {
+			dimSets, entities = g.dimGen.GenerateEntitySets(metricDef, timeSeriesCount)
+		} else {
+			dimSets = g.dimGen.GenerateDimensionSets(metricDef, timeSeriesCount)
+		}
 
 		template := &MetricTemplate{
 			Definition:    metricDef,
 			DimensionSets: dimSets,
+			Entities:      entities,
+			Delta:         isDelta(metricDef, g.config.Temporality),
 		}
 
-		metricTemplates = append(metricTemplates, template)
+		if stream != nil {
+			if err := stream.Add(template); err != nil {
+				return fmt.Errorf("failed to write metrics: %w", err)
+			}
+		} else {
+			metricTemplates = append(metricTemplates, template)
+		}
 		totalTimeSeries += len(dimSets)
+		if len(dimSets) < timeSeriesCount {
+			short++
+			shortfall += timeSeriesCount - len(dimSets)
+		}
 
 		if (i+1)%100 == 0 {
 			fmt.Printf("  Generated %d/%d metrics\n", i+1, len(selectedMetrics))
@@ -66,14 +111,21 @@ func (g *Generator) Generate(writeJSON bool) error {
 
 	// Print statistics
 	fmt.Printf("\nMetrics Generation Statistics:\n")
-	fmt.Printf("  Total metrics: %d\n", len(metricTemplates))
+	fmt.Printf("  Total metrics: %d\n", len(selectedMetrics))
 	fmt.Printf("  Total time series: %d\n", totalTimeSeries)
 	fmt.Printf("  Avg time series per metric: %.2f\n",
-		float64(totalTimeSeries)/float64(len(metricTemplates)))
+		float64(totalTimeSeries)/float64(len(selectedMetrics)))
+	if short > 0 {
+		fmt.Printf("  Short of distinct series: %d metrics, %d series (enlarge the inventory for more)\n", short, shortfall)
+	}
 
 	// Write to disk
 	fmt.Println("\nWriting metrics to disk...")
-	if err := g.writer.WriteMetrics(metricTemplates, writeJSON); err != nil {
+	if stream != nil {
+		if err := stream.Close(); err != nil {
+			return fmt.Errorf("failed to write metrics: %w", err)
+		}
+	} else if err := g.writer.WriteMetrics(metricTemplates, writeJSON); err != nil {
 		return fmt.Errorf("failed to write metrics: %w", err)
 	}
 
@@ -81,6 +133,111 @@ func (g *Generator) Generate(writeJSON bool) error {
 	return nil
 }
 
+// selectMetrics returns the metrics to generate: the custom definitions
+// first, then built-in metrics up to metric_count — sampled evenly from the
+// whole catalogue, or every metric of the metrics.categories groups — and
+// synthetic copies of those for the rest when metrics.synthetic is enabled.
+func (g *Generator) selectMetrics() ([]MetricDefinition, error) {
+	selected, err := g.selectDefinedMetrics()
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) < g.config.MetricCount {
+		if !g.config.Synthetic.Enabled {
+			fmt.Printf("  Only %d metrics available (enable metrics.synthetic for more)\n", len(selected))
+			return selected, nil
+		}
+		selected = SyntheticMetrics(selected, g.config.Synthetic.Prefix, g.config.MetricCount)
+	}
+	return selected, nil
+}
+
+// selectDefinedMetrics returns the custom and built-in metrics to generate,
+// up to metric_count.
+func (g *Generator) selectDefinedMetrics() ([]MetricDefinition, error) {
+	var selected []MetricDefinition
+	if g.config.DefinitionsFile != "" {
+		defs, err := LoadDefinitions(g.config.DefinitionsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load metric definitions: %w", err)
+		}
+		fmt.Printf("  Custom metrics: %d\n", len(defs))
+		selected = defs
+	}
+	if len(selected) >= g.config.MetricCount {
+		return selected[:g.config.MetricCount], nil
+	}
+	remaining := g.config.MetricCount - len(selected)
+
+	if g.config.SamplesCatalogue() {
+		allMetrics := GetAllAvailableMetrics()
+		fmt.Printf("  Available metrics: %d\n", len(allMetrics))
+		return append(selected, SelectMetrics(allMetrics, remaining)...), nil
+	}
+
+	categories := g.config.Selected()
+	builtIn := GetMetricsByCategories(categories)
+	if len(categories) > 0 {
+		fmt.Printf("  Available metrics: %d (%s)\n", len(builtIn), strings.Join(categories, ", "))
+	}
+	selected = append(selected, builtIn[:min(remaining, len(builtIn))]...)
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no metrics selected by metrics.definitions_file and metrics.categories")
+	}
+	return selected, nil
+}
+
+// SyntheticMetrics extends defs to count metrics with copies of defs, cycled
+// in order and named <prefix>.<n>.<name> for n counting from 0.
+func SyntheticMetrics(defs []MetricDefinition, prefix string, count int) []MetricDefinition {
+	metrics := make([]MetricDefinition, len(defs), count)
+	copy(metrics, defs)
+	for n := 0; len(metrics) < count; n++ {
+		def := defs[n%len(defs)]
+		def.Name = fmt.Sprintf("%s.%d.%s", prefix, n, def.Name)
+		metrics = append(metrics, def)
+	}
+	return metrics
+}
+
+// isDelta reports whether def uses delta temporality: its own setting, or
+// metrics.temporality for its category.
+func isDelta(def MetricDefinition, t config.TemporalityConfig) bool {
+	if def.Temporality != "" {
+		return def.Temporality == "delta"
+	}
+	return t.IsDelta(def.Category)
+}
+
+// applyHistogramConfig re-encodes the catalogue's histogram definitions as
+// configured by metrics.histograms and fills in any shape the definition
+// leaves unset. Custom histograms keep the type they were defined with.
+func (g *Generator) applyHistogramConfig(def MetricDefinition) MetricDefinition {
+	h := g.config.Histograms
+	if def.Type == MetricTypeHistogram && def.Category != CustomCategory {
+		switch h.Type {
+		case "exponential":
+			def.Type = MetricTypeExponentialHistogram
+		case "summary":
+			def.Type = MetricTypeSummary
+		}
+	}
+	switch def.Type {
+	case MetricTypeHistogram, MetricTypeExponentialHistogram, MetricTypeSummary:
+		if def.Buckets == nil {
+			def.Buckets = h.Buckets
+		}
+		if def.Scale == nil {
+			def.Scale = h.Scale
+		}
+		def.NegativeBuckets = def.NegativeBuckets || h.NegativeBuckets
+		if def.Quantiles == nil {
+			def.Quantiles = h.Quantiles
+		}
+	}
+	return def
+}
+
 // determineTimeSeriesCount determines the number of time series for a metric
 func (g *Generator) determineTimeSeriesCount() int {
 	min := g.config.TimeSeriesPerMetric.Min
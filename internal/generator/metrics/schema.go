@@ -1,5 +1,10 @@
 package metrics
 
+import (
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+)
+
 // MetricType represents the type of metric
 type MetricType int
 
@@ -7,8 +12,18 @@ const (
 	MetricTypeGauge MetricType = iota
 	MetricTypeSum
 	MetricTypeHistogram
+	MetricTypeExponentialHistogram
+	MetricTypeSummary
 )
 
+// DefaultHistogramBuckets are the explicit bucket bounds used when a
+// definition doesn't set its own.
+var DefaultHistogramBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0}
+
+// DefaultSummaryQuantiles are the quantiles summaries report when a
+// definition doesn't set its own.
+var DefaultSummaryQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
 // MetricDefinition defines a metric with its properties
 type MetricDefinition struct {
 	Name        string
@@ -16,6 +31,22 @@ type MetricDefinition struct {
 	Unit        string
 	Type        MetricType
 	Dimensions  []string // Dimension keys for this metric
+	Category    string   // GetMetricsByType key, set by GetAllAvailableMetrics
+	Temporality string   // "cumulative" or "delta"; empty follows metrics.temporality
+
+	// ValueMin and ValueMax override the unit's value range when ValueMax >
+	// ValueMin.
+	ValueMin, ValueMax float64
+
+	// DimensionValues generates values for the dimensions it holds; other
+	// dimensions use the built-in values for their key.
+	DimensionValues map[string]*common.ValueGenerator
+
+	// Histogram shape; zero values use the defaults.
+	Buckets         []float64 // Explicit bucket bounds
+	Scale           *int32    // Exponential scale, nil = fit the value range
+	NegativeBuckets bool      // Exponential histograms also fill negative buckets
+	Quantiles       []float64 // Summary quantiles
 }
 
 // GetHostMetrics returns definitions for host-level metrics
@@ -1588,34 +1619,21 @@ func GetMetricsByType(metricType string) []MetricDefinition {
 
 // GetAllAvailableMetrics returns all metric definitions from all available types
 func GetAllAvailableMetrics() []MetricDefinition {
-	allTypes := []string{
-		"host_metrics",
-		"k8s_cluster",
-		"k8s_node",
-		"k8s_pod",
-		"k8s_container",
-		"jvm_metrics",
-		"http_metrics",
-		"application_metrics",
-		"database_metrics",
-		"rpc_metrics",
-		"runtime_metrics",
-		"messaging_metrics",
-		"otelcol_metrics",
-		"aspnet_metrics",
-		"aws_metrics",
-		"v8_metrics",
-		"cache_metrics",
-		"browser_metrics",
-	}
+	return GetMetricsByCategories(config.MetricCategories)
+}
 
-	allMetrics := make([]MetricDefinition, 0)
-	for _, metricType := range allTypes {
-		metrics := GetMetricsByType(metricType)
-		allMetrics = append(allMetrics, metrics...)
+// GetMetricsByCategories returns the metric definitions of the given
+// categories, in order.
+func GetMetricsByCategories(categories []string) []MetricDefinition {
+	metrics := make([]MetricDefinition, 0)
+	for _, category := range categories {
+		defs := GetMetricsByType(category)
+		for i := range defs {
+			defs[i].Category = category
+		}
+		metrics = append(metrics, defs...)
 	}
-
-	return allMetrics
+	return metrics
 }
 
 // SelectMetrics selects a subset of metrics up to the target count
@@ -1661,6 +1679,10 @@ func (m MetricType) String() string {
 // GetValueRange returns appropriate value ranges for different metric types
 func (d *MetricDefinition) GetValueRange() (float64, float64) {
 	switch {
+	// Configured range
+	case d.ValueMax > d.ValueMin:
+		return d.ValueMin, d.ValueMax
+
 	// Percentage metrics
 	case d.Unit == "%":
 		return 0.0, 100.0
@@ -1,12 +1,16 @@
 package metrics
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/inventory"
 	otlpcollectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
@@ -18,8 +22,14 @@ import (
 type MetricTemplate struct {
 	Definition     MetricDefinition
 	DimensionSets  []DimensionSet
+	Entities       []inventory.Entity // each set's resource entity; nil: all in the generator's resource
+	Delta          bool               // sums and histograms use delta temporality
 }
 
+// maxExponentialBuckets caps the buckets per sign of an exponential
+// histogram, matching the OpenTelemetry SDK default.
+const maxExponentialBuckets = 160
+
 // MetricsWriter handles writing metric templates to disk
 type MetricsWriter struct {
 	outputDir string
@@ -72,44 +82,106 @@ func (w *MetricsWriter) WriteMetrics(metrics []*MetricTemplate, writeJSON bool)
 
 // metricsToOTLP converts metric templates to OTLP ExportMetricsServiceRequest
 func (w *MetricsWriter) metricsToOTLP(metrics []*MetricTemplate) *otlpcollectormetrics.ExportMetricsServiceRequest {
-	request := &otlpcollectormetrics.ExportMetricsServiceRequest{
-		ResourceMetrics: []*otlpmetrics.ResourceMetrics{
-			{
-				Resource: &resourcepb.Resource{
-					Attributes: []*commonpb.KeyValue{
-						{
-							Key: "service.name",
-							Value: &commonpb.AnyValue{
-								Value: &commonpb.AnyValue_StringValue{
-									StringValue: "telemetry-generator",
-								},
-							},
-						},
-					},
-				},
-				ScopeMetrics: []*otlpmetrics.ScopeMetrics{
-					{
-						Scope: &commonpb.InstrumentationScope{
-							Name:    "telemetry-generator",
-							Version: "1.0.0",
-						},
-						Metrics: make([]*otlpmetrics.Metric, 0),
-					},
-				},
-			},
-		},
-	}
+	var groups resourceGroups
 
 	// Convert each metric template
 	for _, metricTemplate := range metrics {
-		otlpMetric := w.templateToOTLP(metricTemplate)
-		request.ResourceMetrics[0].ScopeMetrics[0].Metrics = append(
-			request.ResourceMetrics[0].ScopeMetrics[0].Metrics,
-			otlpMetric,
-		)
+		groups.add(w, metricTemplate)
+	}
+
+	return &otlpcollectormetrics.ExportMetricsServiceRequest{
+		ResourceMetrics: groups.resourceMetrics(),
+	}
+}
+
+// resourceGroups collects converted metrics by the entity their series
+// describe, in order of first series. The nil entity stands for the
+// generator's own resource.
+type resourceGroups struct {
+	entities []inventory.Entity
+	metrics  map[inventory.Entity][]*otlpmetrics.Metric
+	points   int
+}
+
+// add converts a template, one metric per entity its series describe.
+func (g *resourceGroups) add(w *MetricsWriter, template *MetricTemplate) {
+	if g.metrics == nil {
+		g.metrics = make(map[inventory.Entity][]*otlpmetrics.Metric)
+	}
+	g.points += len(template.DimensionSets)
+
+	entities, parts := splitByEntity(template)
+	for i, entity := range entities {
+		if _, ok := g.metrics[entity]; !ok {
+			g.entities = append(g.entities, entity)
+		}
+		g.metrics[entity] = append(g.metrics[entity], w.templateToOTLP(parts[i]))
 	}
+}
 
-	return request
+// splitByEntity splits a template into one part per entity, in order of
+// first series. A template without entities is one part for the nil entity.
+func splitByEntity(template *MetricTemplate) ([]inventory.Entity, []*MetricTemplate) {
+	if template.Entities == nil {
+		return []inventory.Entity{nil}, []*MetricTemplate{template}
+	}
+	var entities []inventory.Entity
+	var parts []*MetricTemplate
+	index := make(map[inventory.Entity]int)
+	for i, set := range template.DimensionSets {
+		entity := template.Entities[i]
+		n, ok := index[entity]
+		if !ok {
+			n = len(parts)
+			index[entity] = n
+			entities = append(entities, entity)
+			parts = append(parts, &MetricTemplate{Definition: template.Definition, Delta: template.Delta})
+		}
+		parts[n].DimensionSets = append(parts[n].DimensionSets, set)
+		parts[n].Entities = append(parts[n].Entities, entity)
+	}
+	return entities, parts
+}
+
+// resourceMetrics returns one ResourceMetrics per entity.
+func (g *resourceGroups) resourceMetrics() []*otlpmetrics.ResourceMetrics {
+	out := make([]*otlpmetrics.ResourceMetrics, 0, len(g.entities))
+	for _, entity := range g.entities {
+		out = append(out, newResourceMetrics(entity, g.metrics[entity]))
+	}
+	return out
+}
+
+// newResourceMetrics wraps metrics in the generator's scope and the
+// entity's resource, or the generator's own for a nil entity.
+func newResourceMetrics(entity inventory.Entity, metrics []*otlpmetrics.Metric) *otlpmetrics.ResourceMetrics {
+	attrs := []*commonpb.KeyValue{
+		{
+			Key: "service.name",
+			Value: &commonpb.AnyValue{
+				Value: &commonpb.AnyValue_StringValue{
+					StringValue: "telemetry-generator",
+				},
+			},
+		},
+	}
+	if entity != nil {
+		attrs = inventory.KeyValues(entity)
+	}
+	return &otlpmetrics.ResourceMetrics{
+		Resource: &resourcepb.Resource{
+			Attributes: attrs,
+		},
+		ScopeMetrics: []*otlpmetrics.ScopeMetrics{
+			{
+				Scope: &commonpb.InstrumentationScope{
+					Name:    "telemetry-generator",
+					Version: "1.0.0",
+				},
+				Metrics: metrics,
+			},
+		},
+	}
 }
 
 // templateToOTLP converts a metric template to OTLP Metric
@@ -120,6 +192,11 @@ func (w *MetricsWriter) templateToOTLP(template *MetricTemplate) *otlpmetrics.Me
 		Unit:        template.Definition.Unit,
 	}
 
+	temporality := otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	if template.Delta {
+		temporality = otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	}
+
 	// Create data points for each dimension set
 	switch template.Definition.Type {
 	case MetricTypeGauge:
@@ -132,7 +209,7 @@ func (w *MetricsWriter) templateToOTLP(template *MetricTemplate) *otlpmetrics.Me
 	case MetricTypeSum:
 		metric.Data = &otlpmetrics.Metric_Sum{
 			Sum: &otlpmetrics.Sum{
-				AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				AggregationTemporality: temporality,
 				IsMonotonic:            true,
 				DataPoints:             w.createSumDataPoints(template),
 			},
@@ -141,10 +218,25 @@ func (w *MetricsWriter) templateToOTLP(template *MetricTemplate) *otlpmetrics.Me
 	case MetricTypeHistogram:
 		metric.Data = &otlpmetrics.Metric_Histogram{
 			Histogram: &otlpmetrics.Histogram{
-				AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				AggregationTemporality: temporality,
 				DataPoints:             w.createHistogramDataPoints(template),
 			},
 		}
+
+	case MetricTypeExponentialHistogram:
+		metric.Data = &otlpmetrics.Metric_ExponentialHistogram{
+			ExponentialHistogram: &otlpmetrics.ExponentialHistogram{
+				AggregationTemporality: temporality,
+				DataPoints:             w.createExponentialHistogramDataPoints(template),
+			},
+		}
+
+	case MetricTypeSummary:
+		metric.Data = &otlpmetrics.Metric_Summary{
+			Summary: &otlpmetrics.Summary{
+				DataPoints: w.createSummaryDataPoints(template),
+			},
+		}
 	}
 
 	return metric
@@ -197,7 +289,10 @@ func (w *MetricsWriter) createHistogramDataPoints(template *MetricTemplate) []*o
 
 	for _, dimSet := range template.DimensionSets {
 		// Generate histogram buckets
-		buckets := []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0}
+		buckets := template.Definition.Buckets
+		if len(buckets) == 0 {
+			buckets = DefaultHistogramBuckets
+		}
 		counts := make([]uint64, len(buckets)+1)
 
 		// Generate random counts for buckets
@@ -229,6 +324,231 @@ func (w *MetricsWriter) createHistogramDataPoints(template *MetricTemplate) []*o
 	return dataPoints
 }
 
+// createExponentialHistogramDataPoints creates exponential histogram data
+// points whose buckets cover the metric's value range
+func (w *MetricsWriter) createExponentialHistogramDataPoints(template *MetricTemplate) []*otlpmetrics.ExponentialHistogramDataPoint {
+	dataPoints := make([]*otlpmetrics.ExponentialHistogramDataPoint, 0, len(template.DimensionSets))
+	minVal, maxVal := template.Definition.GetValueRange()
+	if minVal <= 0 {
+		minVal = maxVal / 1000
+	}
+
+	scale := fitExponentialScale(minVal, maxVal)
+	if template.Definition.Scale != nil {
+		scale = *template.Definition.Scale
+	}
+	offset := exponentialIndex(minVal, scale)
+	size := min(exponentialIndex(maxVal, scale)-offset+1, maxExponentialBuckets)
+
+	for _, dimSet := range template.DimensionSets {
+		positive, posCount, posSum := randomExponentialBuckets(offset, size, scale)
+		zeroCount := uint64(common.RandomInt(0, 100))
+
+		dp := &otlpmetrics.ExponentialHistogramDataPoint{
+			Attributes:   dimSet.ToAttributes(),
+			TimeUnixNano: 0, // No timestamp in template
+			Count:        posCount + zeroCount,
+			Scale:        scale,
+			ZeroCount:    zeroCount,
+			Positive:     positive,
+		}
+		sum := posSum
+		if template.Definition.NegativeBuckets {
+			negative, negCount, negSum := randomExponentialBuckets(offset, size, scale)
+			dp.Negative = negative
+			dp.Count += negCount
+			sum -= negSum
+		}
+		dp.Sum = &sum
+
+		dataPoints = append(dataPoints, dp)
+	}
+
+	return dataPoints
+}
+
+// randomExponentialBuckets fills size buckets starting at index offset with
+// random counts, returning them with their total count and approximate sum.
+func randomExponentialBuckets(offset, size, scale int32) (*otlpmetrics.ExponentialHistogramDataPoint_Buckets, uint64, float64) {
+	counts := make([]uint64, size)
+	base := math.Exp2(math.Exp2(-float64(scale)))
+	count, sum := uint64(0), 0.0
+	for i := range counts {
+		counts[i] = uint64(common.RandomInt(10, 1000))
+		count += counts[i]
+		// Bucket index i covers (base^i, base^(i+1)]; use its midpoint.
+		lower := math.Pow(base, float64(offset+int32(i)))
+		sum += float64(counts[i]) * lower * (1 + base) / 2
+	}
+	return &otlpmetrics.ExponentialHistogramDataPoint_Buckets{Offset: offset, BucketCounts: counts}, count, sum
+}
+
+// exponentialIndex returns the index of the bucket holding v at scale.
+func exponentialIndex(v float64, scale int32) int32 {
+	return int32(math.Ceil(math.Log2(v)*math.Exp2(float64(scale)))) - 1
+}
+
+// fitExponentialScale returns the largest scale (at most 20) at which
+// minVal..maxVal spans no more than maxExponentialBuckets buckets.
+func fitExponentialScale(minVal, maxVal float64) int32 {
+	for scale := int32(20); scale > -10; scale-- {
+		if exponentialIndex(maxVal, scale)-exponentialIndex(minVal, scale) < maxExponentialBuckets {
+			return scale
+		}
+	}
+	return -10
+}
+
+// createSummaryDataPoints creates summary data points
+func (w *MetricsWriter) createSummaryDataPoints(template *MetricTemplate) []*otlpmetrics.SummaryDataPoint {
+	dataPoints := make([]*otlpmetrics.SummaryDataPoint, 0, len(template.DimensionSets))
+	minVal, maxVal := template.Definition.GetValueRange()
+	quantiles := template.Definition.Quantiles
+	if len(quantiles) == 0 {
+		quantiles = DefaultSummaryQuantiles
+	}
+	quantiles = slices.Sorted(slices.Values(quantiles))
+
+	for _, dimSet := range template.DimensionSets {
+		// Random values, sorted so that they rise with the quantile
+		values := make([]float64, len(quantiles))
+		for i := range values {
+			values[i] = common.RandomFloat64(minVal, maxVal)
+		}
+		slices.Sort(values)
+
+		count := uint64(common.RandomInt(100, 10000))
+		sum := float64(count) * common.RandomFloat64(values[0], values[len(values)-1])
+
+		dp := &otlpmetrics.SummaryDataPoint{
+			Attributes:     dimSet.ToAttributes(),
+			TimeUnixNano:   0, // No timestamp in template
+			Count:          count,
+			Sum:            sum,
+			QuantileValues: make([]*otlpmetrics.SummaryDataPoint_ValueAtQuantile, len(quantiles)),
+		}
+		for i, q := range quantiles {
+			dp.QuantileValues[i] = &otlpmetrics.SummaryDataPoint_ValueAtQuantile{Quantile: q, Value: values[i]}
+		}
+
+		dataPoints = append(dataPoints, dp)
+	}
+
+	return dataPoints
+}
+
+// MetricsStream writes metric templates to the protobuf file in chunks of
+// ResourceMetrics as they are generated. Serialized messages concatenate
+// into one message with their repeated fields merged, so the file reads back
+// as a single ExportMetricsServiceRequest. A chunk of series grouped by
+// entity holds one ResourceMetrics per entity; an entity whose series span
+// chunks gets a ResourceMetrics in each.
+type MetricsStream struct {
+	writer *MetricsWriter
+	path   string
+	file   *os.File
+	buf    *bufio.Writer
+
+	chunkDataPoints int
+	pending         resourceGroups
+
+	metrics, timeSeries, chunks int
+}
+
+// OpenStream creates the metrics protobuf file for streaming chunks of up to
+// chunkDataPoints data points.
+func (w *MetricsWriter) OpenStream(chunkDataPoints int) (*MetricsStream, error) {
+	if err := os.MkdirAll(w.outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	path := filepath.Join(w.outputDir, fmt.Sprintf("%s-metrics.pb", w.prefix))
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	return &MetricsStream{
+		writer:          w,
+		path:            path,
+		file:            file,
+		buf:             bufio.NewWriter(file),
+		chunkDataPoints: chunkDataPoints,
+	}, nil
+}
+
+// Add converts a template and writes it once its chunk is full. Templates
+// with more series than fit in the chunk are split across chunks.
+func (s *MetricsStream) Add(template *MetricTemplate) error {
+	s.metrics++
+	s.timeSeries += len(template.DimensionSets)
+
+	sets := template.DimensionSets
+	for len(sets) > 0 {
+		n := min(len(sets), s.chunkDataPoints-s.pending.points)
+		part := *template
+		part.DimensionSets = sets[:n]
+		if template.Entities != nil {
+			part.Entities = template.Entities[len(template.DimensionSets)-len(sets):][:n]
+		}
+		sets = sets[n:]
+
+		s.pending.add(s.writer, &part)
+		if s.pending.points >= s.chunkDataPoints {
+			if err := s.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush writes the pending metrics as one chunk.
+func (s *MetricsStream) flush() error {
+	if s.pending.points == 0 {
+		return nil
+	}
+
+	request := &otlpcollectormetrics.ExportMetricsServiceRequest{
+		ResourceMetrics: s.pending.resourceMetrics(),
+	}
+	data, err := proto.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal protobuf: %w", err)
+	}
+	if _, err := s.buf.Write(data); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	s.chunks++
+	s.pending = resourceGroups{}
+	return nil
+}
+
+// Close writes the last chunk and closes the file.
+func (s *MetricsStream) Close() error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	if err := s.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	s.file = nil
+
+	fmt.Printf("Wrote %d metrics (%d time series) in %d chunks to %s\n", s.metrics, s.timeSeries, s.chunks, s.path)
+	return nil
+}
+
+// Abort closes the file if Close wasn't reached, leaving a partial template.
+func (s *MetricsStream) Abort() {
+	if s.file != nil {
+		s.file.Close()
+	}
+}
+
 // writeProtobuf writes the OTLP request as protobuf binary
 func (w *MetricsWriter) writeProtobuf(request *otlpcollectormetrics.ExportMetricsServiceRequest, path string) error {
 	data, err := proto.Marshal(request)
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/inventory"
+	otlpcollectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestMetricsStream verifies streamed chunks read back as one request, with
+// a metric larger than a chunk split across resources.
+func TestMetricsStream(t *testing.T) {
+	dir := t.TempDir()
+	stream, err := NewMetricsWriter(dir, "test").OpenStream(4)
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	gen := NewDimensionGenerator()
+	for _, def := range GetHostMetrics()[:3] {
+		template := &MetricTemplate{Definition: def, DimensionSets: gen.GenerateDimensionSets(def, 3)}
+		if err := stream.Add(template); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "test-metrics.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := &otlpcollectormetrics.ExportMetricsServiceRequest{}
+	if err := proto.Unmarshal(data, request); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(request.ResourceMetrics) != 3 {
+		t.Fatalf("got %d chunks, want 3 (9 points in chunks of 4)", len(request.ResourceMetrics))
+	}
+	points := make(map[string]int)
+	for _, rm := range request.ResourceMetrics {
+		chunk := 0
+		for _, metric := range rm.ScopeMetrics[0].Metrics {
+			n := len(metric.GetGauge().GetDataPoints()) + len(metric.GetSum().GetDataPoints())
+			points[metric.Name] += n
+			chunk += n
+		}
+		if chunk > 4 {
+			t.Errorf("chunk holds %d points, want at most 4", chunk)
+		}
+	}
+	for _, def := range GetHostMetrics()[:3] {
+		if points[def.Name] != 3 {
+			t.Errorf("%s has %d points, want 3", def.Name, points[def.Name])
+		}
+	}
+}
+
+// TestMetricsPerEntity verifies series grouped by entity are written with
+// one resource per entity carrying its identity, leaving the points only
+// their other dimensions, and never twice within a resource.
+func TestMetricsPerEntity(t *testing.T) {
+	gen := NewDimensionGenerator()
+	gen.UseInventory(inventory.Default())
+	pods := len(inventory.Default().Entities(inventory.LevelPod))
+
+	var templates []*MetricTemplate
+	for _, def := range []MetricDefinition{
+		{Name: "pod.cpu", Type: MetricTypeGauge, Dimensions: []string{"k8s.pod.name", "k8s.namespace.name", "cpu"}},
+		{Name: "pod.memory", Type: MetricTypeGauge, Dimensions: []string{"k8s.pod.name", "k8s.namespace.name"}},
+		{Name: "queue.depth", Type: MetricTypeGauge, Dimensions: []string{"queue"}},
+	} {
+		sets, entities := gen.GenerateEntitySets(def, 3*pods)
+		templates = append(templates, &MetricTemplate{Definition: def, DimensionSets: sets, Entities: entities})
+	}
+
+	request := NewMetricsWriter(t.TempDir(), "test").metricsToOTLP(templates)
+	if len(request.ResourceMetrics) != pods+1 {
+		t.Fatalf("got %d resources, want one per pod plus the generator's", len(request.ResourceMetrics))
+	}
+	seen := make(map[string]bool)
+	for _, rm := range request.ResourceMetrics {
+		resource := make(map[string]string)
+		for _, kv := range rm.Resource.Attributes {
+			resource[kv.Key] = kv.Value.GetStringValue()
+		}
+		pod := resource["k8s.pod.name"]
+		if pod == "" {
+			if resource["service.name"] != "telemetry-generator" || rm.ScopeMetrics[0].Metrics[0].Name != "queue.depth" {
+				t.Errorf("entity-less series under resource %v", resource)
+			}
+			continue
+		}
+		if seen[pod] || resource["k8s.node.name"] == "" {
+			t.Errorf("pod resource %v repeated or missing its node", resource)
+		}
+		seen[pod] = true
+		for _, metric := range rm.ScopeMetrics[0].Metrics {
+			points := make(map[string]bool)
+			for _, dp := range metric.GetGauge().DataPoints {
+				var key strings.Builder
+				for _, kv := range dp.Attributes {
+					if kv.Key != "cpu" {
+						t.Errorf("%s point keeps resource attribute %s", metric.Name, kv.Key)
+					}
+					key.WriteString(kv.Key + "=" + kv.Value.GetStringValue() + ",")
+				}
+				if points[key.String()] {
+					t.Errorf("%s repeats point {%s} in pod %s", metric.Name, key.String(), pod)
+				}
+				points[key.String()] = true
+			}
+		}
+	}
+}
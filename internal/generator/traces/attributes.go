@@ -0,0 +1,81 @@
+package traces
+
+import (
+	"fmt"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// declaredAttribute is a traces.custom_attributes.declared entry ready to
+// generate values.
+type declaredAttribute struct {
+	key        string
+	values     *common.ValueGenerator
+	percentage float64
+	services   map[string]bool // nil matches every service
+	operations map[string]bool // nil matches every operation
+}
+
+// newDeclaredAttributes builds the declared attributes.
+func newDeclaredAttributes(decls []config.DeclaredAttribute) ([]declaredAttribute, error) {
+	attrs := make([]declaredAttribute, 0, len(decls))
+	for i, d := range decls {
+		enum, err := config.ParseTypedValues(d.Type, d.Values)
+		if err != nil {
+			return nil, fmt.Errorf("traces.custom_attributes.declared[%d] (%s): %w", i, d.Key, err)
+		}
+		values, err := common.NewValueGenerator(d.Key, common.ValueSpec{
+			Generator:   d.Generator,
+			Values:      enum,
+			Weights:     d.Weights,
+			Min:         d.Min,
+			Max:         d.Max,
+			Prefix:      d.Prefix,
+			Pattern:     d.Pattern,
+			Cardinality: d.Cardinality,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("traces.custom_attributes.declared[%d] (%s): %w", i, d.Key, err)
+		}
+		attrs = append(attrs, declaredAttribute{
+			key:        d.Key,
+			values:     values,
+			percentage: d.Percentage,
+			services:   toSet(d.Services),
+			operations: toSet(d.Operations),
+		})
+	}
+	return attrs, nil
+}
+
+// appendDeclaredAttributes appends the declared attributes that apply to a
+// span of service running operation.
+func (g *SpanGenerator) appendDeclaredAttributes(attrs []*commonpb.KeyValue, service *ServiceNode, operation string) []*commonpb.KeyValue {
+	for _, d := range g.declared {
+		if d.services != nil && !d.services[service.Name] {
+			continue
+		}
+		if d.operations != nil && !d.operations[operation] {
+			continue
+		}
+		if d.percentage > 0 && common.RandomFloat64(0, 100) >= d.percentage {
+			continue
+		}
+		attrs = append(attrs, &commonpb.KeyValue{Key: d.key, Value: d.values.Next()})
+	}
+	return attrs
+}
+
+// toSet returns the strings as a set, or nil when there are none.
+func toSet(items []string) map[string]bool {
+	if len(items) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
@@ -0,0 +1,82 @@
+package traces
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+func findAttribute(attrs []*commonpb.KeyValue, key string) *commonpb.AnyValue {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return nil
+}
+
+// TestDeclaredAttributes verifies declared attributes land only on matching
+// spans, with values from their generator and within their cardinality cap.
+func TestDeclaredAttributes(t *testing.T) {
+	cfg := &config.TracesConfig{
+		Spans:    config.SpansConfig{AvgPerTrace: 10, StdDev: 0},
+		Services: config.ServicesConfig{Count: 3, Names: []string{"api", "worker", "db"}},
+		CustomAttributes: config.CustomAttributesConfig{Declared: []config.DeclaredAttribute{
+			{Key: "user.id", Generator: "pool", Prefix: "user-", Cardinality: 5},
+			{Key: "tier", Generator: "enum", Values: []string{"free", "pro"}, Services: []string{"api"}},
+			{Key: "retries", Generator: "enum", Type: "int", Values: []string{"3"}},
+			{Key: "sku", Generator: "pattern", Pattern: `SKU-[A-Z]{3}-\d{4}`, Cardinality: 3},
+			{Key: "contact", Generator: "email"},
+		}},
+	}
+	g := newSpanGenerator(t, cfg, testTopology())
+	sku := regexp.MustCompile(`^SKU-[A-Z]{3}-\d{4}$`)
+
+	users, skus := map[string]bool{}, map[string]bool{}
+	for i := 0; i < 50; i++ {
+		for _, s := range g.GenerateTrace().CollectSpans() {
+			user := findAttribute(s.Attributes, "user.id").GetStringValue()
+			if !strings.HasPrefix(user, "user-") {
+				t.Fatalf("user.id = %q", user)
+			}
+			users[user] = true
+
+			if tier := findAttribute(s.Attributes, "tier"); (tier != nil) != (s.Service.Name == "api") {
+				t.Fatalf("tier on %s span: %v", s.Service.Name, tier)
+			}
+			if retries := findAttribute(s.Attributes, "retries"); retries.GetIntValue() != 3 {
+				t.Fatalf("retries = %v, want int 3", retries)
+			}
+			v := findAttribute(s.Attributes, "sku").GetStringValue()
+			if !sku.MatchString(v) {
+				t.Fatalf("sku %q does not match the pattern", v)
+			}
+			skus[v] = true
+			if email := findAttribute(s.Attributes, "contact").GetStringValue(); !strings.Contains(email, "@") {
+				t.Fatalf("contact = %q", email)
+			}
+		}
+	}
+	if len(users) != 5 {
+		t.Errorf("%d distinct user.id values, want 5", len(users))
+	}
+	if len(skus) > 3 {
+		t.Errorf("%d distinct sku values, want at most 3", len(skus))
+	}
+}
+
+// TestDeclaredAttributesInvalid verifies an unvalidated bad declaration fails
+// the generator instead of silently dropping the attribute.
+func TestDeclaredAttributesInvalid(t *testing.T) {
+	cfg := &config.TracesConfig{
+		CustomAttributes: config.CustomAttributesConfig{Declared: []config.DeclaredAttribute{
+			{Key: "retries", Generator: "enum", Type: "int", Values: []string{"three"}},
+		}},
+	}
+	if _, err := NewSpanGenerator(cfg, testTopology()); err == nil || !strings.Contains(err.Error(), "retries") {
+		t.Errorf("NewSpanGenerator error = %v, want one naming retries", err)
+	}
+}
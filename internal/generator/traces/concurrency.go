@@ -0,0 +1,108 @@
+package traces
+
+import (
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+)
+
+// childMode is how a parent runs its children.
+type childMode int
+
+const (
+	childrenSequential childMode = iota
+	childrenParallel
+	childrenPooled
+)
+
+// concurrencyEnabled reports whether traces.concurrency is set. When it isn't,
+// children stay sequential and no random numbers are drawn for layout.
+func concurrencyEnabled(cc config.ConcurrencyConfig) bool {
+	return cc.ParallelPercentage > 0 || cc.WorkerPool.Percentage > 0 || cc.AsyncPercentage > 0
+}
+
+// layoutChildren decides how span's children overlap, sets each child's start
+// offset relative to span, and returns the children's critical path: how long
+// span waits on the children it doesn't fire and forget. Children must already
+// have their durations.
+func (g *SpanGenerator) layoutChildren(span *SpanNode) int64 {
+	cc := g.config.Concurrency
+	enabled := concurrencyEnabled(cc)
+
+	mode, workers := childrenSequential, 0
+	if enabled && len(span.Children) > 1 {
+		mode, workers = g.pickChildMode(cc)
+	}
+	lanes := make([]int64, workers)
+
+	var cursor, critical int64
+	for _, child := range span.Children {
+		if child.queueLag > 0 {
+			// A consumer starts once its message has left the queue; the
+			// producer doesn't wait for it.
+			child.async = true
+			child.offset = cursor + child.queueLag
+			continue
+		}
+
+		child.async = enabled && cc.AsyncPercentage > 0 && common.RandomFloat64(0, 100) < cc.AsyncPercentage
+
+		switch mode {
+		case childrenSequential:
+			// Async children are dispatched at the cursor without holding it.
+			child.offset = cursor
+			if !child.async {
+				cursor += child.Duration
+			}
+		case childrenParallel:
+			child.offset = 0
+		case childrenPooled:
+			child.offset = 0
+			if !child.async {
+				// The child goes to whichever worker frees up first.
+				lane := 0
+				for i := range lanes {
+					if lanes[i] < lanes[lane] {
+						lane = i
+					}
+				}
+				child.offset = lanes[lane]
+				lanes[lane] += child.Duration
+			}
+		}
+
+		if !child.async {
+			critical = max(critical, child.offset+child.Duration)
+		}
+	}
+
+	return critical
+}
+
+// pickChildMode draws the mode for one parent, and its worker count when
+// pooled.
+func (g *SpanGenerator) pickChildMode(cc config.ConcurrencyConfig) (childMode, int) {
+	r := common.RandomFloat64(0, 100)
+	switch {
+	case r < cc.ParallelPercentage:
+		return childrenParallel, 0
+	case r < cc.ParallelPercentage+cc.WorkerPool.Percentage:
+		minWorkers, maxWorkers := cc.WorkerPool.MinWorkers, cc.WorkerPool.MaxWorkers
+		if minWorkers == 0 {
+			minWorkers = 2
+		}
+		if maxWorkers == 0 {
+			maxWorkers = max(4, minWorkers)
+		}
+		return childrenPooled, common.RandomInt(minWorkers, maxWorkers)
+	}
+	return childrenSequential, 0
+}
+
+// placeSpans turns the child offsets set by layoutChildren into start times
+// relative to the trace start, top-down.
+func placeSpans(span *SpanNode) {
+	for _, child := range span.Children {
+		child.StartTime = span.StartTime + child.offset
+		placeSpans(child)
+	}
+}
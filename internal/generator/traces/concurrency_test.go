@@ -0,0 +1,108 @@
+package traces
+
+import (
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+)
+
+// forEachParent calls fn for every span with at least two children across a
+// batch of traces.
+func forEachParent(g *SpanGenerator, fn func(parent *SpanNode)) {
+	for i := 0; i < 30; i++ {
+		for _, s := range g.GenerateTrace().CollectSpans() {
+			if len(s.Children) > 1 {
+				fn(s)
+			}
+		}
+	}
+}
+
+func end(s *SpanNode) int64 { return s.StartTime + s.Duration }
+
+// TestSequentialChildrenByDefault verifies that without concurrency config
+// siblings run back to back inside their parent.
+func TestSequentialChildrenByDefault(t *testing.T) {
+	forEachParent(testGenerator(t, nil), func(p *SpanNode) {
+		next := p.StartTime
+		for _, c := range p.Children {
+			if c.StartTime != next {
+				t.Fatalf("child starts at %d, want %d (right after its previous sibling)", c.StartTime, next)
+			}
+			next = end(c)
+		}
+		if next > end(p) {
+			t.Fatalf("children end at %d, after their parent (%d)", next, end(p))
+		}
+	})
+}
+
+// TestParallelChildren verifies parallel children start together and the
+// parent covers the longest child rather than their sum.
+func TestParallelChildren(t *testing.T) {
+	shorter := 0
+	forEachParent(testGenerator(t, func(c *config.TracesConfig) {
+		c.Concurrency = config.ConcurrencyConfig{ParallelPercentage: 100}
+	}), func(p *SpanNode) {
+		var sum int64
+		for _, c := range p.Children {
+			if c.StartTime != p.StartTime {
+				t.Fatalf("parallel child starts at %d, want parent start %d", c.StartTime, p.StartTime)
+			}
+			if end(c) > end(p) {
+				t.Fatalf("parallel child ends after its parent")
+			}
+			sum += c.Duration
+		}
+		if p.Duration < sum {
+			shorter++
+		}
+	})
+	if shorter == 0 {
+		t.Fatal("expected parents to be shorter than the sum of their parallel children")
+	}
+}
+
+// TestWorkerPoolChildren verifies no more children overlap than there are
+// workers, and the parent covers them all.
+func TestWorkerPoolChildren(t *testing.T) {
+	forEachParent(testGenerator(t, func(c *config.TracesConfig) {
+		c.Concurrency = config.ConcurrencyConfig{WorkerPool: config.WorkerPoolConfig{Percentage: 100, MinWorkers: 2, MaxWorkers: 2}}
+	}), func(p *SpanNode) {
+		for _, c := range p.Children {
+			if end(c) > end(p) {
+				t.Fatalf("pooled child ends after its parent")
+			}
+			running := 0
+			for _, other := range p.Children {
+				if other.StartTime <= c.StartTime && end(other) > c.StartTime {
+					running++
+				}
+			}
+			if running > 2 {
+				t.Fatalf("%d children running at once with 2 workers", running)
+			}
+		}
+	})
+}
+
+// TestAsyncChildrenOutliveParent verifies fire-and-forget children don't
+// stretch their parent and may end after it.
+func TestAsyncChildrenOutliveParent(t *testing.T) {
+	outlived := 0
+	forEachParent(testGenerator(t, func(c *config.TracesConfig) {
+		c.Concurrency = config.ConcurrencyConfig{AsyncPercentage: 100}
+	}), func(p *SpanNode) {
+		for _, c := range p.Children {
+			if !c.async {
+				t.Fatal("every child should be async at 100%")
+			}
+			if end(c) > end(p) {
+				outlived++
+			}
+		}
+	})
+	if outlived == 0 {
+		t.Fatal("expected some async children to end after their parent")
+	}
+}
@@ -0,0 +1,217 @@
+package traces
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// exceptionTemplate is a plausible exception for an operation type; %s in
+// message is replaced with the operation name.
+type exceptionTemplate struct {
+	typ     string
+	message string
+}
+
+var exceptionCatalog = map[OperationType][]exceptionTemplate{
+	OperationTypeHTTP: {
+		{"TimeoutError", "request %s timed out after 30s"},
+		{"ConnectionResetError", "connection reset by peer during %s"},
+		{"InternalServerError", "unhandled error while serving %s"},
+	},
+	OperationTypeDB: {
+		{"DatabaseError", "deadlock detected while executing %s"},
+		{"TimeoutError", "query timed out: %s"},
+		{"ConnectionPoolExhaustedError", "no database connections available for %s"},
+	},
+	OperationTypeRPC: {
+		{"DeadlineExceededError", "deadline exceeded calling %s"},
+		{"UnavailableError", "%s: service unavailable"},
+	},
+	OperationTypeMessaging: {
+		{"MessageProcessingError", "failed to process message in %s"},
+		{"DeserializationError", "malformed payload received by %s"},
+	},
+	OperationTypeInternal: {
+		{"NullPointerException", "unexpected nil value in %s"},
+		{"IllegalStateException", "invalid state in %s"},
+		{"ValidationError", "validation failed in %s"},
+	},
+}
+
+// stackFrameComponents name the synthetic classes in generated stacktraces.
+var stackFrameComponents = []string{"Handler", "Service", "Repository", "Client", "Middleware", "Controller"}
+
+// http5xxStatuses are the statuses an injected error gives an HTTP span.
+var http5xxStatuses = []int64{500, 502, 503, 504}
+
+// errorModel resolves traces.errors. A nil *errorModel injects nothing.
+type errorModel struct {
+	def         float64
+	services    map[string]float64
+	operations  map[opKey]float64 // service "" matches any service
+	propagation float64
+}
+
+// newErrorModel builds the error model, or returns nil when traces.errors is
+// empty so no random numbers are drawn for errors.
+func newErrorModel(cfg config.ErrorsConfig) *errorModel {
+	if cfg.Percentage == 0 && len(cfg.Services) == 0 && len(cfg.Operations) == 0 && cfg.PropagationPercentage == 0 {
+		return nil
+	}
+	e := &errorModel{
+		def:         cfg.Percentage,
+		services:    cfg.Services,
+		operations:  make(map[opKey]float64, len(cfg.Operations)),
+		propagation: cfg.PropagationPercentage,
+	}
+	for _, op := range cfg.Operations {
+		e.operations[opKey{service: op.Service, name: op.Operation}] = op.Percentage
+	}
+	return e
+}
+
+// rate returns the error percentage for a span: operation in this service,
+// operation in any service, service, then the default.
+func (e *errorModel) rate(span *SpanNode) float64 {
+	if r, ok := e.operations[opKey{service: span.Service.Name, name: span.Operation.Name}]; ok {
+		return r
+	}
+	if r, ok := e.operations[opKey{name: span.Operation.Name}]; ok {
+		return r
+	}
+	if r, ok := e.services[span.Service.Name]; ok {
+		return r
+	}
+	return e.def
+}
+
+// applyErrors marks failed spans bottom-up and reports whether span failed.
+// A span fails when its HTTP status is 5xx, when it draws its own error, or
+// when a child it waited on failed and the failure propagates. Durations must
+// already be set, since exception events are placed within the span.
+func (g *SpanGenerator) applyErrors(span *SpanNode) bool {
+	var failedChild *SpanNode
+	for _, child := range span.Children {
+		failed := g.applyErrors(child)
+		if failed && !child.async && failedChild == nil && g.errors != nil &&
+			g.errors.propagation > 0 && common.RandomFloat64(0, 100) < g.errors.propagation {
+			failedChild = child
+		}
+	}
+
+	if span.peer != nil {
+		// A client reports its server's response rather than failing on its
+		// own.
+		if span.peer.Error {
+			mirrorFailure(span, span.peer)
+		}
+		return span.Error
+	}
+
+	if status := httpStatusCode(span); status >= 500 {
+		// A 5xx response is an error on its own, per the HTTP semantic
+		// conventions.
+		code := strconv.FormatInt(status, 10)
+		markError(span, "HTTP "+code, code)
+		return true
+	}
+
+	if g.errors != nil {
+		if rate := g.errors.rate(span); rate > 0 && common.RandomFloat64(0, 100) < rate {
+			tmpl := common.RandomChoice(exceptionCatalog[span.Operation.Type])
+			g.failSpan(span, tmpl.typ, fmt.Sprintf(tmpl.message, span.Operation.Name))
+			return true
+		}
+	}
+
+	if failedChild != nil {
+		g.failSpan(span, failedChild.errType,
+			fmt.Sprintf("call to %s failed: %s", failedChild.Service.Name, failedChild.errMessage))
+		return true
+	}
+
+	return false
+}
+
+// failSpan marks span as failed with an exception: error status, error.type,
+// a 5xx status for HTTP spans and an "exception" span event.
+func (g *SpanGenerator) failSpan(span *SpanNode, typ, message string) {
+	markError(span, message, typ)
+
+	switch span.Operation.Type {
+	case OperationTypeHTTP:
+		setIntAttribute(span, "http.status_code", common.RandomChoice(http5xxStatuses))
+	case OperationTypeRPC:
+		setIntAttribute(span, "rpc.grpc.status_code", grpcStatusCode(typ))
+	}
+
+	// Exceptions are usually thrown late in the failing span.
+	offset := int64(float64(span.Duration) * common.RandomFloat64(0.8, 1))
+	span.Events = append(span.Events, &otlptrace.Span_Event{
+		TimeUnixNano: uint64(offset),
+		Name:         "exception",
+		Attributes: []*commonpb.KeyValue{
+			common.CreateStringAttribute("exception.type", typ),
+			common.CreateStringAttribute("exception.message", message),
+			common.CreateStringAttribute("exception.stacktrace", syntheticStacktrace(span, typ, message)),
+		},
+	})
+}
+
+// mirrorFailure fails a client span with its server's error and response
+// code.
+func mirrorFailure(client, server *SpanNode) {
+	markError(client, server.errMessage, server.errType)
+	for _, key := range []string{"http.status_code", "rpc.grpc.status_code"} {
+		if code := intAttribute(server.Attributes, key); code != 0 {
+			setIntAttribute(client, key, code)
+		}
+	}
+}
+
+// markError sets the span's error status and error.type attribute.
+func markError(span *SpanNode, message, errType string) {
+	span.Error = true
+	span.errType = errType
+	span.errMessage = message
+	span.Attributes = append(span.Attributes, common.CreateStringAttribute("error.type", errType))
+}
+
+// syntheticStacktrace builds a JVM-style stacktrace naming the span's service.
+func syntheticStacktrace(span *SpanNode, typ, message string) string {
+	pkg := strings.NewReplacer("-", "", "_", "", ".", "").Replace(strings.ToLower(span.Service.Name))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", typ, message)
+	frames := common.RandomInt(3, 8)
+	for i := 0; i < frames; i++ {
+		component := common.RandomChoice(stackFrameComponents)
+		fmt.Fprintf(&b, "\n\tat com.example.%s.%s.handle%d(%s.java:%d)",
+			pkg, component, i, component, common.RandomInt(20, 400))
+	}
+	return b.String()
+}
+
+// httpStatusCode returns the span's http.status_code attribute, or 0.
+func httpStatusCode(span *SpanNode) int64 {
+	if span.Operation.Type != OperationTypeHTTP {
+		return 0
+	}
+	return intAttribute(span.Attributes, "http.status_code")
+}
+
+// setIntAttribute overwrites an existing attribute's value with an int.
+func setIntAttribute(span *SpanNode, key string, v int64) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			kv.Value = &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+			return
+		}
+	}
+}
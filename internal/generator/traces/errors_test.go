@@ -0,0 +1,82 @@
+package traces
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// TestHTTP5xxIsError verifies that without errors config only 5xx HTTP spans
+// fail, with an error status and no exception events.
+func TestHTTP5xxIsError(t *testing.T) {
+	g := testGenerator(t, nil)
+	for i := 0; i < 50; i++ {
+		for _, s := range g.GenerateTrace().CollectSpans() {
+			otlp := s.ToOTLPSpan()
+			is5xx := httpStatusCode(s) >= 500
+			if is5xx != (otlp.Status.Code == otlptrace.Status_STATUS_CODE_ERROR) {
+				t.Fatalf("span with http.status_code %d has status %v", httpStatusCode(s), otlp.Status.Code)
+			}
+			if len(otlp.Events) != 0 {
+				t.Fatalf("unexpected events without errors config: %v", otlp.Events)
+			}
+		}
+	}
+}
+
+// TestInjectedErrorsPropagate verifies failing spans carry an exception event
+// inside the span and, with full propagation, fail every synchronous ancestor.
+func TestInjectedErrorsPropagate(t *testing.T) {
+	g := testGenerator(t, func(c *config.TracesConfig) {
+		c.Errors = config.ErrorsConfig{
+			Services:              map[string]float64{"db": 100},
+			PropagationPercentage: 100,
+		}
+	})
+
+	var check func(s *SpanNode) bool
+	check = func(s *SpanNode) bool {
+		childFailed := false
+		for _, c := range s.Children {
+			childFailed = check(c) || childFailed
+		}
+		if s.Service.Name == "db" && !s.Error {
+			t.Fatalf("db span %s should always fail", s.Operation.Name)
+		}
+		if childFailed && !s.Error {
+			t.Fatalf("%s/%s has a failed child but did not fail", s.Service.Name, s.Operation.Name)
+		}
+		if !s.Error {
+			return false
+		}
+
+		otlp := s.ToOTLPSpan()
+		if otlp.Status.Code != otlptrace.Status_STATUS_CODE_ERROR || otlp.Status.Message == "" {
+			t.Fatalf("failed span status = %v", otlp.Status)
+		}
+		if intAttribute(s.Attributes, "http.status_code") >= 500 && len(s.Events) == 0 {
+			return true // generated 5xx: no exception, just the status
+		}
+		if len(s.Events) != 1 || s.Events[0].Name != "exception" {
+			t.Fatalf("failed span events = %v, want one exception", s.Events)
+		}
+		ev := s.Events[0]
+		if int64(ev.TimeUnixNano) > s.Duration {
+			t.Fatalf("exception offset %d outside span duration %d", ev.TimeUnixNano, s.Duration)
+		}
+		stack := stringAttribute(ev.Attributes, "exception.stacktrace")
+		if typ := stringAttribute(ev.Attributes, "exception.type"); typ == "" || !strings.HasPrefix(stack, typ+": ") {
+			t.Fatalf("exception type %q / stacktrace %q", typ, stack)
+		}
+		if s.Operation.Type == OperationTypeHTTP && intAttribute(s.Attributes, "http.status_code") < 500 {
+			t.Fatalf("failed HTTP span should have a 5xx status")
+		}
+		return true
+	}
+
+	for i := 0; i < 30; i++ {
+		check(g.GenerateTrace().RootSpan)
+	}
+}
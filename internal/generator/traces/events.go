@@ -0,0 +1,156 @@
+package traces
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// defaultEventNames are used when traces.events.names is empty.
+var defaultEventNames = []string{
+	"cache.miss",
+	"retry",
+	"checkpoint",
+	"message.received",
+	"gc.pause",
+	"feature_flag.evaluated",
+}
+
+// maxLinkTargets bounds the pool of earlier-trace spans that cross-trace and
+// batch-consumer links pick from.
+const maxLinkTargets = 4096
+
+// linkTarget is a span in an already generated trace.
+type linkTarget struct {
+	traceID []byte
+	spanID  []byte
+}
+
+// linksEnabled reports whether traces.links asks for any links.
+func linksEnabled(ln config.SpanLinksConfig) bool {
+	return ln.CrossTracePercentage > 0 || ln.InTracePercentage > 0 || ln.BatchConsumer.Percentage > 0
+}
+
+// applyEventsAndLinks adds the configured span events and links to a trace.
+// Durations must be set, since events are placed within their span. With
+// neither configured it draws no random numbers, so output is unchanged.
+func (g *SpanGenerator) applyEventsAndLinks(trace *TraceTemplate) {
+	ev, ln := g.config.Events, g.config.Links
+	if ev.Percentage == 0 && !linksEnabled(ln) {
+		return
+	}
+
+	spans := trace.CollectSpans()
+	if ev.Percentage > 0 {
+		for _, span := range spans {
+			if common.RandomFloat64(0, 100) < ev.Percentage {
+				g.addSpanEvents(span)
+			}
+		}
+	}
+	if !linksEnabled(ln) {
+		return
+	}
+
+	root := trace.RootSpan
+	if ln.InTracePercentage > 0 && len(spans) > 1 {
+		for _, span := range spans[1:] {
+			if common.RandomFloat64(0, 100) < ln.InTracePercentage {
+				target := common.RandomChoice(spans)
+				for target == span {
+					target = common.RandomChoice(spans)
+				}
+				span.Links = append(span.Links, &otlptrace.Span_Link{TraceId: trace.TraceID, SpanId: target.SpanID})
+			}
+		}
+	}
+
+	if len(g.linkTargets) > 0 {
+		if bc := ln.BatchConsumer; bc.Percentage > 0 && common.RandomFloat64(0, 100) < bc.Percentage {
+			g.makeBatchConsumer(root, bc)
+		} else if ln.CrossTracePercentage > 0 && common.RandomFloat64(0, 100) < ln.CrossTracePercentage {
+			target := common.RandomChoice(g.linkTargets)
+			root.Links = append(root.Links, &otlptrace.Span_Link{TraceId: target.traceID, SpanId: target.spanID})
+		}
+	}
+
+	// Offer one span of this trace as a target for later traces.
+	target := linkTarget{traceID: trace.TraceID, spanID: common.RandomChoice(spans).SpanID}
+	if len(g.linkTargets) < maxLinkTargets {
+		g.linkTargets = append(g.linkTargets, target)
+	} else {
+		g.linkTargets[common.RandomInt(0, maxLinkTargets-1)] = target
+	}
+}
+
+// addSpanEvents gives a span min..max events at random points within it.
+func (g *SpanGenerator) addSpanEvents(span *SpanNode) {
+	ev := g.config.Events
+	minEvents, maxEvents := ev.Min, ev.Max
+	if maxEvents == 0 {
+		minEvents, maxEvents = max(minEvents, 1), max(minEvents, 3)
+	}
+	names := ev.Names
+	if len(names) == 0 {
+		names = defaultEventNames
+	}
+	numAttrs := ev.Attributes
+	if numAttrs == 0 {
+		numAttrs = 2
+	}
+
+	count := common.RandomInt(minEvents, maxEvents)
+	events := make([]*otlptrace.Span_Event, 0, count)
+	for i := 0; i < count; i++ {
+		attrs := make([]*commonpb.KeyValue, numAttrs)
+		for j := range attrs {
+			valueLen := 8
+			if ev.ValueBytes > 0 {
+				valueLen = ev.ValueBytes
+			}
+			attrs[j] = common.CreateStringAttribute(fmt.Sprintf("event.detail.%d", j), common.RandomString(valueLen))
+		}
+		events = append(events, &otlptrace.Span_Event{
+			TimeUnixNano: uint64(common.RandomInt64(0, span.Duration)),
+			Name:         common.RandomChoice(names),
+			Attributes:   attrs,
+		})
+	}
+	slices.SortFunc(events, func(a, b *otlptrace.Span_Event) int {
+		return cmp.Compare(a.TimeUnixNano, b.TimeUnixNano)
+	})
+	span.Events = append(span.Events, events...)
+}
+
+// makeBatchConsumer links root to spans in several distinct earlier traces,
+// as a consumer processing a batch of messages from many producers would.
+func (g *SpanGenerator) makeBatchConsumer(root *SpanNode, bc config.BatchConsumerConfig) {
+	minLinks, maxLinks := bc.MinLinks, bc.MaxLinks
+	if minLinks == 0 {
+		minLinks = 5
+	}
+	if maxLinks == 0 {
+		maxLinks = max(20, minLinks)
+	}
+	count := min(common.RandomInt(minLinks, maxLinks), len(g.linkTargets))
+
+	// Partial Fisher-Yates over the indices picks count distinct targets.
+	picked := make([]int, len(g.linkTargets))
+	for i := range picked {
+		picked[i] = i
+	}
+	for i := 0; i < count; i++ {
+		j := common.RandomInt(i, len(picked)-1)
+		picked[i], picked[j] = picked[j], picked[i]
+		target := g.linkTargets[picked[i]]
+		root.Links = append(root.Links, &otlptrace.Span_Link{TraceId: target.traceID, SpanId: target.spanID})
+	}
+
+	root.Kind = otlptrace.Span_SPAN_KIND_CONSUMER
+	root.Attributes = append(root.Attributes, common.CreateIntAttribute("messaging.batch.message_count", int64(count)))
+}
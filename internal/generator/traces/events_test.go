@@ -0,0 +1,92 @@
+package traces
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// TestSpanEvents verifies every span gets min..max named events placed within
+// the span, in time order, with fat attribute values.
+func TestSpanEvents(t *testing.T) {
+	g := testGenerator(t, func(c *config.TracesConfig) {
+		c.Events = config.SpanEventsConfig{
+			Percentage: 100, Min: 2, Max: 4, Names: []string{"retry"}, Attributes: 3, ValueBytes: 64,
+		}
+	})
+
+	for i := 0; i < 20; i++ {
+		for _, s := range g.GenerateTrace().CollectSpans() {
+			if n := len(s.Events); n < 2 || n > 4 {
+				t.Fatalf("span has %d events, want 2..4", n)
+			}
+			var prev uint64
+			for _, ev := range s.Events {
+				if ev.Name != "retry" || len(ev.Attributes) != 3 {
+					t.Fatalf("event %s has %d attributes", ev.Name, len(ev.Attributes))
+				}
+				if v := ev.Attributes[0].Value.GetStringValue(); len(v) != 64 {
+					t.Fatalf("event attribute value is %d bytes, want 64", len(v))
+				}
+				if int64(ev.TimeUnixNano) > s.Duration || ev.TimeUnixNano < prev {
+					t.Fatalf("event offset %d out of order or outside duration %d", ev.TimeUnixNano, s.Duration)
+				}
+				prev = ev.TimeUnixNano
+			}
+		}
+	}
+}
+
+// TestSpanLinks verifies in-trace links stay in their trace and batch
+// consumers link to distinct earlier traces.
+func TestSpanLinks(t *testing.T) {
+	g := testGenerator(t, func(c *config.TracesConfig) {
+		c.Links = config.SpanLinksConfig{
+			InTracePercentage: 100,
+			BatchConsumer:     config.BatchConsumerConfig{Percentage: 100, MinLinks: 3, MaxLinks: 3},
+		}
+	})
+
+	seen := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		trace := g.GenerateTrace()
+		spans := trace.CollectSpans()
+		ids := map[string]bool{}
+		for _, s := range spans {
+			ids[string(s.SpanID)] = true
+		}
+		for _, s := range spans[1:] {
+			if len(s.Links) != 1 || !bytes.Equal(s.Links[0].TraceId, trace.TraceID) || !ids[string(s.Links[0].SpanId)] {
+				t.Fatalf("in-trace link %v does not target this trace", s.Links)
+			}
+			if bytes.Equal(s.Links[0].SpanId, s.SpanID) {
+				t.Fatalf("span links to itself")
+			}
+		}
+
+		root := trace.RootSpan.ToOTLPSpan()
+		if i == 0 {
+			if len(root.Links) != 0 {
+				t.Fatalf("first trace has nothing to link to but has %d links", len(root.Links))
+			}
+		} else {
+			if root.Kind != otlptrace.Span_SPAN_KIND_CONSUMER {
+				t.Fatalf("batch consumer root kind = %v", root.Kind)
+			}
+			want := min(3, i)
+			targets := map[string]bool{}
+			for _, l := range root.Links {
+				if !seen[string(l.TraceId)] {
+					t.Fatalf("link to unknown trace %x", l.TraceId)
+				}
+				targets[string(l.TraceId)] = true
+			}
+			if len(root.Links) != want || len(targets) != want {
+				t.Fatalf("batch consumer has %d links to %d traces, want %d", len(root.Links), len(targets), want)
+			}
+		}
+		seen[string(trace.TraceID)] = true
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/inventory"
 )
 
 // Generator is the main trace generator
@@ -14,23 +15,61 @@ type Generator struct {
 	writer       *TraceWriter
 }
 
-// NewGenerator creates a new trace generator
-func NewGenerator(cfg *config.TracesConfig, outputDir, prefix string) *Generator {
+// NewGenerator creates a new trace generator. It fails when
+// traces.model.source is set and the source can't be read or learned from, or
+// a declared attribute can't build its value generator.
+func NewGenerator(cfg *config.TracesConfig, outputDir, prefix string) (*Generator, error) {
+	serviceNames, namespaces := cfg.Services.Names, cfg.Services.ResolvedNamespaces
+
+	var model *TraceModel
+	if cfg.Model.ModelEnabled() {
+		var err error
+		model, err = LoadModel(cfg.Model.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to learn trace model: %w", err)
+		}
+		serviceNames, namespaces = model.Services, model.Namespaces
+	}
+
 	topology := BuildTopology(
-		cfg.Services.Names,
+		serviceNames,
 		cfg.Services.Ingress.Single,
 		cfg.Services.Ingress.Service,
-		cfg.Services.ResolvedNamespaces,
+		namespaces,
 	)
+	if len(cfg.Services.Graph) > 0 {
+		topology.ApplyGraph(cfg.Services.Graph, cfg.Services.Ingress.Single)
+	}
 
-	spanGen := NewSpanGenerator(cfg, topology)
+	spanGen, err := NewSpanGenerator(cfg, topology)
+	if err != nil {
+		return nil, err
+	}
+	if model != nil {
+		spanGen.UseModel(model)
+	}
 	writer := NewTraceWriter(outputDir, prefix)
+	if cfg.Resources.PerService {
+		writer.resources = newServiceResources(cfg.Resources)
+	}
 
 	return &Generator{
 		config:   cfg,
 		topology: topology,
 		spanGen:  spanGen,
 		writer:   writer,
+	}, nil
+}
+
+// UseInventory registers every service as a deployment in the inventory
+// shared with the other signals, whose pods then describe the services in
+// per-service resources.
+func (g *Generator) UseInventory(inv *inventory.Inventory) {
+	for _, service := range g.topology.Services {
+		inv.Service(service.Name, service.Namespace)
+	}
+	if g.writer.resources != nil {
+		g.writer.resources.inventory = inv
 	}
 }
 
@@ -38,9 +77,22 @@ func NewGenerator(cfg *config.TracesConfig, outputDir, prefix string) *Generator
 func (g *Generator) Generate(writeJSON bool) error {
 	fmt.Println("Generating traces...")
 	fmt.Printf("  Target trace count: %d\n", g.config.Count)
-	fmt.Printf("  Avg spans per trace: %d (±%d)\n",
-		g.config.Spans.AvgPerTrace, g.config.Spans.StdDev)
-	fmt.Printf("  Services: %d\n", g.config.Services.Count)
+	if model := g.spanGen.model; model != nil {
+		fmt.Printf("  Model: %s (%d traces, %d spans)\n", g.config.Model.Source, model.TraceCount, model.SpanCount)
+		fmt.Printf("  Learned: %d services, %d operations, max %d spans / depth %d\n",
+			len(model.Services), model.OperationCount(), model.MaxSpans, model.MaxDepth)
+	} else {
+		fmt.Printf("  Avg spans per trace: %d (±%d)\n",
+			g.config.Spans.AvgPerTrace, g.config.Spans.StdDev)
+		fmt.Printf("  Services: %d\n", g.config.Services.Count)
+	}
+	if g.topology.HasGraph() {
+		edges := 0
+		for _, service := range g.topology.Services {
+			edges += len(service.Edges)
+		}
+		fmt.Printf("  Service graph: %d declared calls, %d entry point(s)\n", edges, len(g.topology.IngressServices))
+	}
 
 	traces := make([]*TraceTemplate, 0, g.config.Count)
 
@@ -48,6 +100,7 @@ func (g *Generator) Generate(writeJSON bool) error {
 	for i := 0; i < g.config.Count; i++ {
 		trace := g.spanGen.GenerateTrace()
 		traces = append(traces, trace)
+		traces = append(traces, trace.FollowOn...)
 
 		if (i+1)%1000 == 0 {
 			fmt.Printf("  Generated %d/%d traces\n", i+1, g.config.Count)
@@ -0,0 +1,184 @@
+package traces
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+)
+
+// z99 is the standard normal quantile for the 99th percentile, used to derive
+// a lognormal's sigma from its median and p99.
+const z99 = 2.3263478740408408
+
+// latencySampler draws one latency in milliseconds.
+type latencySampler func() float64
+
+// latencyModel resolves traces.latency into samplers. A nil *latencyModel
+// (no latency config) leaves the historic duration behavior in place.
+type latencyModel struct {
+	def        latencySampler
+	services   map[string]latencySampler
+	operations map[opKey]latencySampler // service "" matches any service
+	slow       map[string]config.SlowServiceConfig
+	outliers   config.OutlierConfig
+}
+
+// newLatencyModel builds the latency model, or returns nil when traces.latency
+// is empty so durations keep their historic shape.
+func newLatencyModel(cfg config.LatencyConfig) *latencyModel {
+	if !cfg.Default.Configured() && len(cfg.Services) == 0 && len(cfg.Operations) == 0 &&
+		len(cfg.SlowServices) == 0 && cfg.Outliers.Percentage == 0 {
+		return nil
+	}
+
+	l := &latencyModel{
+		services:   make(map[string]latencySampler, len(cfg.Services)),
+		operations: make(map[opKey]latencySampler, len(cfg.Operations)),
+		slow:       make(map[string]config.SlowServiceConfig, len(cfg.SlowServices)),
+		outliers:   cfg.Outliers,
+	}
+	if cfg.Default.Configured() {
+		l.def = newLatencySampler(cfg.Default)
+	}
+	for name, d := range cfg.Services {
+		l.services[name] = newLatencySampler(d)
+	}
+	for _, op := range cfg.Operations {
+		l.operations[opKey{service: op.Service, name: op.Operation}] = newLatencySampler(op.LatencyDistribution)
+	}
+	for _, slow := range cfg.SlowServices {
+		l.slow[slow.Service] = slow
+	}
+	if l.outliers.MinFactor == 0 {
+		l.outliers.MinFactor = 10
+	}
+	if l.outliers.MaxFactor == 0 {
+		l.outliers.MaxFactor = max(50, l.outliers.MinFactor)
+	}
+	return l
+}
+
+// sampler returns the most specific sampler for a span: operation in this
+// service, operation in any service, service, then (if useDefault) the
+// default. It returns nil when nothing matches.
+func (l *latencyModel) sampler(span *SpanNode, useDefault bool) latencySampler {
+	if s, ok := l.operations[opKey{service: span.Service.Name, name: span.Operation.Name}]; ok {
+		return s
+	}
+	if s, ok := l.operations[opKey{name: span.Operation.Name}]; ok {
+		return s
+	}
+	if s, ok := l.services[span.Service.Name]; ok {
+		return s
+	}
+	if useDefault {
+		return l.def
+	}
+	return nil
+}
+
+// adjust applies slow-service injection and long-tail outliers to a span's
+// own latency.
+func (l *latencyModel) adjust(span *SpanNode, nanos int64) int64 {
+	d := float64(nanos)
+	if slow, ok := l.slow[span.Service.Name]; ok && common.RandomFloat64(0, 100) < slow.Percentage {
+		d *= slow.Factor
+	}
+	if l.outliers.Percentage > 0 && common.RandomFloat64(0, 100) < l.outliers.Percentage {
+		d *= common.RandomFloat64(l.outliers.MinFactor, l.outliers.MaxFactor)
+	}
+	return int64(d)
+}
+
+// newLatencySampler builds a sampler for a validated distribution.
+func newLatencySampler(d config.LatencyDistribution) latencySampler {
+	var sample latencySampler
+	switch d.Distribution {
+	case "uniform":
+		sample = func() float64 { return common.RandomFloat64(d.MinMs, d.MaxMs) }
+	case "lognormal":
+		sample = lognormalSampler(d.MedianMs, d.P99Ms)
+	case "pareto":
+		sample = func() float64 {
+			// Inverse CDF; 1-U keeps the base in (0, 1].
+			return d.MinMs / math.Pow(1-common.RandomFloat64(0, 1), 1/d.Alpha)
+		}
+	case "bimodal":
+		fast := lognormalSampler(d.MedianMs, d.P99Ms)
+		// The slow mode keeps the fast mode's relative spread.
+		slow := lognormalSampler(d.SlowMedianMs, d.SlowMedianMs*d.P99Ms/d.MedianMs)
+		sample = func() float64 {
+			if common.RandomFloat64(0, 100) < d.SlowPercentage {
+				return slow()
+			}
+			return fast()
+		}
+	case "percentiles":
+		sample = percentileSampler(d)
+	default:
+		return nil
+	}
+
+	if d.MaxMs > 0 {
+		capped := sample
+		sample = func() float64 { return min(capped(), d.MaxMs) }
+	}
+	return sample
+}
+
+// lognormalSampler returns a lognormal sampler with the given median and p99.
+func lognormalSampler(medianMs, p99Ms float64) latencySampler {
+	mu := math.Log(medianMs)
+	sigma := math.Log(p99Ms/medianMs) / z99
+	return func() float64 {
+		return math.Exp(mu + sigma*common.RandomNormFloat64())
+	}
+}
+
+// percentileSampler samples by linear interpolation of the inverse CDF
+// through the configured percentile points, starting at min_ms for p0. Draws
+// above the highest percentile return its value.
+func percentileSampler(d config.LatencyDistribution) latencySampler {
+	type point struct{ p, ms float64 }
+	points := []point{{0, d.MinMs}}
+	for key, ms := range d.Percentiles {
+		p, _ := config.ParsePercentileKey(key) // validated by config
+		points = append(points, point{p, ms})
+	}
+	slices.SortFunc(points, func(a, b point) int { return cmp.Compare(a.p, b.p) })
+
+	return func() float64 {
+		u := common.RandomFloat64(0, 100)
+		for i := 1; i < len(points); i++ {
+			lo, hi := points[i-1], points[i]
+			if u <= hi.p {
+				return lo.ms + (hi.ms-lo.ms)*(u-lo.p)/(hi.p-lo.p)
+			}
+		}
+		return points[len(points)-1].ms
+	}
+}
+
+// ownLatency returns a span's own latency in nanoseconds: drawn from the most
+// specific traces.latency distribution matching the span, else fallback, with
+// slow-service and outlier adjustments applied either way. sampled reports
+// whether a distribution matched. Without latency config it returns fallback
+// untouched and draws no random numbers, so output is unchanged.
+func (g *SpanGenerator) ownLatency(span *SpanNode, fallback int64, useDefault bool) (nanos int64, sampled bool) {
+	if g.latency == nil {
+		return fallback, false
+	}
+	nanos = fallback
+	if s := g.latency.sampler(span, useDefault); s != nil {
+		nanos, sampled = msToNanos(s()), true
+	}
+	return g.latency.adjust(span, nanos), sampled
+}
+
+// msToNanos converts a sampled latency to nanoseconds, never below 1µs.
+func msToNanos(ms float64) int64 {
+	return max(int64(ms*1e6), 1000)
+}
@@ -0,0 +1,162 @@
+package traces
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+)
+
+// quantile draws n samples and returns the q-th quantile (0-1).
+func quantile(s latencySampler, n int, q float64) float64 {
+	draws := make([]float64, n)
+	for i := range draws {
+		draws[i] = s()
+	}
+	slices.Sort(draws)
+	return draws[int(q*float64(n-1))]
+}
+
+func within(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= want*tolerance
+}
+
+// TestLatencyDistributions checks each distribution roughly hits the
+// percentiles it was configured with.
+func TestLatencyDistributions(t *testing.T) {
+	const n = 20000
+
+	// The percentiles distribution's CDF kinks at its configured points,
+	// so a sample quantile there strays further than elsewhere; a fixed
+	// seed keeps the checks below deterministic.
+	common.SetSeed(29)
+	defer common.SetSeed(0)
+
+	logn := newLatencySampler(config.LatencyDistribution{Distribution: "lognormal", MedianMs: 20, P99Ms: 400})
+	if got := quantile(logn, n, 0.5); !within(got, 20, 0.1) {
+		t.Errorf("lognormal p50 = %.1fms, want ~20", got)
+	}
+	if got := quantile(logn, n, 0.99); !within(got, 400, 0.2) {
+		t.Errorf("lognormal p99 = %.1fms, want ~400", got)
+	}
+
+	pct := newLatencySampler(config.LatencyDistribution{
+		Distribution: "percentiles", MinMs: 1,
+		Percentiles: map[string]float64{"p50": 10, "p90": 50, "p99": 300},
+	})
+	if got := quantile(pct, n, 0.5); !within(got, 10, 0.1) {
+		t.Errorf("percentiles p50 = %.1fms, want ~10", got)
+	}
+	if got := quantile(pct, n, 0.9); !within(got, 50, 0.1) {
+		t.Errorf("percentiles p90 = %.1fms, want ~50", got)
+	}
+	if got := quantile(pct, n, 1); got > 300 {
+		t.Errorf("percentiles max = %.1fms, want <= 300", got)
+	}
+
+	pareto := newLatencySampler(config.LatencyDistribution{Distribution: "pareto", MinMs: 5, Alpha: 1.2, MaxMs: 2000})
+	if got := quantile(pareto, n, 0); got < 5 {
+		t.Errorf("pareto min = %.2fms, want >= 5", got)
+	}
+	if got := quantile(pareto, n, 1); got > 2000 {
+		t.Errorf("pareto max = %.1fms, want capped at 2000", got)
+	}
+
+	bimodal := newLatencySampler(config.LatencyDistribution{
+		Distribution: "bimodal", MedianMs: 10, P99Ms: 20, SlowMedianMs: 500, SlowPercentage: 20,
+	})
+	slow := 0
+	for i := 0; i < n; i++ {
+		if bimodal() > 100 {
+			slow++
+		}
+	}
+	if frac := float64(slow) / n; !within(frac, 0.2, 0.1) {
+		t.Errorf("bimodal slow fraction = %.3f, want ~0.2", frac)
+	}
+}
+
+// TestLatencyConfigShapesSpans verifies operation overrides win over service
+// and default distributions, slow services are slowed, and parents still
+// cover their children.
+func TestLatencyConfigShapesSpans(t *testing.T) {
+	cfg := &config.TracesConfig{
+		Spans:    config.SpansConfig{AvgPerTrace: 12, StdDev: 0},
+		Services: config.ServicesConfig{Count: 3, Names: []string{"api", "worker", "db"}},
+		Latency: config.LatencyConfig{
+			Default: config.LatencyDistribution{Distribution: "uniform", MinMs: 1, MaxMs: 2},
+			Services: map[string]config.LatencyDistribution{
+				"worker": {Distribution: "uniform", MinMs: 30, MaxMs: 31},
+			},
+			SlowServices: []config.SlowServiceConfig{{Service: "db", Percentage: 100, Factor: 100}},
+		},
+	}
+	topo := testTopology()
+	for _, op := range topo.Services[1].Operations[:1] {
+		cfg.Latency.Operations = append(cfg.Latency.Operations, config.OperationLatency{
+			Service: "worker", Operation: op.Name,
+			LatencyDistribution: config.LatencyDistribution{Distribution: "uniform", MinMs: 700, MaxMs: 701},
+		})
+	}
+	override := cfg.Latency.Operations[0].Operation
+	g := newSpanGenerator(t, cfg, topo)
+
+	for i := 0; i < 30; i++ {
+		for _, s := range g.GenerateTrace().CollectSpans() {
+			ms := float64(s.Duration) / 1e6
+			var childSum int64
+			for _, c := range s.Children {
+				childSum += c.Duration
+			}
+			if s.Duration < childSum {
+				t.Fatalf("%s/%s lasts %d, less than its children (%d)", s.Service.Name, s.Operation.Name, s.Duration, childSum)
+			}
+			if len(s.Children) > 0 {
+				continue
+			}
+			switch {
+			case s.Service.Name == "worker" && s.Operation.Name == override:
+				if ms < 700 || ms > 701 {
+					t.Fatalf("worker/%s leaf = %.1fms, want operation override 700-701ms", override, ms)
+				}
+			case s.Service.Name == "worker":
+				if ms < 30 || ms > 31 {
+					t.Fatalf("worker leaf = %.1fms, want service distribution 30-31ms", ms)
+				}
+			case s.Service.Name == "db":
+				if ms < 100 || ms > 200 {
+					t.Fatalf("db leaf = %.1fms, want default 1-2ms slowed 100x", ms)
+				}
+			default:
+				if ms < 1 || ms > 2 {
+					t.Fatalf("api leaf = %.1fms, want default 1-2ms", ms)
+				}
+			}
+		}
+	}
+}
+
+// TestLatencyOutliers verifies outliers stretch roughly the configured share
+// of spans, and that no latency config means no latency model.
+func TestLatencyOutliers(t *testing.T) {
+	if newLatencyModel(config.LatencyConfig{}) != nil {
+		t.Fatal("empty latency config should leave durations untouched")
+	}
+
+	l := newLatencyModel(config.LatencyConfig{Outliers: config.OutlierConfig{Percentage: 10}})
+	span := &SpanNode{Service: &ServiceNode{Name: "api"}}
+	stretched := 0
+	for i := 0; i < 10000; i++ {
+		if d := l.adjust(span, 1e6); d != 1e6 {
+			if d < 10e6 || d > 50e6 {
+				t.Fatalf("outlier = %dns, want 10-50x of 1ms", d)
+			}
+			stretched++
+		}
+	}
+	if frac := float64(stretched) / 10000; !within(frac, 0.1, 0.15) {
+		t.Fatalf("outlier fraction = %.3f, want ~0.1", frac)
+	}
+}
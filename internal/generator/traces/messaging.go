@@ -0,0 +1,142 @@
+package traces
+
+import (
+	"encoding/hex"
+	"strconv"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// defaultQueueLag is used when traces.messaging.queue_lag is unset.
+var defaultQueueLag = config.LatencyDistribution{Distribution: "uniform", MinMs: 1, MaxMs: 50}
+
+// newQueueLagSampler returns the sampler for broker queue lag.
+func newQueueLagSampler(d config.LatencyDistribution) latencySampler {
+	if !d.Configured() {
+		d = defaultQueueLag
+	}
+	return newLatencySampler(d)
+}
+
+// expandMessaging turns each messaging hop in the trace into a PRODUCER span
+// in the calling service whose child is the CONSUMER span in the callee.
+// Consumers normally continue the trace after a queue lag; with
+// separate_trace_percentage some start a follow-on trace linked to the
+// producer instead. Hops come from graph calls with protocol "messaging" and,
+// in the generated topology, traces.messaging.percentage of cross-service
+// calls, so with neither no random numbers are drawn.
+func (g *SpanGenerator) expandMessaging(trace *TraceTemplate) {
+	if g.expandMessagingHops(trace, trace.RootSpan, &trace.FollowOn) {
+		trace.SpanCount = len(trace.CollectSpans())
+	}
+}
+
+// expandMessagingHops expands the hops below parent, which belongs to trace,
+// and reports whether it changed the trace.
+func (g *SpanGenerator) expandMessagingHops(trace *TraceTemplate, parent *SpanNode, followOn *[]*TraceTemplate) bool {
+	mc := g.config.Messaging
+	changed := false
+
+	for i, child := range parent.Children {
+		if !g.messagingHop(parent, child) {
+			changed = g.expandMessagingHops(trace, child, followOn) || changed
+			continue
+		}
+		changed = true
+
+		producer := g.newProducer(parent, child)
+		parent.Children[i] = producer
+
+		if mc.SeparateTracePercentage > 0 && common.RandomFloat64(0, 100) < mc.SeparateTracePercentage {
+			child.ParentID = nil
+			child.Links = append(child.Links, &otlptrace.Span_Link{TraceId: trace.TraceID, SpanId: producer.SpanID})
+			consumerTrace := &TraceTemplate{TraceID: generateTraceID(), RootSpan: child}
+			*followOn = append(*followOn, consumerTrace)
+			g.expandMessagingHops(consumerTrace, child, followOn)
+			consumerTrace.SpanCount = len(consumerTrace.CollectSpans())
+			continue
+		}
+
+		child.ParentID = producer.SpanID
+		child.queueLag = max(msToNanos(g.queueLag()), 1)
+		producer.Children = []*SpanNode{child}
+		g.expandMessagingHops(trace, child, followOn)
+	}
+
+	return changed
+}
+
+// messagingHop reports whether the call from parent to child goes through a
+// broker, converting child into a consumer when traces.messaging.percentage
+// picks a generated cross-service call.
+func (g *SpanGenerator) messagingHop(parent, child *SpanNode) bool {
+	if child.Operation.Type == OperationTypeMessaging {
+		return true
+	}
+	mc := g.config.Messaging
+	if mc.Percentage == 0 || g.topology.HasGraph() || child.Service == parent.Service {
+		return false
+	}
+	if common.RandomFloat64(0, 100) >= mc.Percentage {
+		return false
+	}
+
+	ops, _ := child.Service.operationsFor(OperationTypeMessaging)
+	child.Operation = ops[0]
+	child.Attributes = g.generateAttributes(child.Service, child.Operation)
+	return true
+}
+
+// newProducer creates the PRODUCER span publishing consumer's message from
+// parent's service, and marks consumer as the CONSUMER side. Both carry the
+// message's partition and ID.
+func (g *SpanGenerator) newProducer(parent, consumer *SpanNode) *SpanNode {
+	mc := g.config.Messaging
+	if mc.System != "" && mc.System != consumer.Operation.MessagingSystem {
+		consumer.Operation.MessagingSystem = mc.System
+		for _, kv := range consumer.Attributes {
+			if kv.Key == "messaging.system" {
+				kv.Value = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: mc.System}}
+			}
+		}
+	}
+	partitions := mc.Partitions
+	if partitions == 0 {
+		partitions = 12
+	}
+
+	op := consumer.Operation
+	publish := Operation{
+		Name:               op.Destination + " publish",
+		Type:               OperationTypeMessaging,
+		MessagingSystem:    op.MessagingSystem,
+		Destination:        op.Destination,
+		MessagingOperation: "publish",
+	}
+	partition := common.RandomInt(0, partitions-1)
+	messageID := hex.EncodeToString(common.RandomBytes(16))
+	message := func() []*commonpb.KeyValue {
+		return []*commonpb.KeyValue{
+			common.CreateStringAttribute("messaging.destination.partition.id", strconv.Itoa(partition)),
+			common.CreateStringAttribute("messaging.message.id", messageID),
+		}
+	}
+
+	producer := &SpanNode{
+		SpanID:     generateSpanID(),
+		ParentID:   parent.SpanID,
+		Service:    parent.Service,
+		Operation:  publish,
+		Kind:       otlptrace.Span_SPAN_KIND_PRODUCER,
+		Attributes: append(g.generateAttributes(parent.Service, publish), message()...),
+	}
+
+	consumer.Kind = otlptrace.Span_SPAN_KIND_CONSUMER
+	consumer.Attributes = append(consumer.Attributes, message()...)
+	consumer.Attributes = append(consumer.Attributes,
+		common.CreateStringAttribute("messaging.consumer.group.name", consumer.Service.Name))
+	return producer
+}
@@ -0,0 +1,110 @@
+package traces
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// TestMessagingHops verifies every messaging hop is a producer in the caller
+// with a consumer child in the callee that starts after the queue lag and
+// shares the message's attributes.
+func TestMessagingHops(t *testing.T) {
+	g := testGenerator(t, func(c *config.TracesConfig) {
+		c.Messaging = config.MessagingConfig{
+			Percentage: 100,
+			System:     "rabbitmq",
+			QueueLag:   config.LatencyDistribution{Distribution: "uniform", MinMs: 100, MaxMs: 200},
+		}
+	})
+
+	hops := 0
+	for i := 0; i < 20; i++ {
+		trace := g.GenerateTrace()
+		if got := len(trace.CollectSpans()); got != trace.SpanCount {
+			t.Fatalf("SpanCount = %d, trace has %d spans", trace.SpanCount, got)
+		}
+		forEach(trace.RootSpan, func(parent *SpanNode) {
+			for _, child := range parent.Children {
+				if child.Service != parent.Service && parent.Kind != otlptrace.Span_SPAN_KIND_PRODUCER {
+					t.Fatalf("cross-service call %s -> %s without a producer", parent.Service.Name, child.Service.Name)
+				}
+			}
+			if parent.Kind != otlptrace.Span_SPAN_KIND_PRODUCER {
+				return
+			}
+			hops++
+			if len(parent.Children) != 1 {
+				t.Fatalf("producer has %d children, want 1", len(parent.Children))
+			}
+			consumer := parent.Children[0]
+			if consumer.ToOTLPSpan().Kind != otlptrace.Span_SPAN_KIND_CONSUMER {
+				t.Fatalf("producer child kind = %v", consumer.ToOTLPSpan().Kind)
+			}
+			if lag := consumer.StartTime - parent.StartTime; lag < 100e6 || lag > 200e6 {
+				t.Fatalf("queue lag %dns outside 100-200ms", lag)
+			}
+			for _, key := range []string{"messaging.system", "messaging.destination.partition.id", "messaging.message.id"} {
+				if p, c := stringAttribute(parent.Attributes, key), stringAttribute(consumer.Attributes, key); p == "" || p != c {
+					t.Fatalf("%s: producer %q, consumer %q", key, p, c)
+				}
+			}
+			if sys := stringAttribute(consumer.Attributes, "messaging.system"); sys != "rabbitmq" {
+				t.Fatalf("messaging.system = %q", sys)
+			}
+			if stringAttribute(parent.Attributes, "messaging.operation") != "publish" {
+				t.Fatalf("producer messaging.operation = %q", stringAttribute(parent.Attributes, "messaging.operation"))
+			}
+		})
+	}
+	if hops == 0 {
+		t.Fatal("no messaging hops generated")
+	}
+}
+
+// TestMessagingSeparateTraces verifies consumers can start follow-on traces
+// linked to their producer span.
+func TestMessagingSeparateTraces(t *testing.T) {
+	g := testGenerator(t, func(c *config.TracesConfig) {
+		c.Messaging = config.MessagingConfig{Percentage: 100, SeparateTracePercentage: 100}
+	})
+
+	followOns := 0
+	for i := 0; i < 20; i++ {
+		trace := g.GenerateTrace()
+		producers := map[string][]byte{}
+		for _, tr := range append([]*TraceTemplate{trace}, trace.FollowOn...) {
+			forEach(tr.RootSpan, func(s *SpanNode) {
+				if s.Kind == otlptrace.Span_SPAN_KIND_PRODUCER {
+					if len(s.Children) != 0 {
+						t.Fatalf("producer kept its consumer")
+					}
+					producers[string(s.SpanID)] = tr.TraceID
+				}
+			})
+		}
+		for _, tr := range trace.FollowOn {
+			followOns++
+			root := tr.RootSpan
+			if root.ParentID != nil || len(root.Links) == 0 {
+				t.Fatalf("follow-on root has parent %x, %d links", root.ParentID, len(root.Links))
+			}
+			link := root.Links[0]
+			if traceID, ok := producers[string(link.SpanId)]; !ok || !bytes.Equal(traceID, link.TraceId) {
+				t.Fatalf("follow-on link does not target a producer span")
+			}
+		}
+	}
+	if followOns == 0 {
+		t.Fatal("no follow-on traces generated")
+	}
+}
+
+func forEach(span *SpanNode, fn func(*SpanNode)) {
+	fn(span)
+	for _, child := range span.Children {
+		forEach(child, fn)
+	}
+}
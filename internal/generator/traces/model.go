@@ -0,0 +1,646 @@
+package traces
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	otlpcollectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// maxModelSamples bounds the per-operation reservoirs of observed child
+	// lists and latencies, so learning from a large capture stays cheap.
+	maxModelSamples = 512
+
+	// maxTrackedValues bounds the distinct values counted per attribute key.
+	// Keys above it are modeled as having exactly this cardinality.
+	maxTrackedValues = 10000
+
+	// maxPreservedValues bounds the verbatim values kept per preserved key.
+	maxPreservedValues = 64
+)
+
+// preservedAttributeKeys are low-cardinality semantic-convention keys whose
+// observed values carry no identifying data and are replayed verbatim. Every
+// other attribute value is synthesized.
+var preservedAttributeKeys = map[string]bool{
+	"http.method":               true,
+	"http.request.method":       true,
+	"http.status_code":          true,
+	"http.response.status_code": true,
+	"rpc.system":                true,
+	"db.system":                 true,
+	"messaging.system":          true,
+	"messaging.operation":       true,
+	"span.kind":                 true,
+}
+
+// opKey identifies an operation in the model: a span name within a service.
+type opKey struct {
+	service string
+	name    string
+}
+
+// TraceModel is a statistical model of trace shape learned from captured
+// traces. Synthetic traces are sampled by picking a root operation by its
+// observed frequency and recursively expanding each span with a child list
+// observed for the same operation at the same depth, so edge frequencies,
+// fan-out, span counts and depths follow the source.
+type TraceModel struct {
+	// Services are the service names seen in the source, in first-seen order.
+	Services []string
+	// Namespaces maps service name → service.namespace, when the source had one.
+	Namespaces map[string]string
+
+	TraceCount int
+	SpanCount  int
+	MaxSpans   int
+	MaxDepth   int
+
+	roots       []opKey
+	rootWeights []int
+	ops         map[opKey]*opModel
+}
+
+// opModel holds what was observed for one operation.
+type opModel struct {
+	kind      otlptrace.Span_SpanKind
+	operation Operation
+	seen      int
+	durations []int64
+
+	// childSets holds observed child lists per depth, since an operation
+	// called near the root typically fans out more than the same operation
+	// called deep in a trace. seenAt counts observations per depth.
+	childSets map[int][][]opKey
+	seenAt    map[int]int
+
+	attrs     []*attrModel
+	attrIndex map[string]*attrModel
+}
+
+type attrValueKind int
+
+const (
+	attrString attrValueKind = iota
+	attrInt
+	attrDouble
+	attrBool
+)
+
+// attrModel describes one attribute key of an operation: how often it is
+// present, its value type, cardinality and numeric range.
+type attrModel struct {
+	key      string
+	kind     attrValueKind
+	seen     int
+	presence float64
+	distinct int
+	min, max float64
+
+	// preserved holds verbatim values for preservedAttributeKeys.
+	preserved []*commonpb.AnyValue
+	values    map[string]struct{}
+}
+
+// capturedSpan is a source span with its resolved service.
+type capturedSpan struct {
+	span      *otlptrace.Span
+	service   string
+	namespace string
+}
+
+// LoadModel reads an OTLP trace export and learns a TraceModel from it. Files
+// with a .json extension are read as OTLP/JSON, anything else as binary
+// protobuf (ExportTraceServiceRequest and TracesData share a wire format).
+func LoadModel(path string) (*TraceModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	request := &otlpcollectortrace.ExportTraceServiceRequest{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = protojson.Unmarshal(data, request)
+	} else {
+		err = proto.Unmarshal(data, request)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return LearnModel(request.ResourceSpans)
+}
+
+// LearnModel builds a TraceModel from captured ResourceSpans.
+func LearnModel(resourceSpans []*otlptrace.ResourceSpans) (*TraceModel, error) {
+	m := &TraceModel{
+		Namespaces: make(map[string]string),
+		ops:        make(map[opKey]*opModel),
+	}
+
+	// Group spans by trace, keeping first-seen order so a seeded run learns
+	// the same model every time.
+	var traceOrder []string
+	byTrace := make(map[string][]*capturedSpan)
+	knownServices := make(map[string]bool)
+
+	for _, rs := range resourceSpans {
+		resService, resNamespace := "", ""
+		if rs.Resource != nil {
+			resService = stringAttribute(rs.Resource.Attributes, "service.name")
+			resNamespace = stringAttribute(rs.Resource.Attributes, "service.namespace")
+		}
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				cs := &capturedSpan{span: span, service: resService, namespace: resNamespace}
+				// Per-span service.name wins over the resource, so traces
+				// spanning several services in one resource are modeled right.
+				if name := stringAttribute(span.Attributes, "service.name"); name != "" {
+					cs.service = name
+					cs.namespace = stringAttribute(span.Attributes, "service.namespace")
+				}
+				if cs.service == "" {
+					cs.service = "unknown_service"
+				}
+				if !knownServices[cs.service] {
+					knownServices[cs.service] = true
+					m.Services = append(m.Services, cs.service)
+				}
+				if cs.namespace != "" {
+					m.Namespaces[cs.service] = cs.namespace
+				}
+
+				id := string(span.TraceId)
+				if _, ok := byTrace[id]; !ok {
+					traceOrder = append(traceOrder, id)
+				}
+				byTrace[id] = append(byTrace[id], cs)
+			}
+		}
+	}
+
+	if len(traceOrder) == 0 {
+		return nil, fmt.Errorf("source contains no spans")
+	}
+
+	rootCounts := make(map[opKey]int)
+	for _, id := range traceOrder {
+		m.learnTrace(byTrace[id], rootCounts)
+	}
+	for _, key := range m.roots {
+		m.rootWeights = append(m.rootWeights, rootCounts[key])
+	}
+	for _, op := range m.ops {
+		op.finalize()
+	}
+
+	return m, nil
+}
+
+// learnTrace folds one captured trace into the model.
+func (m *TraceModel) learnTrace(spans []*capturedSpan, rootCounts map[opKey]int) {
+	byID := make(map[string]*capturedSpan, len(spans))
+	for _, cs := range spans {
+		byID[string(cs.span.SpanId)] = cs
+	}
+
+	var roots []*capturedSpan
+	children := make(map[string][]*capturedSpan)
+	for _, cs := range spans {
+		parent := string(cs.span.ParentSpanId)
+		if _, ok := byID[parent]; len(cs.span.ParentSpanId) == 0 || !ok {
+			roots = append(roots, cs)
+			continue
+		}
+		children[parent] = append(children[parent], cs)
+	}
+	for _, list := range children {
+		sort.SliceStable(list, func(i, j int) bool {
+			return spanStartOffset(list[i].span) < spanStartOffset(list[j].span)
+		})
+	}
+
+	m.TraceCount++
+	m.SpanCount += len(spans)
+	if len(spans) > m.MaxSpans {
+		m.MaxSpans = len(spans)
+	}
+
+	// Malformed captures can repeat span IDs or parent a span on itself;
+	// each span ID is walked once so such cycles end.
+	visited := make(map[string]bool, len(spans))
+	var walk func(cs *capturedSpan, depth int)
+	walk = func(cs *capturedSpan, depth int) {
+		if visited[string(cs.span.SpanId)] {
+			return
+		}
+		visited[string(cs.span.SpanId)] = true
+		if depth > m.MaxDepth {
+			m.MaxDepth = depth
+		}
+		kids := children[string(cs.span.SpanId)]
+		childKeys := make([]opKey, len(kids))
+		for i, kid := range kids {
+			childKeys[i] = opKey{service: kid.service, name: kid.span.Name}
+		}
+		m.learnSpan(cs, childKeys, depth)
+		for _, kid := range kids {
+			walk(kid, depth+1)
+		}
+	}
+
+	for _, root := range roots {
+		key := opKey{service: root.service, name: root.span.Name}
+		if rootCounts[key] == 0 {
+			m.roots = append(m.roots, key)
+		}
+		rootCounts[key]++
+		walk(root, 1)
+	}
+}
+
+// learnOperation derives an operation's protocol from a captured span: its
+// semantic-convention attributes first, then its kind for messaging. Errors on
+// the generated spans then get their protocol's status codes and exceptions.
+// Only preserved attribute values are copied.
+func learnOperation(name string, span *otlptrace.Span) Operation {
+	op := Operation{Name: name, Type: OperationTypeInternal}
+	method := stringAttribute(span.Attributes, "http.request.method")
+	if method == "" {
+		method = stringAttribute(span.Attributes, "http.method")
+	}
+
+	switch {
+	case stringAttribute(span.Attributes, "messaging.system") != "" ||
+		span.Kind == otlptrace.Span_SPAN_KIND_PRODUCER || span.Kind == otlptrace.Span_SPAN_KIND_CONSUMER:
+		op.Type = OperationTypeMessaging
+		op.MessagingSystem = stringAttribute(span.Attributes, "messaging.system")
+		op.MessagingOperation = stringAttribute(span.Attributes, "messaging.operation")
+		if op.MessagingOperation == "" && span.Kind == otlptrace.Span_SPAN_KIND_PRODUCER {
+			op.MessagingOperation = "publish"
+		}
+	case stringAttribute(span.Attributes, "db.system") != "":
+		op.Type = OperationTypeDB
+		op.DBSystem = stringAttribute(span.Attributes, "db.system")
+	case stringAttribute(span.Attributes, "rpc.system") != "":
+		op.Type = OperationTypeRPC
+	case method != "":
+		op.Type = OperationTypeHTTP
+		op.HTTPMethod = method
+	}
+	return op
+}
+
+// learnSpan records one span's children, latency and attributes.
+func (m *TraceModel) learnSpan(cs *capturedSpan, childKeys []opKey, depth int) {
+	key := opKey{service: cs.service, name: cs.span.Name}
+	op, ok := m.ops[key]
+	if !ok {
+		op = &opModel{
+			kind:      cs.span.Kind,
+			operation: learnOperation(key.name, cs.span),
+			childSets: make(map[int][][]opKey),
+			seenAt:    make(map[int]int),
+			attrIndex: make(map[string]*attrModel),
+		}
+		m.ops[key] = op
+	}
+	op.seen++
+	if op.operation.Type == OperationTypeInternal {
+		// An earlier observation may have lacked the protocol attributes.
+		op.operation = learnOperation(key.name, cs.span)
+	}
+
+	op.seenAt[depth]++
+	op.childSets[depth] = reservoirAdd(op.childSets[depth], op.seenAt[depth], childKeys)
+	if d := spanDuration(cs.span); d > 0 {
+		op.durations = reservoirAdd(op.durations, op.seen, d)
+	}
+
+	for _, kv := range cs.span.Attributes {
+		if kv.Value == nil || kv.Key == "service.name" || kv.Key == "service.namespace" ||
+			strings.HasPrefix(kv.Key, "_template.") {
+			continue
+		}
+		a, ok := op.attrIndex[kv.Key]
+		if !ok {
+			kind, ok := valueKind(kv.Value)
+			if !ok {
+				continue // arrays, maps and bytes aren't modeled
+			}
+			a = &attrModel{key: kv.Key, kind: kind, values: make(map[string]struct{})}
+			op.attrIndex[kv.Key] = a
+			op.attrs = append(op.attrs, a)
+		}
+		a.observe(kv.Value)
+	}
+}
+
+// reservoirAdd keeps a uniform sample of at most maxModelSamples values out
+// of the seen values observed so far.
+func reservoirAdd[T any](samples []T, seen int, v T) []T {
+	if len(samples) < maxModelSamples {
+		return append(samples, v)
+	}
+	if j := common.RandomInt(0, seen-1); j < maxModelSamples {
+		samples[j] = v
+	}
+	return samples
+}
+
+// observe folds one value into the attribute model. Values of a different
+// type than the first one seen are ignored.
+func (a *attrModel) observe(v *commonpb.AnyValue) {
+	kind, ok := valueKind(v)
+	if !ok || kind != a.kind {
+		return
+	}
+
+	var f float64
+	var s string
+	switch val := v.Value.(type) {
+	case *commonpb.AnyValue_StringValue:
+		s = val.StringValue
+	case *commonpb.AnyValue_IntValue:
+		f = float64(val.IntValue)
+		s = strconv.FormatInt(val.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		f = val.DoubleValue
+		s = strconv.FormatFloat(val.DoubleValue, 'g', -1, 64)
+	case *commonpb.AnyValue_BoolValue:
+		s = strconv.FormatBool(val.BoolValue)
+	}
+
+	if a.seen == 0 || f < a.min {
+		a.min = f
+	}
+	if a.seen == 0 || f > a.max {
+		a.max = f
+	}
+	a.seen++
+
+	if _, ok := a.values[s]; !ok && len(a.values) < maxTrackedValues {
+		a.values[s] = struct{}{}
+		if preservedAttributeKeys[a.key] && len(a.preserved) < maxPreservedValues {
+			a.preserved = append(a.preserved, v)
+		}
+	}
+}
+
+// finalize turns learning state into sampling state and frees the value sets.
+func (op *opModel) finalize() {
+	for _, a := range op.attrs {
+		a.presence = float64(a.seen) / float64(op.seen)
+		a.distinct = max(len(a.values), 1)
+		a.values = nil
+	}
+	op.attrIndex = nil
+	op.seenAt = nil
+}
+
+// childSetsAt returns the child lists observed at depth, falling back to the
+// nearest shallower depth the operation was seen at (sampled traces can place
+// an operation deeper than it ever appeared).
+func (op *opModel) childSetsAt(depth int) [][]opKey {
+	for d := depth; d >= 1; d-- {
+		if sets := op.childSets[d]; len(sets) > 0 {
+			return sets
+		}
+	}
+	for d := depth + 1; d <= depth+len(op.childSets); d++ {
+		if sets := op.childSets[d]; len(sets) > 0 {
+			return sets
+		}
+	}
+	return nil
+}
+
+// sample returns a synthetic value with the observed type, range and
+// cardinality. Preserved keys replay an observed value.
+func (a *attrModel) sample() *commonpb.AnyValue {
+	if len(a.preserved) > 0 {
+		return common.RandomChoice(a.preserved)
+	}
+
+	idx := common.RandomInt(0, a.distinct-1)
+	switch a.kind {
+	case attrInt:
+		lo, hi := int64(a.min), int64(a.max)
+		value := lo
+		if a.distinct > 1 {
+			value = lo + int64(idx)*(hi-lo)/int64(a.distinct-1)
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}}
+	case attrDouble:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: common.RandomFloat64(a.min, a.max)}}
+	case attrBool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: common.RandomBool()}}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprintf("%s-%d", attrValueStem(a.key), idx)}}
+	}
+}
+
+// OperationCount returns the number of distinct operations in the model.
+func (m *TraceModel) OperationCount() int {
+	return len(m.ops)
+}
+
+// UseModel switches the generator to sampling traces from a learned model.
+// The topology must contain every service in the model.
+func (g *SpanGenerator) UseModel(m *TraceModel) {
+	g.model = m
+	g.modelServices = make(map[string]*ServiceNode, len(g.topology.Services))
+	for _, service := range g.topology.Services {
+		g.modelServices[service.Name] = service
+	}
+}
+
+// generateModelTrace samples one trace from the learned model.
+func (g *SpanGenerator) generateModelTrace() *TraceTemplate {
+	maxSpans := g.model.MaxSpans
+	if limit := g.config.Model.MaxSpans; limit > 0 && limit < maxSpans {
+		maxSpans = limit
+	}
+	maxDepth := g.model.MaxDepth
+	if limit := g.config.Model.MaxDepth; limit > 0 && limit < maxDepth {
+		maxDepth = limit
+	}
+
+	rootKey := common.RandomChoiceWeighted(g.model.roots, g.model.rootWeights)
+	trace := &TraceTemplate{
+		TraceID:  generateTraceID(),
+		RootSpan: g.newModelSpan(rootKey, nil),
+	}
+
+	budget := maxSpans - 1
+	trace.SpanCount = 1 + g.buildModelSpanTree(trace.RootSpan, &budget, 1, maxDepth)
+	g.expandRemoteCalls(trace)
+
+	g.calculateModelDurations(trace.RootSpan)
+	placeSpans(trace.RootSpan)
+	g.applyErrors(trace.RootSpan)
+	g.applyEventsAndLinks(trace)
+
+	g.applyRootTreatment(trace)
+
+	return trace
+}
+
+// buildModelSpanTree gives parent one child list observed for its operation,
+// then expands each child in turn. budget and maxDepth cap the trace at the
+// largest and deepest observed (or configured) shape.
+func (g *SpanGenerator) buildModelSpanTree(parent *SpanNode, budget *int, depth, maxDepth int) int {
+	if depth >= maxDepth {
+		return 0
+	}
+	op := g.model.ops[opKey{service: parent.Service.Name, name: parent.Operation.Name}]
+	if op == nil {
+		return 0
+	}
+	childSets := op.childSetsAt(depth)
+	if len(childSets) == 0 {
+		return 0
+	}
+
+	for _, key := range common.RandomChoice(childSets) {
+		if *budget <= 0 {
+			break
+		}
+		parent.Children = append(parent.Children, g.newModelSpan(key, parent))
+		*budget--
+	}
+
+	spansCreated := len(parent.Children)
+	for _, child := range parent.Children {
+		spansCreated += g.buildModelSpanTree(child, budget, depth+1, maxDepth)
+	}
+	return spansCreated
+}
+
+// newModelSpan creates a span for a model operation with synthetic attributes.
+func (g *SpanGenerator) newModelSpan(key opKey, parent *SpanNode) *SpanNode {
+	service := g.modelServices[key.service]
+	op := g.model.ops[key]
+
+	attrs := []*commonpb.KeyValue{common.CreateStringAttribute("service.name", service.Name)}
+	if service.Namespace != "" {
+		attrs = append(attrs, common.CreateStringAttribute("service.namespace", service.Namespace))
+	}
+	for _, a := range op.attrs {
+		if a.presence >= 1 || common.RandomFloat64(0, 1) < a.presence {
+			attrs = append(attrs, &commonpb.KeyValue{Key: a.key, Value: a.sample()})
+		}
+	}
+	if g.config.CustomAttributes.FatSpansEnabled() {
+		attrs = g.appendFatAttributes(attrs)
+	}
+	attrs = g.appendDeclaredAttributes(attrs, service, key.name)
+
+	span := &SpanNode{
+		SpanID:     generateSpanID(),
+		Service:    service,
+		Operation:  op.operation,
+		Kind:       op.kind,
+		Attributes: attrs,
+		Children:   make([]*SpanNode, 0),
+	}
+	if parent != nil {
+		span.ParentID = parent.SpanID
+	}
+	return span
+}
+
+// calculateModelDurations draws each span's latency from the samples observed
+// for its operation, stretching parents to cover their children.
+// Operation and service distributions in traces.latency override the learned
+// samples; traces.latency.default does not.
+func (g *SpanGenerator) calculateModelDurations(span *SpanNode) int64 {
+	var sampled int64
+	if op := g.model.ops[opKey{service: span.Service.Name, name: span.Operation.Name}]; len(op.durations) > 0 {
+		sampled = common.RandomChoice(op.durations)
+	} else {
+		sampled = common.RandomDuration(1000000, 100000000) // 1-100ms, as for synthetic leaves
+	}
+	sampled, _ = g.ownLatency(span, sampled, false)
+
+	if len(span.Children) == 0 {
+		span.Duration = sampled
+		return span.Duration
+	}
+
+	for _, child := range span.Children {
+		g.calculateModelDurations(child)
+	}
+	totalChildDuration := g.layoutChildren(span)
+	span.Duration = max(sampled, totalChildDuration+common.RandomDuration(500000, 5000000))
+
+	return span.Duration
+}
+
+// spanDuration returns a captured span's duration, falling back to the
+// generator's own template attribute so templates can be learned from too.
+func spanDuration(span *otlptrace.Span) int64 {
+	if span.EndTimeUnixNano > span.StartTimeUnixNano {
+		return int64(span.EndTimeUnixNano - span.StartTimeUnixNano)
+	}
+	return intAttribute(span.Attributes, "_template.duration_nanos")
+}
+
+// spanStartOffset orders sibling spans by start time (or template offset).
+func spanStartOffset(span *otlptrace.Span) int64 {
+	if span.StartTimeUnixNano > 0 {
+		return int64(span.StartTimeUnixNano)
+	}
+	return intAttribute(span.Attributes, "_template.start_offset_nanos")
+}
+
+func stringAttribute(attrs []*commonpb.KeyValue, key string) string {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.GetValue().GetStringValue()
+		}
+	}
+	return ""
+}
+
+func intAttribute(attrs []*commonpb.KeyValue, key string) int64 {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.GetValue().GetIntValue()
+		}
+	}
+	return 0
+}
+
+func valueKind(v *commonpb.AnyValue) (attrValueKind, bool) {
+	switch v.Value.(type) {
+	case *commonpb.AnyValue_StringValue:
+		return attrString, true
+	case *commonpb.AnyValue_IntValue:
+		return attrInt, true
+	case *commonpb.AnyValue_DoubleValue:
+		return attrDouble, true
+	case *commonpb.AnyValue_BoolValue:
+		return attrBool, true
+	}
+	return 0, false
+}
+
+// attrValueStem names synthetic string values after the last segment of
+// their key, e.g. user.id → "id-17".
+func attrValueStem(key string) string {
+	if i := strings.LastIndex(key, "."); i >= 0 && i < len(key)-1 {
+		return key[i+1:]
+	}
+	return key
+}
@@ -0,0 +1,241 @@
+package traces
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestModelSamplesObservedShape learns a model from generated traces and
+// verifies sampled traces only use observed roots and parent→child calls,
+// stay within the observed size, and carry synthetic attribute values.
+func TestModelSamplesObservedShape(t *testing.T) {
+	cfg := &config.TracesConfig{
+		Count:    1,
+		Spans:    config.SpansConfig{AvgPerTrace: 20, StdDev: 0},
+		Services: config.ServicesConfig{Count: 3, Names: []string{"api", "orders", "db"}},
+	}
+	src := newSpanGenerator(t, cfg, graphTopology())
+	var templates []*TraceTemplate
+	for i := 0; i < 50; i++ {
+		templates = append(templates, src.GenerateTrace())
+	}
+	req := NewTraceWriter(t.TempDir(), "source").tracesToOTLP(templates)
+
+	observed := make(map[string]bool)
+	sourceValues := make(map[string]bool)
+	for _, rs := range req.ResourceSpans {
+		spans := rs.ScopeSpans[0].Spans
+		names := make(map[string]string)
+		for _, s := range spans {
+			names[string(s.SpanId)] = s.Name
+			for _, kv := range s.Attributes {
+				if kv.Key == "db.statement" {
+					sourceValues[kv.Value.GetStringValue()] = true
+				}
+			}
+		}
+		for _, s := range spans {
+			observed[names[string(s.ParentSpanId)]+" -> "+s.Name] = true
+		}
+	}
+
+	model, err := LearnModel(req.ResourceSpans)
+	if err != nil {
+		t.Fatalf("LearnModel: %v", err)
+	}
+	if model.TraceCount != 50 || model.MaxSpans != 5 || model.MaxDepth != 3 {
+		t.Fatalf("model traces=%d maxSpans=%d maxDepth=%d, want 50/5/3", model.TraceCount, model.MaxSpans, model.MaxDepth)
+	}
+
+	g := newSpanGenerator(t, cfg, BuildTopology(model.Services, false, "", model.Namespaces))
+	g.UseModel(model)
+
+	for i := 0; i < 50; i++ {
+		tr := g.GenerateTrace()
+		spans := tr.CollectSpans()
+		if len(spans) != tr.SpanCount || tr.SpanCount > model.MaxSpans {
+			t.Fatalf("span count = %d (collected %d), max %d", tr.SpanCount, len(spans), model.MaxSpans)
+		}
+		if !observed[" -> "+tr.RootSpan.Operation.Name] {
+			t.Fatalf("root %q was never a root in the source", tr.RootSpan.Operation.Name)
+		}
+		for _, s := range spans {
+			for _, c := range s.Children {
+				if !observed[s.Operation.Name+" -> "+c.Operation.Name] {
+					t.Fatalf("call %s -> %s was never observed", s.Operation.Name, c.Operation.Name)
+				}
+				if c.StartTime < s.StartTime || c.StartTime+c.Duration > s.StartTime+s.Duration {
+					t.Fatalf("child %s [%d,+%d] outside parent %s [%d,+%d]",
+						c.Operation.Name, c.StartTime, c.Duration, s.Operation.Name, s.StartTime, s.Duration)
+				}
+			}
+			for _, kv := range s.Attributes {
+				if kv.Key == "db.statement" && sourceValues[kv.Value.GetStringValue()] {
+					t.Fatalf("db.statement value %q copied from the source", kv.Value.GetStringValue())
+				}
+			}
+			if s.Service.Name == "db" && s.ToOTLPSpan().Kind != otlptrace.Span_SPAN_KIND_CLIENT {
+				t.Fatalf("db span kind = %v, want the observed CLIENT", s.ToOTLPSpan().Kind)
+			}
+		}
+	}
+}
+
+// TestModelAttributeCardinality verifies synthetic values keep the observed
+// cardinality and preserved keys replay observed values.
+func TestModelAttributeCardinality(t *testing.T) {
+	var spans []*otlptrace.Span
+	for i := 0; i < 200; i++ {
+		spans = append(spans, &otlptrace.Span{
+			TraceId: []byte{byte(i), 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+			SpanId:  []byte{byte(i), 1, 2, 3, 4, 5, 6, 7},
+			Name:    "GET /users",
+			Attributes: []*commonpb.KeyValue{
+				common.CreateStringAttribute("user.id", []string{"alice", "bob", "carol", "dave"}[i%4]),
+				common.CreateStringAttribute("http.method", []string{"GET", "POST"}[i%2]),
+			},
+		})
+	}
+	model, err := LearnModel([]*otlptrace.ResourceSpans{{ScopeSpans: []*otlptrace.ScopeSpans{{Spans: spans}}}})
+	if err != nil {
+		t.Fatalf("LearnModel: %v", err)
+	}
+	if len(model.Services) != 1 || model.Services[0] != "unknown_service" {
+		t.Fatalf("services = %v, want [unknown_service]", model.Services)
+	}
+
+	g := newSpanGenerator(t, &config.TracesConfig{}, BuildTopology(model.Services, false, "", nil))
+	g.UseModel(model)
+
+	users := make(map[string]bool)
+	methods := make(map[string]bool)
+	for i := 0; i < 500; i++ {
+		for _, kv := range g.GenerateTrace().RootSpan.Attributes {
+			switch kv.Key {
+			case "user.id":
+				users[kv.Value.GetStringValue()] = true
+			case "http.method":
+				methods[kv.Value.GetStringValue()] = true
+			}
+		}
+	}
+	if len(users) != 4 {
+		t.Fatalf("user.id cardinality = %d, want 4", len(users))
+	}
+	for u := range users {
+		if !strings.HasPrefix(u, "id-") {
+			t.Fatalf("user.id value %q is not synthetic", u)
+		}
+	}
+	if len(methods) != 2 || !methods["GET"] || !methods["POST"] {
+		t.Fatalf("http.method values = %v, want GET and POST", methods)
+	}
+}
+
+// TestModelSurvivesSpanCycles verifies a capture with a duplicated span ID,
+// one copy parented on itself, is learned instead of recursing forever.
+func TestModelSurvivesSpanCycles(t *testing.T) {
+	traceID := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	spanID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	spans := []*otlptrace.Span{
+		{TraceId: traceID, SpanId: spanID, Name: "GET /"},
+		{TraceId: traceID, SpanId: spanID, ParentSpanId: spanID, Name: "GET /"},
+	}
+	model, err := LearnModel([]*otlptrace.ResourceSpans{{ScopeSpans: []*otlptrace.ScopeSpans{{Spans: spans}}}})
+	if err != nil {
+		t.Fatalf("LearnModel: %v", err)
+	}
+	if model.TraceCount != 1 || model.MaxDepth != 1 {
+		t.Errorf("learned %d traces of depth %d, want 1 of depth 1", model.TraceCount, model.MaxDepth)
+	}
+}
+
+// TestModelOperationTypes verifies learned operations take their protocol
+// from the source spans' attributes, so injected errors get that protocol's
+// exceptions and status codes.
+func TestModelOperationTypes(t *testing.T) {
+	cfg := &config.TracesConfig{
+		Spans:    config.SpansConfig{AvgPerTrace: 20, StdDev: 0},
+		Services: config.ServicesConfig{Count: 3, Names: []string{"api", "orders", "db"}},
+	}
+	src := newSpanGenerator(t, cfg, graphTopology())
+	var templates []*TraceTemplate
+	for i := 0; i < 20; i++ {
+		templates = append(templates, src.GenerateTrace())
+	}
+	model, err := LearnModel(NewTraceWriter(t.TempDir(), "source").tracesToOTLP(templates).ResourceSpans)
+	if err != nil {
+		t.Fatalf("LearnModel: %v", err)
+	}
+
+	cfg.Errors = config.ErrorsConfig{Services: map[string]float64{"api": 100, "orders": 100, "db": 100}}
+	g := newSpanGenerator(t, cfg, BuildTopology(model.Services, false, "", model.Namespaces))
+	g.UseModel(model)
+
+	want := map[string]OperationType{"api": OperationTypeHTTP, "orders": OperationTypeRPC, "db": OperationTypeDB}
+	for i := 0; i < 20; i++ {
+		for _, s := range g.GenerateTrace().CollectSpans() {
+			if typ := s.Operation.Type; typ != want[s.Service.Name] {
+				t.Fatalf("%s span %q has operation type %v, want %v", s.Service.Name, s.Operation.Name, typ, want[s.Service.Name])
+			}
+			if !s.Error {
+				t.Fatalf("span %q did not fail at 100%%", s.Operation.Name)
+			}
+			known := false
+			for _, tmpl := range exceptionCatalog[s.Operation.Type] {
+				known = known || tmpl.typ == s.errType
+			}
+			if !known && !strings.HasPrefix(s.errMessage, "HTTP ") {
+				t.Fatalf("%s span failed with %q, not one of its protocol's exceptions", s.Service.Name, s.errType)
+			}
+			switch s.Operation.Type {
+			case OperationTypeHTTP:
+				if code := intAttribute(s.Attributes, "http.status_code"); code < 500 {
+					t.Fatalf("failed HTTP span has http.status_code %d", code)
+				}
+			case OperationTypeDB:
+				if got := stringAttribute(s.Attributes, "db.system"); got != s.Operation.DBSystem || got == "" {
+					t.Fatalf("db span has db.system %q, operation %q", got, s.Operation.DBSystem)
+				}
+			}
+		}
+	}
+}
+
+// TestLoadModelFromFile verifies a generator .pb output can be learned from.
+func TestLoadModelFromFile(t *testing.T) {
+	cfg := &config.TracesConfig{
+		Spans:    config.SpansConfig{AvgPerTrace: 6, StdDev: 0},
+		Services: config.ServicesConfig{Count: 3, Names: []string{"api", "worker", "db"}},
+	}
+	g := newSpanGenerator(t, cfg, testTopology())
+	req := NewTraceWriter("", "").tracesToOTLP([]*TraceTemplate{g.GenerateTrace(), g.GenerateTrace()})
+	data, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "capture.pb")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	model, err := LoadModel(path)
+	if err != nil {
+		t.Fatalf("LoadModel: %v", err)
+	}
+	if model.TraceCount != 2 || model.SpanCount != 12 {
+		t.Fatalf("model traces=%d spans=%d, want 2/12", model.TraceCount, model.SpanCount)
+	}
+
+	if _, err := LoadModel(filepath.Join(t.TempDir(), "missing.pb")); err == nil {
+		t.Fatal("expected an error for a missing source")
+	}
+}
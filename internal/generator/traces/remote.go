@@ -0,0 +1,112 @@
+package traces
+
+import (
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// Ports reported in peer attributes for each protocol.
+const (
+	httpPort = 8080
+	grpcPort = 50051
+)
+
+// grpcStatusCodes maps exception types to the gRPC status a failed RPC span
+// reports; anything else is UNKNOWN (2).
+var grpcStatusCodes = map[string]int64{
+	"DeadlineExceededError": 4,
+	"TimeoutError":          4,
+	"UnavailableError":      14,
+	"ValidationError":       3,
+}
+
+// expandRemoteCalls gives every cross-service call in the trace a CLIENT span
+// in the caller and a SERVER span in the callee when traces.remote_calls is
+// enabled. A callee span that isn't an HTTP or gRPC operation (a query or
+// internal step) gets a SERVER span for one of the callee's HTTP operations
+// above it. Messaging hops are left alone.
+func (g *SpanGenerator) expandRemoteCalls(trace *TraceTemplate) {
+	if !g.config.RemoteCalls.Enabled {
+		return
+	}
+	g.expandRemoteCallsBelow(trace.RootSpan)
+	trace.SpanCount = len(trace.CollectSpans())
+}
+
+func (g *SpanGenerator) expandRemoteCallsBelow(parent *SpanNode) {
+	for i, child := range parent.Children {
+		g.expandRemoteCallsBelow(child)
+
+		if child.Service == parent.Service || child.Kind != otlptrace.Span_SPAN_KIND_UNSPECIFIED ||
+			child.Operation.Type == OperationTypeMessaging {
+			continue
+		}
+
+		server := child
+		if t := child.Operation.Type; t != OperationTypeHTTP && t != OperationTypeRPC {
+			ops, weights := child.Service.operationsFor(OperationTypeHTTP)
+			op := common.RandomChoiceWeighted(ops, weights)
+			server = &SpanNode{
+				SpanID:     generateSpanID(),
+				Service:    child.Service,
+				Operation:  op,
+				Attributes: g.generateAttributes(child.Service, op),
+				Children:   []*SpanNode{child},
+			}
+			child.ParentID = server.SpanID
+		}
+		server.Kind = otlptrace.Span_SPAN_KIND_SERVER
+
+		client := &SpanNode{
+			SpanID:     generateSpanID(),
+			ParentID:   parent.SpanID,
+			Service:    parent.Service,
+			Operation:  server.Operation,
+			Kind:       otlptrace.Span_SPAN_KIND_CLIENT,
+			Attributes: g.generateAttributes(parent.Service, server.Operation),
+			Children:   []*SpanNode{server},
+			peer:       server,
+		}
+		server.ParentID = client.SpanID
+		parent.Children[i] = client
+
+		addPeerAttributes(client, server)
+	}
+}
+
+// addPeerAttributes sets the attributes that let backends draw the edge
+// between client and server: the callee's address on both sides, the peer
+// service on the client, and matching response codes.
+func addPeerAttributes(client, server *SpanNode) {
+	port := int64(httpPort)
+	if server.Operation.Type == OperationTypeRPC {
+		port = grpcPort
+		for _, s := range []*SpanNode{client, server} {
+			s.Attributes = append(s.Attributes, common.CreateIntAttribute("rpc.grpc.status_code", 0))
+		}
+	} else {
+		setIntAttribute(client, "http.status_code", httpStatusCode(server))
+	}
+
+	address := server.Service.Name
+	client.Attributes = append(client.Attributes,
+		common.CreateStringAttribute("server.address", address),
+		common.CreateIntAttribute("server.port", port),
+		common.CreateStringAttribute("net.peer.name", address),
+		common.CreateIntAttribute("net.peer.port", port),
+		common.CreateStringAttribute("peer.service", server.Service.Name),
+	)
+	server.Attributes = append(server.Attributes,
+		common.CreateStringAttribute("server.address", address),
+		common.CreateIntAttribute("server.port", port),
+		common.CreateStringAttribute("client.address", client.Service.Name),
+	)
+}
+
+// grpcStatusCode returns the gRPC status for a failure of the given type.
+func grpcStatusCode(errType string) int64 {
+	if code, ok := grpcStatusCodes[errType]; ok {
+		return code
+	}
+	return 2
+}
@@ -0,0 +1,93 @@
+package traces
+
+import (
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// TestRemoteCallPairs verifies every cross-service call is a CLIENT span in
+// the caller over a SERVER span in the callee, with peer attributes and
+// matching response codes.
+func TestRemoteCallPairs(t *testing.T) {
+	cfg := &config.TracesConfig{
+		Spans:       config.SpansConfig{AvgPerTrace: 12, StdDev: 0},
+		Services:    config.ServicesConfig{Count: 3, Names: []string{"api", "worker", "db"}},
+		RemoteCalls: config.RemoteCallsConfig{Enabled: true},
+		Errors:      config.ErrorsConfig{Percentage: 10},
+	}
+	g := newSpanGenerator(t, cfg, testTopology())
+
+	pairs := 0
+	for i := 0; i < 30; i++ {
+		pairs += checkRemoteCallPairs(t, g.GenerateTrace())
+	}
+	if pairs == 0 {
+		t.Fatal("no remote calls generated")
+	}
+}
+
+// checkRemoteCallPairs fails the test unless every cross-service call in
+// trace is a matching CLIENT/SERVER pair, and returns the number of pairs.
+func checkRemoteCallPairs(t *testing.T, trace *TraceTemplate) int {
+	t.Helper()
+	pairs := 0
+	if got := len(trace.CollectSpans()); got != trace.SpanCount {
+		t.Fatalf("SpanCount = %d, trace has %d spans", trace.SpanCount, got)
+	}
+	forEach(trace.RootSpan, func(parent *SpanNode) {
+		for _, child := range parent.Children {
+			if child.Service == parent.Service {
+				continue
+			}
+			if parent.ToOTLPSpan().Kind != otlptrace.Span_SPAN_KIND_CLIENT || child.ToOTLPSpan().Kind != otlptrace.Span_SPAN_KIND_SERVER {
+				t.Fatalf("call %s -> %s is %v -> %v, want CLIENT -> SERVER",
+					parent.Service.Name, child.Service.Name, parent.ToOTLPSpan().Kind, child.ToOTLPSpan().Kind)
+			}
+			if typ := child.Operation.Type; typ != OperationTypeHTTP && typ != OperationTypeRPC {
+				t.Fatalf("server span has operation type %v", typ)
+			}
+			pairs++
+
+			client, server := parent, child
+			if got := stringAttribute(client.Attributes, "server.address"); got != server.Service.Name {
+				t.Fatalf("client server.address = %q, want %q", got, server.Service.Name)
+			}
+			if got := stringAttribute(client.Attributes, "peer.service"); got != server.Service.Name {
+				t.Fatalf("client peer.service = %q", got)
+			}
+			if client.Operation.Name != server.Operation.Name {
+				t.Fatalf("client %q and server %q names differ", client.Operation.Name, server.Operation.Name)
+			}
+			for _, key := range []string{"http.status_code", "rpc.grpc.status_code"} {
+				if c, s := intAttribute(client.Attributes, key), intAttribute(server.Attributes, key); c != s {
+					t.Fatalf("%s: client %d, server %d", key, c, s)
+				}
+			}
+			if server.Error && !client.Error {
+				t.Fatalf("server failed but its client did not")
+			}
+		}
+	})
+	return pairs
+}
+
+// TestRemoteCallPairsHighSpan verifies high-span traces pair their
+// cross-service calls too.
+func TestRemoteCallPairsHighSpan(t *testing.T) {
+	cfg := &config.TracesConfig{
+		Spans:       config.SpansConfig{AvgPerTrace: 12, StdDev: 0},
+		Services:    config.ServicesConfig{Count: 3, Names: []string{"api", "worker", "db"}},
+		RemoteCalls: config.RemoteCallsConfig{Enabled: true},
+	}
+	g := newSpanGenerator(t, cfg, testTopology())
+
+	pairs := 0
+	for i := 0; i < 10; i++ {
+		pairs += checkRemoteCallPairs(t, g.GenerateHighSpanTrace(100))
+	}
+	if pairs == 0 {
+		t.Fatal("no remote calls generated")
+	}
+}
@@ -0,0 +1,84 @@
+package traces
+
+import (
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/inventory"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// serviceResources builds one stable resource per service for
+// traces.resources.per_service: every trace's spans from a service share the
+// same version and instance, and the pod, node and cluster of the service's
+// first replica in the entity inventory, as spans from one deployed process
+// would.
+type serviceResources struct {
+	environment string
+	inventory   *inventory.Inventory
+	byService   map[*ServiceNode]*resourcepb.Resource
+}
+
+func newServiceResources(cfg config.TraceResourcesConfig) *serviceResources {
+	env := cfg.Environment
+	if env == "" {
+		env = "production"
+	}
+	return &serviceResources{environment: env, byService: make(map[*ServiceNode]*resourcepb.Resource)}
+}
+
+// get returns the service's resource, building it on first use so services
+// are described in the order they first appear.
+func (r *serviceResources) get(service *ServiceNode) *resourcepb.Resource {
+	if res, ok := r.byService[service]; ok {
+		return res
+	}
+	if r.inventory == nil {
+		r.inventory = inventory.Default()
+	}
+	container := r.inventory.Service(service.Name, service.Namespace).Pods[0].Containers[0]
+
+	attrs := common.CreateServiceAttributes(service.Name)
+	if service.Namespace != "" {
+		attrs = append(attrs, common.CreateStringAttribute("service.namespace", service.Namespace))
+	}
+	attrs = append(attrs,
+		common.CreateStringAttribute("service.instance.id", container.Pod.UID),
+		common.CreateStringAttribute("deployment.environment", r.environment),
+	)
+	for _, kv := range inventory.KeyValues(container) {
+		if kv.Key != "service.name" {
+			attrs = append(attrs, kv)
+		}
+	}
+	attrs = append(attrs,
+		common.CreateStringAttribute("telemetry.sdk.name", "telemetry-generator"),
+		common.CreateStringAttribute("telemetry.sdk.version", "1.0.0"),
+	)
+
+	res := &resourcepb.Resource{Attributes: attrs}
+	r.byService[service] = res
+	return res
+}
+
+// splitByService groups a trace's spans by service, in order of each
+// service's first span.
+func splitByService(spans []*SpanNode) ([]*ServiceNode, map[*ServiceNode][]*SpanNode) {
+	var order []*ServiceNode
+	groups := make(map[*ServiceNode][]*SpanNode)
+	for _, span := range spans {
+		if _, ok := groups[span.Service]; !ok {
+			order = append(order, span.Service)
+		}
+		groups[span.Service] = append(groups[span.Service], span)
+	}
+	return order, groups
+}
+
+// scope is the instrumentation scope every generated span is written under.
+func scope() *commonpb.InstrumentationScope {
+	return &commonpb.InstrumentationScope{
+		Name:    "telemetry-generator",
+		Version: "1.0.0",
+	}
+}
@@ -1,7 +1,6 @@
 package traces
 
 import (
-	"crypto/rand"
 	"encoding/hex"
 	"fmt"
 
@@ -22,6 +21,34 @@ type SpanNode struct {
 	Attributes []*commonpb.KeyValue
 	Children   []*SpanNode
 
+	// Kind, when set, overrides the span kind otherwise derived from the
+	// operation type. Spans sampled from a learned model carry the kind
+	// observed in the source.
+	Kind otlptrace.Span_SpanKind
+
+	// Error marks a failed span (STATUS_CODE_ERROR); see applyErrors. Events
+	// are span events whose TimeUnixNano is an offset from the span's start
+	// until the sender rebases them.
+	Error      bool
+	Events     []*otlptrace.Span_Event
+	Links      []*otlptrace.Span_Link
+	errType    string
+	errMessage string
+
+	// offset is the start relative to the parent, set by layoutChildren once
+	// durations are known; async marks a fire-and-forget child the parent
+	// doesn't wait for.
+	offset int64
+	async  bool
+
+	// queueLag, set on a messaging consumer, is how long its message waited
+	// in the broker: the consumer starts that long after its producer.
+	queueLag int64
+
+	// peer, set on the CLIENT side of a remote call, is the callee's SERVER
+	// span; the client reports the server's failures.
+	peer *SpanNode
+
 	// EmitDelayMs, when > 0, tells the sender to export this span that many
 	// milliseconds after the rest of its trace (via _template.emit_delay_ms).
 	// Used to simulate a root span that arrives after the receiver's trace
@@ -34,6 +61,10 @@ type TraceTemplate struct {
 	TraceID   []byte
 	RootSpan  *SpanNode
 	SpanCount int
+
+	// FollowOn holds traces started by this trace's messages: consumers that
+	// begin a new trace linked to the producer span.
+	FollowOn []*TraceTemplate
 }
 
 // SpanGenerator generates spans for traces
@@ -41,19 +72,44 @@ type SpanGenerator struct {
 	config   *config.TracesConfig
 	topology *ServiceTopology
 	customAttrs []common.AttributeSchema
+	declared    []declaredAttribute
+	latency     *latencyModel
+	errors      *errorModel
+	queueLag    latencySampler
+
+	// linkTargets are spans of earlier traces that cross-trace and batch
+	// consumer links can point at.
+	linkTargets []linkTarget
+
+	// model, when set via UseModel, replaces the topology as the source of
+	// trace shape for GenerateTrace.
+	model         *TraceModel
+	modelServices map[string]*ServiceNode
 }
 
 // NewSpanGenerator creates a new span generator
-func NewSpanGenerator(cfg *config.TracesConfig, topology *ServiceTopology) *SpanGenerator {
+func NewSpanGenerator(cfg *config.TracesConfig, topology *ServiceTopology) (*SpanGenerator, error) {
+	declared, err := newDeclaredAttributes(cfg.CustomAttributes.Declared)
+	if err != nil {
+		return nil, err
+	}
 	return &SpanGenerator{
 		config:      cfg,
 		topology:    topology,
 		customAttrs: common.GenerateCustomAttributeSchemas(cfg.CustomAttributes.Count),
-	}
+		declared:    declared,
+		latency:     newLatencyModel(cfg.Latency),
+		errors:      newErrorModel(cfg.Errors),
+		queueLag:    newQueueLagSampler(cfg.Messaging.QueueLag),
+	}, nil
 }
 
 // GenerateTrace generates a complete trace
 func (g *SpanGenerator) GenerateTrace() *TraceTemplate {
+	if g.model != nil {
+		return g.generateModelTrace()
+	}
+
 	// Determine span count using normal distribution
 	spanCount := common.NormalInt(g.config.Spans.AvgPerTrace, g.config.Spans.StdDev)
 
@@ -82,17 +138,38 @@ func (g *SpanGenerator) GenerateTrace() *TraceTemplate {
 		Children:   make([]*SpanNode, 0),
 	}
 
-	// Build the rest of the tree
-	remainingSpans := spanCount - 1
-	g.buildSpanTree(trace.RootSpan, remainingSpans, 0)
+	// Build the rest of the tree. A declared service graph shapes the trace
+	// itself; avg_per_trace + 3*std_dev only caps its size.
+	if g.topology.HasGraph() {
+		budget := g.config.Spans.AvgPerTrace + 3*g.config.Spans.StdDev - 1
+		trace.SpanCount = 1 + g.buildGraphSpanTree(trace.RootSpan, &budget)
+	} else {
+		remainingSpans := spanCount - 1
+		g.buildSpanTree(trace.RootSpan, remainingSpans, 0)
+	}
+
+	// Split messaging hops into producer/consumer pairs; consumers may
+	// continue in traces of their own.
+	g.expandMessaging(trace)
+
+	for _, tr := range append([]*TraceTemplate{trace}, trace.FollowOn...) {
+		g.expandRemoteCalls(tr)
+		g.finishTrace(tr)
+	}
 
-	// Calculate durations bottom-up
+	return trace
+}
+
+// finishTrace times a built trace and applies the per-trace treatments.
+func (g *SpanGenerator) finishTrace(trace *TraceTemplate) {
+	// Calculate durations bottom-up, then place spans in time
 	g.calculateDurations(trace.RootSpan)
+	placeSpans(trace.RootSpan)
+	g.applyErrors(trace.RootSpan)
+	g.applyEventsAndLinks(trace)
 
 	// Optionally make the root missing/late.
 	g.applyRootTreatment(trace)
-
-	return trace
 }
 
 // GenerateHighSpanTrace generates a trace with a very high span count
@@ -122,8 +199,12 @@ func (g *SpanGenerator) GenerateHighSpanTrace(spanCount int) *TraceTemplate {
 	// For high span count, use a wider tree structure
 	remainingSpans := spanCount - 1
 	g.buildWideSpanTree(trace.RootSpan, remainingSpans, 0)
+	g.expandRemoteCalls(trace)
 
 	g.calculateDurations(trace.RootSpan)
+	placeSpans(trace.RootSpan)
+	g.applyErrors(trace.RootSpan)
+	g.applyEventsAndLinks(trace)
 
 	// Optionally make the root missing/late — applies to gigatraces too, so
 	// a high-span trace can accumulate in the receiver's cache before (or
@@ -146,7 +227,6 @@ func (g *SpanGenerator) buildSpanTree(parent *SpanNode, remainingSpans int, dept
 	}
 
 	spansCreated := 0
-	currentOffset := parent.StartTime
 
 	for i := 0; i < childCount && spansCreated < remainingSpans; i++ {
 		// Determine service for child span
@@ -168,8 +248,6 @@ func (g *SpanGenerator) buildSpanTree(parent *SpanNode, remainingSpans int, dept
 			ParentID:   parent.SpanID,
 			Service:    childService,
 			Operation:  childOp,
-			Duration:   0,
-			StartTime:  currentOffset,
 			Attributes: g.generateAttributes(childService, childOp),
 			Children:   make([]*SpanNode, 0),
 		}
@@ -185,9 +263,45 @@ func (g *SpanGenerator) buildSpanTree(parent *SpanNode, remainingSpans int, dept
 
 		childSpans := g.buildSpanTree(child, childBudget, depth+1)
 		spansCreated += childSpans
+	}
+
+	return spansCreated
+}
 
-		// Offset for next sibling (sequential execution)
-		currentOffset += child.Duration
+// buildGraphSpanTree expands a span along its service's declared graph edges.
+// Each edge fires with its call probability and fans out into FanOutMin..Max
+// calls, recursing into the callee. budget caps the total number of spans so
+// a dense graph can't blow up a single trace; the graph is acyclic, so depth
+// is bounded by the number of services.
+func (g *SpanGenerator) buildGraphSpanTree(parent *SpanNode, budget *int) int {
+	spansCreated := 0
+
+	for _, edge := range parent.Service.Edges {
+		if *budget <= 0 {
+			break
+		}
+		if edge.Probability < 1 && common.RandomFloat64(0, 1) >= edge.Probability {
+			continue
+		}
+
+		calls := common.RandomInt(edge.FanOutMin, edge.FanOutMax)
+		for i := 0; i < calls && *budget > 0; i++ {
+			childOp := edge.Operation()
+			child := &SpanNode{
+				SpanID:     generateSpanID(),
+				ParentID:   parent.SpanID,
+				Service:    edge.Target,
+				Operation:  childOp,
+				Attributes: g.generateAttributes(edge.Target, childOp),
+				Children:   make([]*SpanNode, 0),
+			}
+
+			parent.Children = append(parent.Children, child)
+			*budget--
+			spansCreated++
+
+			spansCreated += g.buildGraphSpanTree(child, budget)
+		}
 	}
 
 	return spansCreated
@@ -242,24 +356,31 @@ func (g *SpanGenerator) buildWideSpanTree(parent *SpanNode, remainingSpans int,
 	return spansCreated
 }
 
-// calculateDurations calculates durations for all spans bottom-up
+// calculateDurations calculates durations for all spans bottom-up. A span's
+// own latency comes from traces.latency when configured; a parent always
+// lasts at least as long as its children's critical path (see layoutChildren)
+// plus some overhead.
 func (g *SpanGenerator) calculateDurations(span *SpanNode) int64 {
 	if len(span.Children) == 0 {
 		// Leaf span - generate random duration
-		span.Duration = common.RandomDuration(1000000, 100000000) // 1-100ms in nanoseconds
+		span.Duration, _ = g.ownLatency(span, common.RandomDuration(1000000, 100000000), true) // 1-100ms in nanoseconds
 		return span.Duration
 	}
 
-	// Calculate children durations first
-	totalChildDuration := int64(0)
+	// Calculate children durations first, then lay them out
 	for _, child := range span.Children {
-		childDuration := g.calculateDurations(child)
-		totalChildDuration += childDuration
+		g.calculateDurations(child)
 	}
+	totalChildDuration := g.layoutChildren(span)
 
 	// Parent duration is children duration plus some overhead
 	overhead := common.RandomDuration(500000, 5000000) // 0.5-5ms overhead
-	span.Duration = totalChildDuration + overhead
+	if own, sampled := g.ownLatency(span, overhead, true); sampled {
+		span.Duration = max(own, totalChildDuration+overhead)
+	} else {
+		// No distribution for this span: its own time is the overhead.
+		span.Duration = totalChildDuration + own
+	}
 
 	return span.Duration
 }
@@ -285,6 +406,16 @@ func (g *SpanGenerator) generateAttributes(service *ServiceNode, op Operation) [
 		dbAttrs := common.CreateDBAttributes(op.DBSystem, op.DBStatement)
 		attrs = append(attrs, dbAttrs...)
 
+	case OperationTypeRPC:
+		attrs = append(attrs, common.CreateRPCAttributes("grpc", op.RPCService, op.RPCMethod)...)
+
+	case OperationTypeMessaging:
+		operation := op.MessagingOperation
+		if operation == "" {
+			operation = "process"
+		}
+		attrs = append(attrs, common.CreateMessagingAttributes(op.MessagingSystem, op.Destination, operation)...)
+
 	case OperationTypeInternal:
 		attrs = append(attrs, common.CreateStringAttribute("span.kind", "internal"))
 	}
@@ -294,11 +425,7 @@ func (g *SpanGenerator) generateAttributes(service *ServiceNode, op Operation) [
 	// When disabled (the default), fall back to the legacy random path.
 	ca := g.config.CustomAttributes
 	if ca.FatSpansEnabled() {
-		numFat := common.RandomInt(ca.PerSpanMin, ca.PerSpanMax)
-		for i := 0; i < numFat; i++ {
-			key := fmt.Sprintf("%s.%d", ca.KeyPrefix, i)
-			attrs = append(attrs, common.CreateStringAttribute(key, common.RandomString(ca.ValueBytes)))
-		}
+		attrs = g.appendFatAttributes(attrs)
 	} else if common.RandomInt(1, 100) <= 30 && len(g.customAttrs) > 0 {
 		// Legacy behavior: randomly add 1-3 custom attributes to ~30% of spans.
 		numCustom := common.RandomInt(1, 3)
@@ -308,6 +435,18 @@ func (g *SpanGenerator) generateAttributes(service *ServiceNode, op Operation) [
 		}
 	}
 
+	return g.appendDeclaredAttributes(attrs, service, op.Name)
+}
+
+// appendFatAttributes appends the fat-span attributes (per_span_min..max large
+// string values) to attrs.
+func (g *SpanGenerator) appendFatAttributes(attrs []*commonpb.KeyValue) []*commonpb.KeyValue {
+	ca := g.config.CustomAttributes
+	numFat := common.RandomInt(ca.PerSpanMin, ca.PerSpanMax)
+	for i := 0; i < numFat; i++ {
+		key := fmt.Sprintf("%s.%d", ca.KeyPrefix, i)
+		attrs = append(attrs, common.CreateStringAttribute(key, common.RandomString(ca.ValueBytes)))
+	}
 	return attrs
 }
 
@@ -353,18 +492,16 @@ func phantomParentID(used map[string]struct{}) []byte {
 	}
 }
 
-// generateTraceID generates a random trace ID (16 bytes)
+// generateTraceID generates a random trace ID (16 bytes). IDs come from the
+// common package source so a seeded run reproduces them; the sender
+// regenerates every ID at send time anyway.
 func generateTraceID() []byte {
-	id := make([]byte, 16)
-	rand.Read(id)
-	return id
+	return common.RandomBytes(16)
 }
 
 // generateSpanID generates a random span ID (8 bytes)
 func generateSpanID() []byte {
-	id := make([]byte, 8)
-	rand.Read(id)
-	return id
+	return common.RandomBytes(8)
 }
 
 // ToOTLPSpan converts a SpanNode to an OTLP Span
@@ -378,14 +515,22 @@ func (s *SpanNode) ToOTLPSpan() *otlptrace.Span {
 		StartTimeUnixNano: 0, // No timestamp in template
 		EndTimeUnixNano:   0, // No timestamp in template
 		Attributes:        s.Attributes,
+		Events:            s.Events,
+		Links:             s.Links,
 		Status: &otlptrace.Status{
 			Code: otlptrace.Status_STATUS_CODE_OK,
 		},
 	}
+	if s.Error {
+		span.Status = &otlptrace.Status{
+			Code:    otlptrace.Status_STATUS_CODE_ERROR,
+			Message: s.errMessage,
+		}
+	}
 
 	// Set span kind based on operation type
 	switch s.Operation.Type {
-	case OperationTypeHTTP:
+	case OperationTypeHTTP, OperationTypeRPC:
 		if s.ParentID == nil {
 			span.Kind = otlptrace.Span_SPAN_KIND_SERVER
 		} else {
@@ -393,6 +538,13 @@ func (s *SpanNode) ToOTLPSpan() *otlptrace.Span {
 		}
 	case OperationTypeDB:
 		span.Kind = otlptrace.Span_SPAN_KIND_CLIENT
+	case OperationTypeMessaging:
+		// Unless marked as the producer, the span is owned by the receiving
+		// service, so it is the consumer side of the hop.
+		span.Kind = otlptrace.Span_SPAN_KIND_CONSUMER
+	}
+	if s.Kind != otlptrace.Span_SPAN_KIND_UNSPECIFIED {
+		span.Kind = s.Kind
 	}
 
 	return span
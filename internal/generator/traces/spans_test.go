@@ -11,6 +11,31 @@ func testTopology() *ServiceTopology {
 	return BuildTopology([]string{"api", "worker", "db"}, false, "", nil)
 }
 
+// newSpanGenerator creates a span generator, failing the test if cfg is
+// invalid.
+func newSpanGenerator(t *testing.T, cfg *config.TracesConfig, topology *ServiceTopology) *SpanGenerator {
+	t.Helper()
+	g, err := NewSpanGenerator(cfg, topology)
+	if err != nil {
+		t.Fatalf("NewSpanGenerator: %v", err)
+	}
+	return g
+}
+
+// testGenerator creates a span generator over testTopology with a small
+// synthetic traces config, after mutate (if any) has adjusted it.
+func testGenerator(t *testing.T, mutate func(cfg *config.TracesConfig)) *SpanGenerator {
+	t.Helper()
+	cfg := &config.TracesConfig{
+		Spans:    config.SpansConfig{AvgPerTrace: 12, StdDev: 0},
+		Services: config.ServicesConfig{Count: 3, Names: []string{"api", "worker", "db"}},
+	}
+	if mutate != nil {
+		mutate(cfg)
+	}
+	return newSpanGenerator(t, cfg, testTopology())
+}
+
 func collect(tr *TraceTemplate) []*SpanNode {
 	return tr.CollectSpans()
 }
@@ -26,7 +51,7 @@ func TestFatSpansEverySpan(t *testing.T) {
 			PerSpanMin: 3, PerSpanMax: 3, ValueBytes: 200, KeyPrefix: "custom.fat",
 		},
 	}
-	g := NewSpanGenerator(cfg, testTopology())
+	g := newSpanGenerator(t, cfg, testTopology())
 
 	for i := 0; i < 10; i++ {
 		tr := g.GenerateTrace()
@@ -56,7 +81,7 @@ func TestBaselineUnchanged(t *testing.T) {
 		Services:         config.ServicesConfig{Count: 3, Names: []string{"api", "worker", "db"}},
 		CustomAttributes: config.CustomAttributesConfig{Count: 5},
 	}
-	g := NewSpanGenerator(cfg, testTopology())
+	g := newSpanGenerator(t, cfg, testTopology())
 
 	for i := 0; i < 20; i++ {
 		tr := g.GenerateTrace()
@@ -88,7 +113,7 @@ func TestRootlessAndLateRoot(t *testing.T) {
 			LateRoot: config.LateRootConfig{Enabled: true, Percentage: 100, DelayMs: 75000},
 		},
 	}
-	g := NewSpanGenerator(cfg, testTopology())
+	g := newSpanGenerator(t, cfg, testTopology())
 
 	for i := 0; i < 20; i++ {
 		tr := g.GenerateTrace()
@@ -118,7 +143,7 @@ func TestHighSpanTraceGetsRootTreatment(t *testing.T) {
 			Rootless: config.RootlessConfig{Enabled: true, Percentage: 100},
 		},
 	}
-	g := NewSpanGenerator(cfg, testTopology())
+	g := newSpanGenerator(t, cfg, testTopology())
 
 	tr := g.GenerateHighSpanTrace(500)
 	if len(tr.RootSpan.ParentID) == 0 {
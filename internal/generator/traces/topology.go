@@ -1,6 +1,9 @@
 package traces
 
 import (
+	"fmt"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
 	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
 )
 
@@ -11,6 +14,8 @@ const (
 	OperationTypeHTTP OperationType = iota
 	OperationTypeDB
 	OperationTypeInternal
+	OperationTypeRPC
+	OperationTypeMessaging
 )
 
 // ServiceNode represents a service in the topology
@@ -20,6 +25,28 @@ type ServiceNode struct {
 	IsIngress  bool
 	Operations []Operation
 	Downstream []*ServiceNode
+
+	// Edges holds the declared calls from traces.services.graph. It is empty
+	// for the default topology, where Downstream alone drives the tree shape.
+	Edges []*Edge
+
+	// opWeights are per-operation selection weights for graph-declared
+	// operations; nil means operations are picked uniformly.
+	opWeights []int
+}
+
+// Edge is a declared downstream call from one service to another.
+type Edge struct {
+	Target      *ServiceNode
+	Probability float64
+	FanOutMin   int
+	FanOutMax   int
+	Protocol    OperationType
+
+	// operations are the callee operations this edge can invoke: the target's
+	// operations matching Protocol, or a synthesized one when none match.
+	operations []Operation
+	weights    []int
 }
 
 // Operation represents an operation that a service can perform
@@ -34,6 +61,19 @@ type Operation struct {
 	// DB specific
 	DBSystem    string
 	DBStatement string
+
+	// RPC specific
+	RPCService string
+	RPCMethod  string
+
+	// Messaging specific. MessagingOperation is "publish" for a producer
+	// and "process" (or empty) for a consumer.
+	MessagingSystem    string
+	Destination        string
+	MessagingOperation string
+
+	// Weight is the relative selection weight of a graph-declared operation.
+	Weight int
 }
 
 // ServiceTopology represents the overall service graph
@@ -102,6 +142,212 @@ func BuildTopology(serviceNames []string, singleIngress bool, ingressService str
 	return topology
 }
 
+// ApplyGraph rewires the topology from a declared traces.services.graph. The
+// graph is authoritative: every random downstream relationship built by
+// BuildTopology is discarded, services with declared operations serve exactly
+// those, and (unless singleIngress) the ingress set becomes the services that
+// nothing else calls. The graph must already be validated (known services, no
+// cycles).
+func (t *ServiceTopology) ApplyGraph(graph []config.ServiceGraphNode, singleIngress bool) {
+	serviceMap := make(map[string]*ServiceNode, len(t.Services))
+	for _, service := range t.Services {
+		service.Downstream = nil
+		service.Edges = nil
+		serviceMap[service.Name] = service
+	}
+
+	// Operations first, so edges can resolve the callee's operations.
+	for _, node := range graph {
+		if len(node.Operations) == 0 {
+			continue
+		}
+		service := serviceMap[node.Service]
+		service.Operations = make([]Operation, 0, len(node.Operations))
+		service.opWeights = make([]int, 0, len(node.Operations))
+		for _, op := range node.Operations {
+			service.Operations = append(service.Operations, graphOperation(service.Name, op))
+			service.opWeights = append(service.opWeights, graphWeight(op.Weight))
+		}
+	}
+
+	called := make(map[*ServiceNode]bool)
+	for _, node := range graph {
+		service := serviceMap[node.Service]
+		for _, call := range node.Calls {
+			target := serviceMap[call.Service]
+			edge := &Edge{
+				Target:      target,
+				Probability: 1,
+				FanOutMin:   call.FanOut.Min,
+				FanOutMax:   call.FanOut.Max,
+				Protocol:    parseOperationType(call.Protocol, OperationTypeHTTP),
+			}
+			if call.Probability != nil {
+				edge.Probability = *call.Probability
+			}
+			if edge.FanOutMin == 0 {
+				edge.FanOutMin = 1
+			}
+			if edge.FanOutMax < edge.FanOutMin {
+				edge.FanOutMax = edge.FanOutMin
+			}
+			edge.operations, edge.weights = target.operationsFor(edge.Protocol)
+
+			service.Edges = append(service.Edges, edge)
+			service.Downstream = append(service.Downstream, target)
+			called[target] = true
+		}
+	}
+
+	if singleIngress {
+		return
+	}
+	t.IngressServices = t.IngressServices[:0]
+	for _, service := range t.Services {
+		service.IsIngress = !called[service]
+		if service.IsIngress {
+			t.IngressServices = append(t.IngressServices, service)
+		}
+	}
+}
+
+// HasGraph reports whether the topology was declared via traces.services.graph.
+func (t *ServiceTopology) HasGraph() bool {
+	for _, service := range t.Services {
+		if len(service.Edges) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// graphOperation converts a declared graph operation into an Operation,
+// filling protocol-specific defaults.
+func graphOperation(serviceName string, op config.GraphOperation) Operation {
+	operation := Operation{
+		Name:   op.Name,
+		Type:   parseOperationType(op.Type, OperationTypeHTTP),
+		Weight: graphWeight(op.Weight),
+	}
+
+	switch operation.Type {
+	case OperationTypeHTTP:
+		operation.HTTPMethod = op.Method
+		if operation.HTTPMethod == "" {
+			operation.HTTPMethod = "GET"
+		}
+		operation.HTTPPath = op.Path
+		if operation.HTTPPath == "" {
+			operation.HTTPPath = op.Name
+		}
+	case OperationTypeDB:
+		operation.DBSystem = op.DBSystem
+		if operation.DBSystem == "" {
+			operation.DBSystem = "postgresql"
+		}
+		operation.DBStatement = op.Statement
+		if operation.DBStatement == "" {
+			operation.DBStatement = common.RandomDBStatement(operation.DBSystem)
+		}
+	case OperationTypeRPC:
+		operation.RPCService = serviceName
+		operation.RPCMethod = op.Name
+	case OperationTypeMessaging:
+		operation.MessagingSystem = "kafka"
+		operation.Destination = op.Name
+	}
+
+	return operation
+}
+
+// operationsFor returns the service's operations of the given type with their
+// weights. When the service declares none, a single operation is synthesized
+// so every edge protocol still produces a sensible span.
+func (s *ServiceNode) operationsFor(opType OperationType) ([]Operation, []int) {
+	var ops []Operation
+	var weights []int
+	for i, op := range s.Operations {
+		if op.Type != opType {
+			continue
+		}
+		ops = append(ops, op)
+		if s.opWeights != nil {
+			weights = append(weights, s.opWeights[i])
+		} else {
+			weights = append(weights, 1)
+		}
+	}
+	if len(ops) > 0 {
+		return ops, weights
+	}
+
+	var op Operation
+	switch opType {
+	case OperationTypeHTTP:
+		path := "/" + s.Name
+		op = Operation{Name: "POST " + path, Type: OperationTypeHTTP, HTTPMethod: "POST", HTTPPath: path}
+	case OperationTypeRPC:
+		op = Operation{Name: s.Name + "/Handle", Type: OperationTypeRPC, RPCService: s.Name, RPCMethod: "Handle"}
+	case OperationTypeMessaging:
+		op = Operation{Name: s.Name + " process", Type: OperationTypeMessaging, MessagingSystem: "kafka", Destination: s.Name}
+	case OperationTypeDB:
+		op = Operation{Name: "db.query", Type: OperationTypeDB, DBSystem: "postgresql", DBStatement: common.RandomDBStatement("postgresql")}
+	default:
+		op = Operation{Name: "internal.process", Type: OperationTypeInternal}
+	}
+	return []Operation{op}, []int{1}
+}
+
+// Operation picks one of the callee operations this edge can invoke.
+func (e *Edge) Operation() Operation {
+	return common.RandomChoiceWeighted(e.operations, e.weights)
+}
+
+// parseOperationType maps a graph type/protocol string to an OperationType,
+// returning def for the empty string. Values are validated by the config.
+func parseOperationType(name string, def OperationType) OperationType {
+	switch name {
+	case "http":
+		return OperationTypeHTTP
+	case "grpc":
+		return OperationTypeRPC
+	case "messaging":
+		return OperationTypeMessaging
+	case "db":
+		return OperationTypeDB
+	case "internal":
+		return OperationTypeInternal
+	default:
+		return def
+	}
+}
+
+// graphWeight applies the default weight of 1 to an unset operation weight.
+func graphWeight(w int) int {
+	if w == 0 {
+		return 1
+	}
+	return w
+}
+
+// String returns the operation type as used in traces.services.graph.
+func (o OperationType) String() string {
+	switch o {
+	case OperationTypeHTTP:
+		return "http"
+	case OperationTypeDB:
+		return "db"
+	case OperationTypeInternal:
+		return "internal"
+	case OperationTypeRPC:
+		return "grpc"
+	case OperationTypeMessaging:
+		return "messaging"
+	default:
+		return fmt.Sprintf("OperationType(%d)", int(o))
+	}
+}
+
 // generateOperationsForService generates a set of operations for a service
 func generateOperationsForService(serviceName string) []Operation {
 	operations := make([]Operation, 0)
@@ -159,6 +405,9 @@ func (s *ServiceNode) GetRandomOperation() Operation {
 			Type: OperationTypeInternal,
 		}
 	}
+	if s.opWeights != nil {
+		return common.RandomChoiceWeighted(s.Operations, s.opWeights)
+	}
 	return common.RandomChoice(s.Operations)
 }
 
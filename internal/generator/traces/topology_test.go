@@ -0,0 +1,127 @@
+package traces
+
+import (
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+)
+
+func graphTopology() *ServiceTopology {
+	topo := BuildTopology([]string{"api", "orders", "db"}, false, "", nil)
+	topo.ApplyGraph([]config.ServiceGraphNode{
+		{
+			Service: "api",
+			Operations: []config.GraphOperation{
+				{Name: "GET /orders", Weight: 3},
+				{Name: "POST /orders", Method: "POST", Weight: 1},
+			},
+			Calls: []config.GraphEdge{{Service: "orders", Protocol: "grpc", FanOut: config.FanOutConfig{Min: 2, Max: 2}}},
+		},
+		{
+			Service:    "orders",
+			Operations: []config.GraphOperation{{Name: "GetOrder", Type: "grpc"}},
+			Calls:      []config.GraphEdge{{Service: "db", Protocol: "db"}},
+		},
+	}, false)
+	return topo
+}
+
+// TestApplyGraphWiring verifies the declared graph replaces the default wiring
+// and that only uncalled services are entry points.
+func TestApplyGraphWiring(t *testing.T) {
+	topo := graphTopology()
+
+	if len(topo.IngressServices) != 1 || topo.IngressServices[0].Name != "api" {
+		t.Fatalf("ingress services = %v, want only api", topo.IngressServices)
+	}
+	for _, s := range topo.Services {
+		want := map[string]int{"api": 1, "orders": 1, "db": 0}[s.Name]
+		if len(s.Downstream) != want || len(s.Edges) != want {
+			t.Errorf("%s: downstream=%d edges=%d, want %d", s.Name, len(s.Downstream), len(s.Edges), want)
+		}
+	}
+}
+
+// TestGraphTraceShape verifies generated traces follow the graph: fan-out
+// counts, callee services and edge protocols.
+func TestGraphTraceShape(t *testing.T) {
+	cfg := &config.TracesConfig{
+		Count:    1,
+		Spans:    config.SpansConfig{AvgPerTrace: 20, StdDev: 0},
+		Services: config.ServicesConfig{Count: 3, Names: []string{"api", "orders", "db"}},
+	}
+	g := newSpanGenerator(t, cfg, graphTopology())
+
+	for i := 0; i < 20; i++ {
+		tr := g.GenerateTrace()
+		root := tr.RootSpan
+		if root.Service.Name != "api" {
+			t.Fatalf("root service = %s, want api", root.Service.Name)
+		}
+		if len(root.Children) != 2 {
+			t.Fatalf("api fan-out = %d, want 2", len(root.Children))
+		}
+		for _, call := range root.Children {
+			if call.Service.Name != "orders" || call.Operation.Type != OperationTypeRPC || call.Operation.Name != "GetOrder" {
+				t.Fatalf("api child = %s/%s (%v), want orders/GetOrder (grpc)", call.Service.Name, call.Operation.Name, call.Operation.Type)
+			}
+			if len(call.Children) != 1 || call.Children[0].Operation.Type != OperationTypeDB {
+				t.Fatalf("orders should make exactly one db call, got %d children", len(call.Children))
+			}
+		}
+		if tr.SpanCount != 5 || len(tr.CollectSpans()) != 5 {
+			t.Fatalf("span count = %d (collected %d), want 5", tr.SpanCount, len(tr.CollectSpans()))
+		}
+	}
+}
+
+// TestGraphTraceBudget verifies the span budget caps a graph-shaped trace.
+func TestGraphTraceBudget(t *testing.T) {
+	cfg := &config.TracesConfig{
+		Count:    1,
+		Spans:    config.SpansConfig{AvgPerTrace: 3, StdDev: 0},
+		Services: config.ServicesConfig{Count: 3, Names: []string{"api", "orders", "db"}},
+	}
+	g := newSpanGenerator(t, cfg, graphTopology())
+
+	tr := g.GenerateTrace()
+	if got := len(tr.CollectSpans()); got != 3 || tr.SpanCount != 3 {
+		t.Fatalf("spans = %d (SpanCount %d), want 3", got, tr.SpanCount)
+	}
+}
+
+// TestGraphEdgeProbability verifies an omitted call probability always fires
+// and an explicit 0 never does.
+func TestGraphEdgeProbability(t *testing.T) {
+	never := 0.0
+	topo := BuildTopology([]string{"api", "orders", "db"}, false, "", nil)
+	topo.ApplyGraph([]config.ServiceGraphNode{{
+		Service: "api",
+		Calls: []config.GraphEdge{
+			{Service: "orders"},
+			{Service: "db", Protocol: "db", Probability: &never},
+		},
+	}}, false)
+	cfg := &config.TracesConfig{
+		Count:    1,
+		Spans:    config.SpansConfig{AvgPerTrace: 20, StdDev: 0},
+		Services: config.ServicesConfig{Count: 3, Names: []string{"api", "orders", "db"}},
+	}
+	g := newSpanGenerator(t, cfg, topo)
+
+	for i := 0; i < 50; i++ {
+		tr := g.GenerateTrace()
+		var orders int
+		for _, call := range tr.RootSpan.Children {
+			switch call.Service.Name {
+			case "orders":
+				orders++
+			case "db":
+				t.Fatalf("disabled api -> db call fired")
+			}
+		}
+		if orders != 1 {
+			t.Fatalf("api made %d calls to orders, want 1", orders)
+		}
+	}
+}
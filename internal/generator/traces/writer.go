@@ -18,6 +18,9 @@ import (
 type TraceWriter struct {
 	outputDir string
 	prefix    string
+
+	// resources, when set, splits each trace into per-service resources.
+	resources *serviceResources
 }
 
 // NewTraceWriter creates a new trace writer
@@ -64,79 +67,90 @@ func (w *TraceWriter) tracesToOTLP(traces []*TraceTemplate) *otlpcollectortrace.
 		ResourceSpans: make([]*otlptrace.ResourceSpans, 0),
 	}
 
-	// Each trace becomes its own ResourceSpans to keep all spans together
-	// This ensures that cross-service traces remain connected
 	for _, trace := range traces {
 		spans := trace.CollectSpans()
 
-		// Each trace becomes its own ResourceSpans. The resource carries the
-		// trace's entry-point (root) service.name so backends route the trace to
-		// the correct service dataset; per-span service.name is still set on each
-		// span for the other services involved in the trace.
-		rs := &otlptrace.ResourceSpans{
-			Resource: buildTraceResource(trace.RootSpan.Service),
-			ScopeSpans: []*otlptrace.ScopeSpans{
-				{
-					Scope: &commonpb.InstrumentationScope{
-						Name:    "telemetry-generator",
-						Version: "1.0.0",
-					},
-					Spans: make([]*otlptrace.Span, 0, len(spans)),
-				},
-			},
+		if w.resources != nil {
+			// One ResourceSpans per service, written consecutively; the sender
+			// regroups them by trace ID so the trace is transformed as one.
+			services, byService := splitByService(spans)
+			for _, service := range services {
+				request.ResourceSpans = append(request.ResourceSpans,
+					w.resourceSpans(w.resources.get(service), trace, byService[service]))
+			}
+			continue
 		}
 
-		// Add all spans from this trace
-		for _, spanNode := range spans {
-			// Convert span to OTLP
-			otlpSpan := spanNode.ToOTLPSpan()
-			otlpSpan.TraceId = trace.TraceID
+		// Each trace becomes its own ResourceSpans to keep all spans together.
+		// The resource carries the trace's entry-point (root) service.name so
+		// backends route the trace to the correct service dataset; per-span
+		// service.name is still set on each span for the other services
+		// involved in the trace.
+		request.ResourceSpans = append(request.ResourceSpans,
+			w.resourceSpans(buildTraceResource(trace.RootSpan.Service), trace, spans))
+	}
 
-			// Service name is already in the span attributes (added by generateAttributes)
-			// No need to add it to resource
+	return request
+}
 
-			// Store duration in attributes since we can't use timestamps
-			// This allows the sender to reconstruct relative timings
-			otlpSpan.Attributes = append(otlpSpan.Attributes, &commonpb.KeyValue{
-				Key: "_template.duration_nanos",
-				Value: &commonpb.AnyValue{
-					Value: &commonpb.AnyValue_IntValue{
-						IntValue: spanNode.Duration,
-					},
+// resourceSpans writes spans of trace as one ResourceSpans under resource.
+func (w *TraceWriter) resourceSpans(resource *resourcepb.Resource, trace *TraceTemplate, spans []*SpanNode) *otlptrace.ResourceSpans {
+	rs := &otlptrace.ResourceSpans{
+		Resource: resource,
+		ScopeSpans: []*otlptrace.ScopeSpans{
+			{
+				Scope: scope(),
+				Spans: make([]*otlptrace.Span, 0, len(spans)),
+			},
+		},
+	}
+
+	for _, spanNode := range spans {
+		// Convert span to OTLP
+		otlpSpan := spanNode.ToOTLPSpan()
+		otlpSpan.TraceId = trace.TraceID
+
+		// Service name is already in the span attributes (added by generateAttributes)
+
+		// Store duration in attributes since we can't use timestamps
+		// This allows the sender to reconstruct relative timings
+		otlpSpan.Attributes = append(otlpSpan.Attributes, &commonpb.KeyValue{
+			Key: "_template.duration_nanos",
+			Value: &commonpb.AnyValue{
+				Value: &commonpb.AnyValue_IntValue{
+					IntValue: spanNode.Duration,
 				},
-			})
+			},
+		})
+
+		// Store start offset for relative timing
+		otlpSpan.Attributes = append(otlpSpan.Attributes, &commonpb.KeyValue{
+			Key: "_template.start_offset_nanos",
+			Value: &commonpb.AnyValue{
+				Value: &commonpb.AnyValue_IntValue{
+					IntValue: spanNode.StartTime,
+				},
+			},
+		})
 
-			// Store start offset for relative timing
+		// Store per-span emit delay (only when set) so the sender can
+		// export this span later than the rest of its trace. Omitted when
+		// zero to keep baseline output byte-identical.
+		if spanNode.EmitDelayMs > 0 {
 			otlpSpan.Attributes = append(otlpSpan.Attributes, &commonpb.KeyValue{
-				Key: "_template.start_offset_nanos",
+				Key: "_template.emit_delay_ms",
 				Value: &commonpb.AnyValue{
 					Value: &commonpb.AnyValue_IntValue{
-						IntValue: spanNode.StartTime,
+						IntValue: int64(spanNode.EmitDelayMs),
 					},
 				},
 			})
-
-			// Store per-span emit delay (only when set) so the sender can
-			// export this span later than the rest of its trace. Omitted when
-			// zero to keep baseline output byte-identical.
-			if spanNode.EmitDelayMs > 0 {
-				otlpSpan.Attributes = append(otlpSpan.Attributes, &commonpb.KeyValue{
-					Key: "_template.emit_delay_ms",
-					Value: &commonpb.AnyValue{
-						Value: &commonpb.AnyValue_IntValue{
-							IntValue: int64(spanNode.EmitDelayMs),
-						},
-					},
-				})
-			}
-
-			rs.ScopeSpans[0].Spans = append(rs.ScopeSpans[0].Spans, otlpSpan)
 		}
 
-		request.ResourceSpans = append(request.ResourceSpans, rs)
+		rs.ScopeSpans[0].Spans = append(rs.ScopeSpans[0].Spans, otlpSpan)
 	}
 
-	return request
+	return rs
 }
 
 // buildTraceResource builds the OTLP resource for a trace. It sets
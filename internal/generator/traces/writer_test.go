@@ -1,10 +1,13 @@
 package traces
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/generator/common"
 	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
 )
 
 func resourceAttr(rs *otlptrace.ResourceSpans, key string) (string, bool) {
@@ -25,7 +28,7 @@ func TestResourceHasEntryPointServiceName(t *testing.T) {
 		Spans:    config.SpansConfig{AvgPerTrace: 6, StdDev: 0},
 		Services: config.ServicesConfig{Count: 3, Names: []string{"api", "worker", "db"}},
 	}
-	g := NewSpanGenerator(cfg, testTopology())
+	g := newSpanGenerator(t, cfg, testTopology())
 	w := NewTraceWriter("/tmp", "test")
 
 	for i := 0; i < 20; i++ {
@@ -57,7 +60,7 @@ func TestResourceHasNamespaceWhenSet(t *testing.T) {
 		},
 	}
 	topo := BuildTopology(cfg.Services.Names, false, "", map[string]string{"api": "shop", "db": "shop"})
-	g := NewSpanGenerator(cfg, topo)
+	g := newSpanGenerator(t, cfg, topo)
 	w := NewTraceWriter("/tmp", "test")
 
 	tr := g.GenerateTrace()
@@ -66,3 +69,88 @@ func TestResourceHasNamespaceWhenSet(t *testing.T) {
 		t.Fatalf("resource service.namespace = %q (ok=%v), want \"shop\"", ns, ok)
 	}
 }
+
+// TestSeededGenerationReproducible verifies that the same seed yields
+// byte-for-byte identical trace templates, topology included: NewGenerator
+// builds the topology from the config under the seed, as the CLI does.
+func TestSeededGenerationReproducible(t *testing.T) {
+	defer common.SetSeed(0)
+
+	cfg := &config.TracesConfig{
+		Count:            5,
+		Spans:            config.SpansConfig{AvgPerTrace: 8, StdDev: 2},
+		Services:         config.ServicesConfig{Count: 3, Names: []string{"api", "worker", "db"}},
+		CustomAttributes: config.CustomAttributesConfig{Count: 10},
+	}
+	w := NewTraceWriter("/tmp", "test")
+
+	generate := func(seed int64) []byte {
+		common.SetSeed(seed)
+		g, err := NewGenerator(cfg, t.TempDir(), "test")
+		if err != nil {
+			t.Fatalf("NewGenerator: %v", err)
+		}
+		traces := make([]*TraceTemplate, 0, cfg.Count)
+		for i := 0; i < cfg.Count; i++ {
+			traces = append(traces, g.spanGen.GenerateTrace())
+		}
+		data, err := proto.Marshal(w.tracesToOTLP(traces))
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return data
+	}
+
+	first, second := generate(42), generate(42)
+	if !bytes.Equal(first, second) {
+		t.Fatal("same seed produced different trace templates")
+	}
+	if bytes.Equal(first, generate(43)) {
+		t.Fatal("different seeds produced identical trace templates")
+	}
+}
+
+// TestPerServiceResources verifies traces.resources.per_service writes one
+// ResourceSpans per service holding exactly that service's spans, with the
+// same resource for a service in every trace.
+func TestPerServiceResources(t *testing.T) {
+	cfg := &config.TracesConfig{
+		Spans:     config.SpansConfig{AvgPerTrace: 10, StdDev: 0},
+		Services:  config.ServicesConfig{Count: 3, Names: []string{"api", "worker", "db"}},
+		Resources: config.TraceResourcesConfig{PerService: true, Environment: "staging"},
+	}
+	g := newSpanGenerator(t, cfg, testTopology())
+	w := NewTraceWriter("/tmp", "test")
+	w.resources = newServiceResources(cfg.Resources)
+
+	traces := []*TraceTemplate{g.GenerateTrace(), g.GenerateTrace(), g.GenerateTrace()}
+	req := w.tracesToOTLP(traces)
+
+	instances := map[string]string{}
+	spans := 0
+	for _, rs := range req.ResourceSpans {
+		service, _ := resourceAttr(rs, "service.name")
+		if env, _ := resourceAttr(rs, "deployment.environment"); env != "staging" {
+			t.Fatalf("deployment.environment = %q", env)
+		}
+		instance, _ := resourceAttr(rs, "service.instance.id")
+		if prev, ok := instances[service]; ok && prev != instance {
+			t.Fatalf("service %s has instances %s and %s", service, prev, instance)
+		}
+		instances[service] = instance
+		for _, s := range rs.ScopeSpans[0].Spans {
+			spans++
+			if got := stringAttribute(s.Attributes, "service.name"); got != service {
+				t.Fatalf("span of %s under resource %s", got, service)
+			}
+		}
+	}
+
+	want := 0
+	for _, tr := range traces {
+		want += len(tr.CollectSpans())
+	}
+	if spans != want {
+		t.Fatalf("wrote %d spans, want %d", spans, want)
+	}
+}
@@ -58,17 +58,19 @@ func (l *Loader) Load(tracesPath, metricsPath, logsPath string) (*Templates, err
 		}
 		templates.Metrics = metrics
 
-		// Count data points
+		// Count metric names and data points. Streamed templates split large
+		// metrics across resources, so names are counted once.
+		names := make(map[string]bool)
 		dataPoints := 0
 		for _, rm := range metrics.ResourceMetrics {
 			for _, sm := range rm.ScopeMetrics {
 				for _, metric := range sm.Metrics {
+					names[metric.Name] = true
 					dataPoints += l.countMetricDataPoints(metric)
 				}
 			}
 		}
-		fmt.Printf("  Loaded %d metrics with %d data points\n",
-			len(metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics), dataPoints)
+		fmt.Printf("  Loaded %d metrics with %d data points\n", len(names), dataPoints)
 	}
 
 	// Load logs if path provided
@@ -147,6 +149,8 @@ func (l *Loader) countMetricDataPoints(metric *otlpmetrics.Metric) int {
 		return len(data.Sum.DataPoints)
 	case *otlpmetrics.Metric_Histogram:
 		return len(data.Histogram.DataPoints)
+	case *otlpmetrics.Metric_ExponentialHistogram:
+		return len(data.ExponentialHistogram.DataPoints)
 	case *otlpmetrics.Metric_Summary:
 		return len(data.Summary.DataPoints)
 	default:
@@ -13,6 +13,8 @@ type Reporter struct {
 	metricsSent  atomic.Int64
 	logsSent     atomic.Int64
 	errors       atomic.Int64
+	anomalies    anomalyCounters
+	series       seriesCounters
 	startTime    time.Time
 	mu           sync.Mutex
 	lastReport   time.Time
@@ -20,6 +22,22 @@ type Reporter struct {
 	stopCh       chan struct{}
 }
 
+// anomalyCounters count injected timestamp anomalies.
+type anomalyCounters struct {
+	skewedSpans       atomic.Int64
+	childBeforeParent atomic.Int64
+	futureTraces      atomic.Int64
+	outOfOrderBatches atomic.Int64
+}
+
+// seriesCounters count churning metric series. Churn replaces series one for
+// one, so the series seen are the active ones; each replacement creates one
+// more.
+type seriesCounters struct {
+	active   atomic.Int64
+	replaced atomic.Int64
+}
+
 // NewReporter creates a new stats reporter
 func NewReporter() *Reporter {
 	return &Reporter{
@@ -49,6 +67,48 @@ func (r *Reporter) RecordError() {
 	r.errors.Add(1)
 }
 
+// RecordTimestampAnomalies records spans skewed, children moved before their
+// parent and traces moved into the future
+func (r *Reporter) RecordTimestampAnomalies(skewedSpans, childBeforeParent, futureTraces int) {
+	r.anomalies.skewedSpans.Add(int64(skewedSpans))
+	r.anomalies.childBeforeParent.Add(int64(childBeforeParent))
+	r.anomalies.futureTraces.Add(int64(futureTraces))
+}
+
+// RecordOutOfOrderBatch records a trace batch held back to arrive out of order
+func (r *Reporter) RecordOutOfOrderBatch() {
+	r.anomalies.outOfOrderBatches.Add(1)
+}
+
+// RecordSeries records churning series seen for the first time and series
+// replaced by churn
+func (r *Reporter) RecordSeries(newSeries, replaced int) {
+	r.series.active.Add(int64(newSeries))
+	r.series.replaced.Add(int64(replaced))
+}
+
+// printSeries prints the churning series counters, if churn saw any series
+func (r *Reporter) printSeries(indent string) {
+	active, replaced := r.series.active.Load(), r.series.replaced.Load()
+	if active == 0 {
+		return
+	}
+	fmt.Printf("%sChurning series: %d active, %d created (%d replaced)\n",
+		indent, active, active+replaced, replaced)
+}
+
+// printAnomalies prints the anomaly counters, if any anomaly was injected
+func (r *Reporter) printAnomalies(indent string) {
+	a := &r.anomalies
+	skewed, reordered := a.skewedSpans.Load(), a.childBeforeParent.Load()
+	future, batches := a.futureTraces.Load(), a.outOfOrderBatches.Load()
+	if skewed+reordered+future+batches == 0 {
+		return
+	}
+	fmt.Printf("%sTimestamp anomalies: %d skewed spans, %d children before parent, %d future traces, %d out-of-order batches\n",
+		indent, skewed, reordered, future, batches)
+}
+
 // StartPeriodicReporting starts periodic stat reporting
 func (r *Reporter) StartPeriodicReporting(interval time.Duration) {
 	r.reportTicker = time.NewTicker(interval)
@@ -104,6 +164,8 @@ func (r *Reporter) PrintStats() {
 	fmt.Printf("  Elapsed: %s\n", elapsed.Round(time.Second))
 	fmt.Printf("  Overall rate: %.0f events/sec\n", overallRate)
 	fmt.Printf("  Recent rate: %.0f events/sec\n", recentRate)
+	r.printAnomalies("  ")
+	r.printSeries("  ")
 
 	r.lastReport = now
 }
@@ -133,5 +195,7 @@ func (r *Reporter) PrintFinalStats() {
 	fmt.Printf("Total errors:       %d\n", errs)
 	fmt.Printf("Total duration:     %s\n", elapsed.Round(time.Millisecond))
 	fmt.Printf("Average rate:       %.0f events/sec\n", rate)
+	r.printAnomalies("")
+	r.printSeries("")
 	fmt.Println("═══════════════════════════════════════════════════════════")
 }
@@ -0,0 +1,172 @@
+package transformer
+
+import (
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// ClockAnomalies shifts already timestamped spans to simulate bad clocks:
+// skewed services, children that start before their parent and traces in the
+// future. It also picks the trace batches to send out of order. A nil
+// *ClockAnomalies changes nothing.
+type ClockAnomalies struct {
+	cfg      config.AnomaliesConfig
+	services map[string]bool
+}
+
+// AnomalyCounts reports what Apply changed in one trace.
+type AnomalyCounts struct {
+	SkewedSpans       int
+	ChildBeforeParent int
+	FutureTraces      int
+}
+
+// NewClockAnomalies creates the anomaly injector, or returns nil when no
+// anomaly is configured.
+func NewClockAnomalies(cfg config.AnomaliesConfig) *ClockAnomalies {
+	if cfg.Skew.Percentage == 0 && cfg.ChildBeforeParentPercentage == 0 &&
+		cfg.Future.Percentage == 0 && cfg.OutOfOrderBatches.Percentage == 0 {
+		return nil
+	}
+	a := &ClockAnomalies{cfg: cfg}
+	if len(cfg.Skew.Services) > 0 {
+		a.services = make(map[string]bool, len(cfg.Skew.Services))
+		for _, s := range cfg.Skew.Services {
+			a.services[s] = true
+		}
+	}
+	return a
+}
+
+// Apply injects anomalies into one trace whose timestamps are already set.
+// services[i] is the service.name of spans[i].
+func (a *ClockAnomalies) Apply(spans []*otlptrace.Span, services []string) AnomalyCounts {
+	var counts AnomalyCounts
+	if a == nil || len(spans) == 0 {
+		return counts
+	}
+
+	if skew := a.cfg.Skew; skew.Percentage > 0 && chance(skew.Percentage) {
+		skewed := a.services
+		if skewed == nil {
+			skewed = map[string]bool{pickSkewedService(spans, services): true}
+		}
+		offsets := make(map[string]int64)
+		for i, span := range spans {
+			if !skewed[services[i]] {
+				continue
+			}
+			offset, ok := offsets[services[i]]
+			if !ok {
+				offset = uniformNanos(skew.MinMs, skew.MaxMs)
+				offsets[services[i]] = offset
+			}
+			shiftSpan(span, offset)
+			counts.SkewedSpans++
+		}
+	}
+
+	if pct := a.cfg.ChildBeforeParentPercentage; pct > 0 && chance(pct) {
+		if moveChildBeforeParent(spans) {
+			counts.ChildBeforeParent++
+		}
+	}
+
+	if future := a.cfg.Future; future.Percentage > 0 && chance(future.Percentage) {
+		// Move the whole trace so that it ends the drawn offset from now.
+		var end uint64
+		for _, span := range spans {
+			end = max(end, span.EndTimeUnixNano)
+		}
+		offset := time.Now().UnixNano() + uniformNanos(future.MinMs, future.MaxMs) - int64(end)
+		for _, span := range spans {
+			shiftSpan(span, offset)
+		}
+		counts.FutureTraces++
+	}
+
+	return counts
+}
+
+// BatchDelayMs returns how long to hold the next trace batch back, so that it
+// arrives out of chronological order; 0 sends it now.
+func (a *ClockAnomalies) BatchDelayMs() int64 {
+	if a == nil || a.cfg.OutOfOrderBatches.Percentage == 0 || !chance(a.cfg.OutOfOrderBatches.Percentage) {
+		return 0
+	}
+	return a.cfg.OutOfOrderBatches.DelayMs
+}
+
+// pickSkewedService picks one service of the trace to skew, preferring one
+// other than the root's so the skew shows up relative to its callers.
+func pickSkewedService(spans []*otlptrace.Span, services []string) string {
+	// The root is the span that starts first.
+	root := 0
+	for i, span := range spans {
+		if span.StartTimeUnixNano < spans[root].StartTimeUnixNano {
+			root = i
+		}
+	}
+	var others []string
+	for _, s := range services {
+		if s != services[root] && !slices.Contains(others, s) {
+			others = append(others, s)
+		}
+	}
+	if len(others) == 0 {
+		return services[root]
+	}
+	return others[rand.Intn(len(others))]
+}
+
+// moveChildBeforeParent moves one child span (and its events) to start
+// before its parent, keeping its duration. It reports false when the trace
+// has no parent-child pair.
+func moveChildBeforeParent(spans []*otlptrace.Span) bool {
+	byID := make(map[string]*otlptrace.Span, len(spans))
+	for _, span := range spans {
+		byID[string(span.SpanId)] = span
+	}
+	var children []*otlptrace.Span
+	for _, span := range spans {
+		if byID[string(span.ParentSpanId)] != nil {
+			children = append(children, span)
+		}
+	}
+	if len(children) == 0 {
+		return false
+	}
+	child := children[rand.Intn(len(children))]
+	parent := byID[string(child.ParentSpanId)]
+
+	// Start 1-50ms before the parent.
+	start := int64(parent.StartTimeUnixNano) - uniformNanos(1, 50)
+	shiftSpan(child, start-int64(child.StartTimeUnixNano))
+	return true
+}
+
+// shiftSpan moves a span and its events by offset nanoseconds. Events were
+// copied when their timestamps were injected, so they can be changed in
+// place.
+func shiftSpan(span *otlptrace.Span, offset int64) {
+	span.StartTimeUnixNano = uint64(int64(span.StartTimeUnixNano) + offset)
+	span.EndTimeUnixNano = uint64(int64(span.EndTimeUnixNano) + offset)
+	for _, e := range span.Events {
+		e.TimeUnixNano = uint64(int64(e.TimeUnixNano) + offset)
+	}
+}
+
+// chance returns true pct percent of the time.
+func chance(pct float64) bool {
+	return rand.Float64()*100 < pct
+}
+
+// uniformNanos draws a duration uniformly from minMs..maxMs, in nanoseconds.
+func uniformNanos(minMs, maxMs float64) int64 {
+	ms := minMs + rand.Float64()*(maxMs-minMs)
+	return int64(ms * float64(time.Millisecond))
+}
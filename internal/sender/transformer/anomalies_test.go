@@ -0,0 +1,100 @@
+package transformer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// timedTrace returns a root and two children, timestamped as the injector
+// would, plus each span's service.
+func timedTrace() ([]*otlptrace.Span, []string) {
+	base := uint64(time.Now().Add(-time.Minute).UnixNano())
+	ms := uint64(time.Millisecond)
+	spans := []*otlptrace.Span{
+		{SpanId: []byte("root"), StartTimeUnixNano: base, EndTimeUnixNano: base + 100*ms},
+		{SpanId: []byte("a"), ParentSpanId: []byte("root"), StartTimeUnixNano: base + 10*ms, EndTimeUnixNano: base + 40*ms,
+			Events: []*otlptrace.Span_Event{{TimeUnixNano: base + 20*ms}}},
+		{SpanId: []byte("b"), ParentSpanId: []byte("root"), StartTimeUnixNano: base + 50*ms, EndTimeUnixNano: base + 90*ms},
+	}
+	return spans, []string{"gateway", "orders", "orders"}
+}
+
+func TestClockAnomaliesSkew(t *testing.T) {
+	a := NewClockAnomalies(config.AnomaliesConfig{
+		Skew: config.ClockSkewConfig{Percentage: 100, MinMs: 500, MaxMs: 500},
+	})
+	spans, services := timedTrace()
+	before := []uint64{spans[0].StartTimeUnixNano, spans[1].StartTimeUnixNano, spans[2].StartTimeUnixNano}
+	eventBefore := spans[1].Events[0].TimeUnixNano
+
+	counts := a.Apply(spans, services)
+
+	// Without a service list, a non-root service is skewed.
+	if counts.SkewedSpans != 2 {
+		t.Fatalf("skewed %d spans, want 2", counts.SkewedSpans)
+	}
+	shift := uint64(500 * time.Millisecond)
+	if spans[0].StartTimeUnixNano != before[0] {
+		t.Error("root service was skewed")
+	}
+	for i := 1; i < 3; i++ {
+		if spans[i].StartTimeUnixNano != before[i]+shift {
+			t.Errorf("span %d shifted by %v, want 500ms", i, time.Duration(spans[i].StartTimeUnixNano-before[i]))
+		}
+	}
+	if spans[1].Events[0].TimeUnixNano != eventBefore+shift {
+		t.Error("event not shifted with its span")
+	}
+}
+
+func TestClockAnomaliesChildBeforeParentAndFuture(t *testing.T) {
+	a := NewClockAnomalies(config.AnomaliesConfig{
+		ChildBeforeParentPercentage: 100,
+		Future:                      config.FutureTimestampsConfig{Percentage: 100, MinMs: 60_000, MaxMs: 120_000},
+	})
+	spans, services := timedTrace()
+	counts := a.Apply(spans, services)
+	if counts.ChildBeforeParent != 1 || counts.FutureTraces != 1 {
+		t.Fatalf("counts = %+v, want one child moved and one future trace", counts)
+	}
+
+	early := 0
+	var end uint64
+	for _, s := range spans[1:] {
+		if s.StartTimeUnixNano < spans[0].StartTimeUnixNano {
+			early++
+		}
+	}
+	for _, s := range spans {
+		end = max(end, s.EndTimeUnixNano)
+		if s.EndTimeUnixNano-s.StartTimeUnixNano == 0 {
+			t.Error("span lost its duration")
+		}
+	}
+	if early != 1 {
+		t.Errorf("%d children start before the root, want 1", early)
+	}
+	if ahead := time.Duration(int64(end) - time.Now().UnixNano()); ahead < 59*time.Second || ahead > 2*time.Minute {
+		t.Errorf("trace ends %v in the future, want 1-2 minutes", ahead)
+	}
+}
+
+func TestClockAnomaliesDisabled(t *testing.T) {
+	if a := NewClockAnomalies(config.AnomaliesConfig{}); a != nil {
+		t.Fatal("expected nil without anomalies")
+	}
+	var a *ClockAnomalies
+	if a.BatchDelayMs() != 0 {
+		t.Error("nil anomalies delayed a batch")
+	}
+
+	a = NewClockAnomalies(config.AnomaliesConfig{
+		OutOfOrderBatches: config.OutOfOrderBatchesConfig{Percentage: 100, DelayMs: 3000},
+	})
+	if got := a.BatchDelayMs(); got != 3000 {
+		t.Errorf("BatchDelayMs = %d, want 3000", got)
+	}
+}
@@ -0,0 +1,50 @@
+package transformer
+
+import "time"
+
+// BackfillClock is a clock for sending historical data. It starts window
+// before end and advances speed times faster than real time, stopping at end.
+type BackfillClock struct {
+	start time.Time
+	end   time.Time
+	speed float64
+	began time.Time
+}
+
+// NewBackfillClock creates a clock that walks the window ending at end, as of
+// now.
+func NewBackfillClock(window time.Duration, speed float64, end time.Time) *BackfillClock {
+	return &BackfillClock{
+		start: end.Add(-window),
+		end:   end,
+		speed: speed,
+		began: time.Now(),
+	}
+}
+
+// Now returns the current data time: the window start plus the real time
+// elapsed since the clock was created, scaled by speed, capped at the end of
+// the window.
+func (b *BackfillClock) Now() time.Time {
+	return b.at(time.Now())
+}
+
+// at returns the data time at real time t.
+func (b *BackfillClock) at(t time.Time) time.Time {
+	elapsed := time.Duration(float64(t.Sub(b.began)) * b.speed)
+	if now := b.start.Add(elapsed); now.Before(b.end) {
+		return now
+	}
+	return b.end
+}
+
+// Remaining returns the real time left until the window is covered.
+func (b *BackfillClock) Remaining() time.Duration {
+	total := time.Duration(float64(b.end.Sub(b.start)) / b.speed)
+	return max(total-time.Since(b.began), 0)
+}
+
+// Window returns the start and end of the data time being backfilled.
+func (b *BackfillClock) Window() (start, end time.Time) {
+	return b.start, b.end
+}
@@ -0,0 +1,51 @@
+package transformer
+
+import (
+	"testing"
+	"time"
+
+	otlplogs "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestBackfillClock(t *testing.T) {
+	end := time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)
+	b := NewBackfillClock(7*24*time.Hour, 144, end)
+
+	start, _ := b.Window()
+	if want := end.Add(-7 * 24 * time.Hour); !start.Equal(want) {
+		t.Fatalf("window start = %v, want %v", start, want)
+	}
+
+	// 144x: ten real minutes cover one day of data.
+	for _, c := range []struct {
+		real time.Duration
+		want time.Time
+	}{
+		{0, start},
+		{10 * time.Minute, start.Add(24 * time.Hour)},
+		{70 * time.Minute, end},
+		{2 * time.Hour, end}, // capped at the end of the window
+	} {
+		if got := b.at(b.began.Add(c.real)); !got.Equal(c.want) {
+			t.Errorf("after %v: data time %v, want %v", c.real, got, c.want)
+		}
+	}
+
+	if r := b.Remaining(); r > 70*time.Minute || r < 69*time.Minute {
+		t.Errorf("Remaining = %v, want about 70m", r)
+	}
+}
+
+func TestInjectorUsesBackfillClock(t *testing.T) {
+	inj := NewTimestampInjector(0, 0)
+	end := time.Now().Add(-30 * 24 * time.Hour)
+	inj.SetBackfill(NewBackfillClock(24*time.Hour, 1, end))
+
+	logs := []*otlplogs.LogRecord{{}}
+	inj.InjectLogTimestamps(logs)
+
+	got := time.Unix(0, int64(logs[0].TimeUnixNano))
+	if start := end.Add(-24 * time.Hour); got.Before(start) || got.After(start.Add(time.Minute)) {
+		t.Errorf("log time %v not at the start of the backfill window %v", got, start)
+	}
+}
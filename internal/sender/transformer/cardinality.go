@@ -0,0 +1,199 @@
+package transformer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlplogs "go.opentelemetry.io/proto/otlp/logs/v1"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// CardinalityInjector rewrites configured span and log attributes with fresh
+// values at send time, so replaying the same templates keeps adding distinct
+// values instead of repeating the first pass. A nil *CardinalityInjector
+// rewrites nothing.
+type CardinalityInjector struct {
+	rules []*cardinalityRule
+	// salt keeps replay hashes from different sender processes apart.
+	salt    uint64
+	replays atomic.Uint64
+}
+
+type cardinalityRule struct {
+	config.CardinalityAttribute
+	perReplay bool
+	events    atomic.Uint64
+}
+
+// NewCardinalityInjector creates an injector for the configured attributes,
+// or returns nil when there are none.
+func NewCardinalityInjector(attrs []config.CardinalityAttribute) *CardinalityInjector {
+	if len(attrs) == 0 {
+		return nil
+	}
+	c := &CardinalityInjector{salt: rand.Uint64()}
+	for _, a := range attrs {
+		c.rules = append(c.rules, &cardinalityRule{CardinalityAttribute: a, perReplay: a.Per == "replay"})
+	}
+	return c
+}
+
+// CardinalityPass rewrites attributes for one pass over the templates. Rules
+// with per: replay give every event of the pass the same value.
+type CardinalityPass struct {
+	injector *CardinalityInjector
+	replay   uint64
+	values   []*commonpb.AnyValue // per-replay values, nil for per-event rules
+}
+
+// NewPass starts the next replay.
+func (c *CardinalityInjector) NewPass() *CardinalityPass {
+	if c == nil {
+		return nil
+	}
+	pass := &CardinalityPass{
+		injector: c,
+		replay:   c.replays.Add(1),
+		values:   make([]*commonpb.AnyValue, len(c.rules)),
+	}
+	for i, r := range c.rules {
+		if r.perReplay {
+			pass.values[i] = pass.replayValue(r)
+		}
+	}
+	return pass
+}
+
+// RewriteSpans rewrites the configured attributes of each span.
+func (p *CardinalityPass) RewriteSpans(spans []*otlptrace.Span) {
+	if p == nil {
+		return
+	}
+	for _, span := range spans {
+		span.Attributes = p.rewrite(span.Attributes)
+	}
+}
+
+// RewriteLogs rewrites the configured attributes of each log record.
+func (p *CardinalityPass) RewriteLogs(logs []*otlplogs.LogRecord) {
+	if p == nil {
+		return
+	}
+	for _, lr := range logs {
+		lr.Attributes = p.rewrite(lr.Attributes)
+	}
+}
+
+// rewrite returns attrs with each rule's key set to a new value. Attributes
+// are shared with the template, so the slice and the rewritten KeyValues are
+// replaced rather than modified.
+func (p *CardinalityPass) rewrite(attrs []*commonpb.KeyValue) []*commonpb.KeyValue {
+	var out []*commonpb.KeyValue
+	for i, r := range p.injector.rules {
+		idx := slices.IndexFunc(attrs, func(kv *commonpb.KeyValue) bool { return kv.Key == r.Key })
+		if idx < 0 && !r.Add {
+			continue
+		}
+		if out == nil {
+			out = make([]*commonpb.KeyValue, len(attrs), len(attrs)+len(p.injector.rules))
+			copy(out, attrs)
+		}
+
+		value := p.values[i]
+		if value == nil {
+			var old *commonpb.AnyValue
+			if idx >= 0 {
+				old = attrs[idx].Value
+			}
+			value = p.eventValue(r, old)
+		}
+
+		kv := &commonpb.KeyValue{Key: r.Key, Value: value}
+		if idx >= 0 {
+			out[idx] = kv
+		} else {
+			out = append(out, kv)
+		}
+	}
+	if out == nil {
+		return attrs
+	}
+	return out
+}
+
+// replayValue is a per-replay rule's value for this pass.
+func (p *CardinalityPass) replayValue(r *cardinalityRule) *commonpb.AnyValue {
+	switch r.Generator {
+	case "counter":
+		return stringValue(r.Prefix + strconv.FormatUint(p.replay, 10))
+	case "uuid":
+		return stringValue(r.Prefix + newUUID())
+	case "pool":
+		return stringValue(r.Prefix + strconv.FormatUint((p.replay-1)%uint64(r.PoolSize), 10))
+	default: // replay_hash
+		return stringValue(r.Prefix + p.hash(r.Key, ""))
+	}
+}
+
+// eventValue is a per-event rule's value for an event whose template value is
+// old (nil when the attribute is being added).
+func (p *CardinalityPass) eventValue(r *cardinalityRule, old *commonpb.AnyValue) *commonpb.AnyValue {
+	switch r.Generator {
+	case "counter":
+		return stringValue(r.Prefix + strconv.FormatUint(r.events.Add(1), 10))
+	case "uuid":
+		return stringValue(r.Prefix + newUUID())
+	case "pool":
+		return stringValue(r.Prefix + strconv.Itoa(rand.Intn(r.PoolSize)))
+	default: // replay_hash
+		return stringValue(r.Prefix + p.hash(r.Key, valueString(old)))
+	}
+}
+
+// hash returns a hex hash of the replay number, key and template value.
+func (p *CardinalityPass) hash(key, value string) string {
+	h := fnv.New64a()
+	var buf [16]byte
+	binary.LittleEndian.PutUint64(buf[:8], p.injector.salt)
+	binary.LittleEndian.PutUint64(buf[8:], p.replay)
+	h.Write(buf[:])
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// valueString renders a template value for hashing.
+func valueString(v *commonpb.AnyValue) string {
+	switch x := v.GetValue().(type) {
+	case nil:
+		return ""
+	case *commonpb.AnyValue_StringValue:
+		return x.StringValue
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(x.IntValue, 10)
+	default:
+		return v.String()
+	}
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], rand.Uint64())
+	binary.LittleEndian.PutUint64(b[8:], rand.Uint64())
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,132 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func attrSpan(kvs ...string) *otlptrace.Span {
+	s := &otlptrace.Span{}
+	for i := 0; i < len(kvs); i += 2 {
+		s.Attributes = append(s.Attributes, &commonpb.KeyValue{Key: kvs[i], Value: stringValue(kvs[i+1])})
+	}
+	return s
+}
+
+func attrValue(s *otlptrace.Span, key string) (string, bool) {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value.GetStringValue(), true
+		}
+	}
+	return "", false
+}
+
+// replaySpans clones the template spans' attribute slices as the sender's
+// clone does, then rewrites them in a new pass.
+func replaySpans(c *CardinalityInjector, tmpl []*otlptrace.Span) []*otlptrace.Span {
+	spans := make([]*otlptrace.Span, len(tmpl))
+	for i, s := range tmpl {
+		spans[i] = &otlptrace.Span{Attributes: s.Attributes}
+	}
+	c.NewPass().RewriteSpans(spans)
+	return spans
+}
+
+func TestCardinalityPerEvent(t *testing.T) {
+	c := NewCardinalityInjector([]config.CardinalityAttribute{
+		{Key: "request.id", Generator: "counter", Prefix: "req-"},
+		{Key: "session.id", Generator: "uuid", Add: true},
+		{Key: "user.id", Generator: "replay_hash"},
+		{Key: "tenant", Generator: "pool", PoolSize: 3},
+	})
+	tmpl := []*otlptrace.Span{
+		attrSpan("request.id", "r", "user.id", "alice", "tenant", "t"),
+		attrSpan("request.id", "r", "user.id", "alice"),
+		attrSpan("user.id", "bob"),
+	}
+
+	first := replaySpans(c, tmpl)
+	second := replaySpans(c, tmpl)
+
+	if v, _ := attrValue(tmpl[0], "request.id"); v != "r" {
+		t.Fatalf("template attribute modified: %q", v)
+	}
+	if _, ok := attrValue(tmpl[0], "session.id"); ok {
+		t.Fatal("added attribute leaked into the template")
+	}
+
+	seen := map[string]bool{}
+	for _, spans := range [][]*otlptrace.Span{first, second} {
+		for _, s := range spans {
+			if v, ok := attrValue(s, "request.id"); ok {
+				if !strings.HasPrefix(v, "req-") || seen[v] {
+					t.Errorf("request.id %q not a fresh counter value", v)
+				}
+				seen[v] = true
+			}
+			if v, ok := attrValue(s, "session.id"); !ok || len(v) != 36 {
+				t.Errorf("session.id = %q, want an added UUID", v)
+			}
+			if v, ok := attrValue(s, "tenant"); ok && v != "0" && v != "1" && v != "2" {
+				t.Errorf("tenant %q outside the pool", v)
+			}
+		}
+	}
+	if _, ok := attrValue(first[2], "request.id"); ok {
+		t.Error("request.id added although add is false")
+	}
+
+	// replay_hash keeps equal template values equal within a pass, and
+	// changes them between passes.
+	a0, _ := attrValue(first[0], "user.id")
+	a1, _ := attrValue(first[1], "user.id")
+	b, _ := attrValue(first[2], "user.id")
+	again, _ := attrValue(second[0], "user.id")
+	if a0 != a1 || a0 == b || a0 == again {
+		t.Errorf("replay_hash values: alice %q/%q, bob %q, next pass %q", a0, a1, b, again)
+	}
+}
+
+func TestCardinalityPerReplay(t *testing.T) {
+	c := NewCardinalityInjector([]config.CardinalityAttribute{
+		{Key: "build", Generator: "counter", Per: "replay", Prefix: "b", Add: true},
+		{Key: "shard", Generator: "pool", Per: "replay", PoolSize: 2, Add: true},
+	})
+	tmpl := []*otlptrace.Span{attrSpan(), attrSpan()}
+
+	var builds, shards []string
+	for i := 0; i < 3; i++ {
+		spans := replaySpans(c, tmpl)
+		b0, _ := attrValue(spans[0], "build")
+		b1, _ := attrValue(spans[1], "build")
+		if b0 != b1 {
+			t.Fatalf("pass %d: build differs within a replay: %q vs %q", i, b0, b1)
+		}
+		s, _ := attrValue(spans[0], "shard")
+		builds, shards = append(builds, b0), append(shards, s)
+	}
+	if strings.Join(builds, ",") != "b1,b2,b3" {
+		t.Errorf("builds = %v, want b1,b2,b3", builds)
+	}
+	if strings.Join(shards, ",") != "0,1,0" {
+		t.Errorf("shards = %v, want 0,1,0", shards)
+	}
+}
+
+func TestCardinalityNilInjector(t *testing.T) {
+	c := NewCardinalityInjector(nil)
+	if c != nil {
+		t.Fatal("expected nil injector without attributes")
+	}
+	span := attrSpan("k", "v")
+	attrs := span.Attributes
+	c.NewPass().RewriteSpans([]*otlptrace.Span{span})
+	if &span.Attributes[0] != &attrs[0] {
+		t.Error("nil injector replaced attributes")
+	}
+}
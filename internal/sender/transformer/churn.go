@@ -0,0 +1,457 @@
+package transformer
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// SeriesChurn replaces series with new ones over time, as pods and containers
+// are rescheduled in a real cluster. A series' churn attributes (pod name,
+// container ID, ...) form its dimension set; every interval, the configured
+// percentage of dimension sets moves to a new generation with fresh values
+// for those attributes, ending every series that carried the old values and
+// starting new ones. Series of all metrics that share a dimension set churn
+// together, like the pod they describe. Time is read from the injected point
+// timestamps, so churn follows the backfill clock too. Metrics grouped into
+// one resource per entity carry the churn attributes in the resource
+// instead; ApplyResource churns those. A nil *SeriesChurn changes nothing.
+type SeriesChurn struct {
+	pct   float64
+	every time.Duration
+	keys  map[string]bool
+
+	mu        sync.Mutex
+	started   bool
+	origin    uint64 // first send, in Unix nanoseconds
+	sets      map[string]*churnSet
+	series    map[**commonpb.KeyValue]*churnSeries // keyed by template attributes
+	resources map[*resourcepb.Resource]*churnResource
+}
+
+// churnSet is one dimension set's state.
+type churnSet struct {
+	interval   int64  // last interval churn was decided for
+	generation int    // replacements so far
+	born       uint64 // start of the current generation, in Unix nanoseconds
+}
+
+// churnSeries is one series' state; set is nil when the series carries none
+// of the churn attributes.
+type churnSeries struct {
+	set        *churnSet
+	generation int                  // generation last sent
+	attrs      []*commonpb.KeyValue // attributes for that generation
+}
+
+// churnResource is one template resource's state; set is nil when the
+// resource carries none of the churn attributes.
+type churnResource struct {
+	set        *churnSet
+	generation int                  // generation last sent
+	resource   *resourcepb.Resource // resource for that generation
+	series     map[resourceSeries]int
+}
+
+// resourceSeries identifies a series within a resource. The generator never
+// repeats a series within a resource, so a metric has at most one point
+// without attributes there, told apart by metric alone.
+type resourceSeries struct {
+	metric string
+	attrs  **commonpb.KeyValue
+}
+
+// NewSeriesChurn creates the churner, or returns nil when churn is disabled.
+func NewSeriesChurn(cfg config.ChurnConfig) *SeriesChurn {
+	if cfg.Percentage <= 0 {
+		return nil
+	}
+	c := &SeriesChurn{
+		pct:       cfg.Percentage,
+		every:     cfg.GetEvery(),
+		keys:      make(map[string]bool, len(cfg.Attributes)),
+		sets:      make(map[string]*churnSet),
+		series:    make(map[**commonpb.KeyValue]*churnSeries),
+		resources: make(map[*resourcepb.Resource]*churnResource),
+	}
+	for _, key := range cfg.Attributes {
+		c.keys[key] = true
+	}
+	return c
+}
+
+// Apply replaces the churned series' points with copies carrying their
+// current generation's attributes, and returns how many series it saw for
+// the first time and how many it replaced. Points must carry the template's
+// attributes and injected timestamps; they are never modified. Only series
+// that carry a churn attribute are counted. A replaced cumulative series
+// starts no earlier than its replacement.
+func (c *SeriesChurn) Apply(metric *otlpmetrics.Metric) (newSeries, replaced int) {
+	if c == nil {
+		return 0, 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// point returns the attributes and start time a point is sent with, and
+	// whether they differ from the point's own.
+	point := func(attrs []*commonpb.KeyValue, start, now uint64) ([]*commonpb.KeyValue, uint64, bool) {
+		st, created := c.lookup(attrs)
+		if st.set == nil {
+			return attrs, start, false
+		}
+		if created {
+			newSeries++
+		}
+		set := c.advance(st.set, now)
+		if set.generation == 0 {
+			return attrs, start, false
+		}
+		if st.generation != set.generation {
+			st.generation = set.generation
+			st.attrs = c.churnAttributes(attrs, set.generation)
+			if !created {
+				replaced++
+			}
+		}
+		if start != 0 && start < set.born {
+			start = set.born
+		}
+		return st.attrs, start, true
+	}
+
+	rewritePoints(metric, point)
+	return newSeries, replaced
+}
+
+// ApplyResource churns the resource of metrics grouped by entity the way
+// Apply churns points: the resource's churn attributes form its dimension
+// set, so every series under it churns together. Once the set has churned,
+// rm's resource is replaced by a copy carrying the current generation's
+// attributes, and cumulative points are replaced so they start no earlier
+// than it. rm.Resource must be the template's; call it after the points'
+// timestamps are injected. Series that carry a churn attribute of their own
+// are left for Apply to count.
+func (c *SeriesChurn) ApplyResource(rm *otlpmetrics.ResourceMetrics) (newSeries, replaced int) {
+	if c == nil || rm.Resource == nil {
+		return 0, 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res := c.resource(rm.Resource)
+	if res.set == nil {
+		return 0, 0
+	}
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, metric := range sm.Metrics {
+			rewritePoints(metric, func(attrs []*commonpb.KeyValue, start, now uint64) ([]*commonpb.KeyValue, uint64, bool) {
+				set := c.advance(res.set, now)
+				if !c.carriesKey(attrs) {
+					key := resourceSeries{metric: metric.Name}
+					if len(attrs) > 0 {
+						key.attrs = &attrs[0]
+					}
+					generation, seen := res.series[key]
+					switch {
+					case !seen:
+						newSeries++
+					case generation != set.generation:
+						replaced++
+					}
+					res.series[key] = set.generation
+				}
+				if start != 0 && start < set.born {
+					return attrs, set.born, true
+				}
+				return attrs, start, false
+			})
+		}
+	}
+
+	if res.set.generation == 0 {
+		return newSeries, replaced
+	}
+	if res.resource == nil || res.generation != res.set.generation {
+		res.generation = res.set.generation
+		res.resource = &resourcepb.Resource{
+			Attributes:             c.churnAttributes(rm.Resource.Attributes, res.generation),
+			DroppedAttributesCount: rm.Resource.DroppedAttributesCount,
+		}
+	}
+	rm.Resource = res.resource
+	return newSeries, replaced
+}
+
+// pointRewrite returns the attributes and start time a point with the given
+// attributes, start and time is sent with, and whether they differ from the
+// point's own.
+type pointRewrite func(attrs []*commonpb.KeyValue, start, now uint64) ([]*commonpb.KeyValue, uint64, bool)
+
+// rewritePoints replaces the metric's data with copies of the points point
+// rewrites, leaving it alone when point changes none.
+func rewritePoints(metric *otlpmetrics.Metric, point pointRewrite) {
+	switch data := metric.Data.(type) {
+	case *otlpmetrics.Metric_Gauge:
+		if points, ok := rewriteNumberPoints(data.Gauge.DataPoints, point); ok {
+			metric.Data = &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{DataPoints: points}}
+		}
+	case *otlpmetrics.Metric_Sum:
+		if points, ok := rewriteNumberPoints(data.Sum.DataPoints, point); ok {
+			metric.Data = &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+				AggregationTemporality: data.Sum.AggregationTemporality,
+				IsMonotonic:            data.Sum.IsMonotonic,
+				DataPoints:             points,
+			}}
+		}
+	case *otlpmetrics.Metric_Histogram:
+		points := make([]*otlpmetrics.HistogramDataPoint, len(data.Histogram.DataPoints))
+		changed := false
+		for i, dp := range data.Histogram.DataPoints {
+			points[i] = dp
+			if attrs, start, ok := point(dp.Attributes, dp.StartTimeUnixNano, dp.TimeUnixNano); ok {
+				out := cloneHistogramPoint(dp, start, dp.TimeUnixNano)
+				out.Attributes = attrs
+				points[i], changed = out, true
+			}
+		}
+		if changed {
+			metric.Data = &otlpmetrics.Metric_Histogram{Histogram: &otlpmetrics.Histogram{
+				AggregationTemporality: data.Histogram.AggregationTemporality,
+				DataPoints:             points,
+			}}
+		}
+	case *otlpmetrics.Metric_ExponentialHistogram:
+		points := make([]*otlpmetrics.ExponentialHistogramDataPoint, len(data.ExponentialHistogram.DataPoints))
+		changed := false
+		for i, dp := range data.ExponentialHistogram.DataPoints {
+			points[i] = dp
+			if attrs, start, ok := point(dp.Attributes, dp.StartTimeUnixNano, dp.TimeUnixNano); ok {
+				out := cloneExponentialPoint(dp, start, dp.TimeUnixNano)
+				out.Attributes = attrs
+				points[i], changed = out, true
+			}
+		}
+		if changed {
+			metric.Data = &otlpmetrics.Metric_ExponentialHistogram{ExponentialHistogram: &otlpmetrics.ExponentialHistogram{
+				AggregationTemporality: data.ExponentialHistogram.AggregationTemporality,
+				DataPoints:             points,
+			}}
+		}
+	case *otlpmetrics.Metric_Summary:
+		points := make([]*otlpmetrics.SummaryDataPoint, len(data.Summary.DataPoints))
+		changed := false
+		for i, dp := range data.Summary.DataPoints {
+			points[i] = dp
+			if attrs, start, ok := point(dp.Attributes, dp.StartTimeUnixNano, dp.TimeUnixNano); ok {
+				out := cloneSummaryPoint(dp, start, dp.TimeUnixNano)
+				out.Attributes = attrs
+				points[i], changed = out, true
+			}
+		}
+		if changed {
+			metric.Data = &otlpmetrics.Metric_Summary{Summary: &otlpmetrics.Summary{DataPoints: points}}
+		}
+	}
+}
+
+// rewriteNumberPoints applies point to gauge or sum points, returning the
+// new points and whether any changed.
+func rewriteNumberPoints(dps []*otlpmetrics.NumberDataPoint, point pointRewrite) ([]*otlpmetrics.NumberDataPoint, bool) {
+	points := make([]*otlpmetrics.NumberDataPoint, len(dps))
+	changed := false
+	for i, dp := range dps {
+		points[i] = dp
+		if attrs, start, ok := point(dp.Attributes, dp.StartTimeUnixNano, dp.TimeUnixNano); ok {
+			out := cloneNumberPoint(dp, start, dp.TimeUnixNano)
+			out.Attributes = attrs
+			points[i], changed = out, true
+		}
+	}
+	return points, changed
+}
+
+// Born returns when the current generation of a series began: the later of
+// its resource's and its own churn attributes' generations, or 0 while
+// neither has churned. It decides churn up to now like Apply, so the series
+// tracker can restart a cumulative series that churn is about to replace.
+// resource may be nil.
+func (c *SeriesChurn) Born(resource *resourcepb.Resource, attrs []*commonpb.KeyValue, now uint64) uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var born uint64
+	if set := c.setFor(attrs); set != nil {
+		born = c.advance(set, now).born
+	}
+	if resource != nil {
+		if set := c.resource(resource).set; set != nil {
+			born = max(born, c.advance(set, now).born)
+		}
+	}
+	return born
+}
+
+// resource returns the state of a template resource, adding it if new.
+func (c *SeriesChurn) resource(resource *resourcepb.Resource) *churnResource {
+	res, ok := c.resources[resource]
+	if !ok {
+		res = &churnResource{
+			set:    c.setFor(resource.Attributes),
+			series: make(map[resourceSeries]int),
+		}
+		c.resources[resource] = res
+	}
+	return res
+}
+
+// lookup returns the state of the series with the given template attributes,
+// and whether it was just created. Series without attributes are never
+// churned.
+func (c *SeriesChurn) lookup(attrs []*commonpb.KeyValue) (*churnSeries, bool) {
+	if len(attrs) == 0 {
+		return &churnSeries{}, false
+	}
+	if st, ok := c.series[&attrs[0]]; ok {
+		return st, false
+	}
+	st := &churnSeries{set: c.setFor(attrs)}
+	c.series[&attrs[0]] = st
+	return st, true
+}
+
+// setFor returns the dimension set of the churn attributes among attrs, or
+// nil if they carry none.
+func (c *SeriesChurn) setFor(attrs []*commonpb.KeyValue) *churnSet {
+	var id strings.Builder
+	for _, kv := range attrs {
+		if c.keys[kv.Key] {
+			id.WriteString(kv.Key)
+			id.WriteByte('=')
+			id.WriteString(kv.Value.GetStringValue())
+			id.WriteByte(0)
+		}
+	}
+	if id.Len() == 0 {
+		return nil
+	}
+	set := c.sets[id.String()]
+	if set == nil {
+		set = &churnSet{interval: -1}
+		c.sets[id.String()] = set
+	}
+	return set
+}
+
+// carriesKey reports whether attrs carry a churn attribute.
+func (c *SeriesChurn) carriesKey(attrs []*commonpb.KeyValue) bool {
+	for _, kv := range attrs {
+		if c.keys[kv.Key] {
+			return true
+		}
+	}
+	return false
+}
+
+// advance decides churn for every interval the dimension set hasn't been
+// seen in, up to the one containing now.
+func (c *SeriesChurn) advance(set *churnSet, now uint64) *churnSet {
+	if !c.started {
+		c.started, c.origin = true, now
+	}
+	elapsed := time.Duration(max(int64(now)-int64(c.origin), 0))
+	interval := int64(elapsed / c.every)
+	if set.interval < 0 {
+		// A dimension set first seen now starts as itself.
+		set.interval = interval
+		return set
+	}
+	for ; set.interval < interval; set.interval++ {
+		if chance(c.pct) {
+			set.generation++
+			set.born = c.origin + uint64(set.interval+1)*uint64(c.every)
+		}
+	}
+	return set
+}
+
+// churnAttributes copies attrs with fresh values for the churn attributes.
+func (c *SeriesChurn) churnAttributes(attrs []*commonpb.KeyValue, generation int) []*commonpb.KeyValue {
+	out := make([]*commonpb.KeyValue, len(attrs))
+	for i, kv := range attrs {
+		out[i] = kv
+		if c.keys[kv.Key] {
+			out[i] = &commonpb.KeyValue{
+				Key:   kv.Key,
+				Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: churnValue(kv.Value.GetStringValue(), generation)}},
+			}
+		}
+	}
+	return out
+}
+
+// podSuffixChars are the characters Kubernetes uses for generated name
+// suffixes.
+const podSuffixChars = "bcdfghjklmnpqrstvwxz2456789"
+
+// churnValue returns value's replacement in the given generation. It is
+// derived from the value, so every series carrying a churned value gets the
+// same replacement: hex IDs and UUIDs get new hex digits, names ending in a
+// short generated suffix (frontend-5d8f9c-x2k4p) get a new suffix, and any
+// other value gets a suffix appended.
+func churnValue(value string, generation int) string {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	h.Write([]byte(strconv.Itoa(generation)))
+	rng := rand.New(rand.NewSource(int64(h.Sum64())))
+
+	if isHexID(value) {
+		out := []byte(value)
+		for i, ch := range out {
+			if ch != '-' {
+				out[i] = "0123456789abcdef"[rng.Intn(16)]
+			}
+		}
+		return string(out)
+	}
+	base, n := value+"-", 5
+	if i := strings.LastIndexByte(value, '-'); i > 0 && len(value)-i-1 >= 1 && len(value)-i-1 <= 10 {
+		base, n = value[:i+1], len(value)-i-1
+	}
+	suffix := make([]byte, n)
+	for i := range suffix {
+		suffix[i] = podSuffixChars[rng.Intn(len(podSuffixChars))]
+	}
+	return base + string(suffix)
+}
+
+// isHexID reports whether value is at least 8 hex digits, optionally
+// dash-separated like a UUID.
+func isHexID(value string) bool {
+	digits := 0
+	for _, ch := range value {
+		switch {
+		case ch >= '0' && ch <= '9', ch >= 'a' && ch <= 'f':
+			digits++
+		case ch == '-':
+		default:
+			return false
+		}
+	}
+	return digits >= 8
+}
@@ -0,0 +1,214 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// podGauge is a gauge with one series for pod.
+func podGauge(name, pod string) *otlpmetrics.Metric {
+	return &otlpmetrics.Metric{Name: name, Data: &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{
+		DataPoints: []*otlpmetrics.NumberDataPoint{{
+			Attributes: []*commonpb.KeyValue{strAttr("k8s.pod.name", pod), strAttr("region", "us-east-1")},
+			Value:      &otlpmetrics.NumberDataPoint_AsDouble{AsDouble: 1},
+		}},
+	}}}
+}
+
+// sendChurn stamps the template point with now and returns this send's pod
+// name, region and counts.
+func sendChurn(c *SeriesChurn, tmpl *otlpmetrics.Metric, now time.Duration) (pod, region string, newSeries, replaced int) {
+	tmpl.GetGauge().DataPoints[0].TimeUnixNano = uint64(now)
+	out := &otlpmetrics.Metric{Name: tmpl.Name, Data: tmpl.Data}
+	newSeries, replaced = c.Apply(out)
+	attrs := out.GetGauge().DataPoints[0].Attributes
+	return attrs[0].Value.GetStringValue(), attrs[1].Value.GetStringValue(), newSeries, replaced
+}
+
+func TestSeriesChurn(t *testing.T) {
+	c := NewSeriesChurn(config.ChurnConfig{Percentage: 100, Every: "10m", Attributes: []string{"k8s.pod.name"}})
+	cpu := podGauge("cpu", "frontend-7d9f8b6c5-x2k4p")
+	mem := podGauge("memory", "frontend-7d9f8b6c5-x2k4p")
+
+	pod, _, newSeries, replaced := sendChurn(c, cpu, 0)
+	if pod != "frontend-7d9f8b6c5-x2k4p" || newSeries != 1 || replaced != 0 {
+		t.Fatalf("first send: pod %q, %d new, %d replaced", pod, newSeries, replaced)
+	}
+	if pod, _, _, replaced = sendChurn(c, cpu, 5*time.Minute); pod != "frontend-7d9f8b6c5-x2k4p" || replaced != 0 {
+		t.Fatalf("within the interval: pod %q, %d replaced", pod, replaced)
+	}
+
+	pod, region, _, replaced := sendChurn(c, cpu, 11*time.Minute)
+	if replaced != 1 || region != "us-east-1" {
+		t.Fatalf("after the interval: %d replaced, region %q", replaced, region)
+	}
+	if !strings.HasPrefix(pod, "frontend-7d9f8b6c5-") || len(pod) != len("frontend-7d9f8b6c5-x2k4p") || pod == "frontend-7d9f8b6c5-x2k4p" {
+		t.Fatalf("churned pod name = %q, want a new suffix", pod)
+	}
+	if got := cpu.GetGauge().DataPoints[0].Attributes[0].Value.GetStringValue(); got != "frontend-7d9f8b6c5-x2k4p" {
+		t.Errorf("template attributes modified to %q", got)
+	}
+
+	// Another metric of the same pod churns with it.
+	memPod, _, newSeries, _ := sendChurn(c, mem, 11*time.Minute)
+	if memPod != pod || newSeries != 1 {
+		t.Errorf("memory series: pod %q, %d new, want %q, 1", memPod, newSeries, pod)
+	}
+}
+
+func TestSeriesChurnResource(t *testing.T) {
+	c := NewSeriesChurn(config.ChurnConfig{Percentage: 100, Every: "10m", Attributes: []string{"k8s.pod.name"}})
+	resource := &resourcepb.Resource{Attributes: []*commonpb.KeyValue{strAttr("k8s.node.name", "node-1"), strAttr("k8s.pod.name", "api-7d9f8b6c5-x2k4p")}}
+	cpu := &otlpmetrics.Metric{Name: "cpu", Data: &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+		AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		DataPoints:             []*otlpmetrics.NumberDataPoint{{StartTimeUnixNano: 1}},
+	}}}
+
+	// send stamps the template point with now and returns this send's
+	// resource, start time and counts.
+	send := func(now time.Duration) (*resourcepb.Resource, uint64, int, int) {
+		cpu.GetSum().DataPoints[0].TimeUnixNano = uint64(now)
+		rm := &otlpmetrics.ResourceMetrics{Resource: resource, ScopeMetrics: []*otlpmetrics.ScopeMetrics{{
+			Metrics: []*otlpmetrics.Metric{{Name: cpu.Name, Data: cpu.Data}},
+		}}}
+		newSeries, replaced := c.ApplyResource(rm)
+		return rm.Resource, rm.ScopeMetrics[0].Metrics[0].GetSum().DataPoints[0].StartTimeUnixNano, newSeries, replaced
+	}
+
+	got, _, newSeries, replaced := send(time.Minute)
+	if got != resource || newSeries != 1 || replaced != 0 {
+		t.Fatalf("first send: resource replaced %v, %d new, %d replaced", got != resource, newSeries, replaced)
+	}
+	got, start, _, replaced := send(12 * time.Minute)
+	if replaced != 1 || got == resource {
+		t.Fatalf("after the interval: %d replaced, resource replaced %v", replaced, got != resource)
+	}
+	if pod := got.Attributes[1].Value.GetStringValue(); !strings.HasPrefix(pod, "api-7d9f8b6c5-") || pod == "api-7d9f8b6c5-x2k4p" {
+		t.Errorf("churned pod name = %q", pod)
+	}
+	if node := got.Attributes[0].Value.GetStringValue(); node != "node-1" {
+		t.Errorf("node churned to %q", node)
+	}
+	if start != uint64(11*time.Minute) {
+		t.Errorf("replaced series starts at %v, want its generation's start", time.Duration(start))
+	}
+	if resource.Attributes[1].Value.GetStringValue() != "api-7d9f8b6c5-x2k4p" {
+		t.Error("template resource modified")
+	}
+}
+
+func TestSeriesChurnResourceCounts(t *testing.T) {
+	c := NewSeriesChurn(config.ChurnConfig{Percentage: 100, Every: "10m", Attributes: []string{"k8s.pod.name"}})
+	resource := &resourcepb.Resource{Attributes: []*commonpb.KeyValue{strAttr("k8s.pod.name", "api-7d9f8b6c5-x2k4p")}}
+	gauge := func(name string, attrs ...[]*commonpb.KeyValue) *otlpmetrics.Metric {
+		points := make([]*otlpmetrics.NumberDataPoint, len(attrs))
+		for i := range attrs {
+			points[i] = &otlpmetrics.NumberDataPoint{Attributes: attrs[i]}
+		}
+		return &otlpmetrics.Metric{Name: name, Data: &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{DataPoints: points}}}
+	}
+	// Two attribute-less series and two told apart by state.
+	metrics := []*otlpmetrics.Metric{
+		gauge("cpu", nil),
+		gauge("uptime", nil),
+		gauge("memory", []*commonpb.KeyValue{strAttr("state", "used")}, []*commonpb.KeyValue{strAttr("state", "free")}),
+	}
+
+	send := func(now time.Duration) (int, int) {
+		for _, metric := range metrics {
+			for _, dp := range metric.GetGauge().DataPoints {
+				dp.TimeUnixNano = uint64(now)
+			}
+		}
+		rm := &otlpmetrics.ResourceMetrics{Resource: resource, ScopeMetrics: []*otlpmetrics.ScopeMetrics{{Metrics: metrics}}}
+		return c.ApplyResource(rm)
+	}
+	if newSeries, replaced := send(time.Minute); newSeries != 4 || replaced != 0 {
+		t.Errorf("first send: %d new, %d replaced, want 4, 0", newSeries, replaced)
+	}
+	if newSeries, replaced := send(2 * time.Minute); newSeries != 0 || replaced != 0 {
+		t.Errorf("resend: %d new, %d replaced, want 0, 0", newSeries, replaced)
+	}
+	if newSeries, replaced := send(12 * time.Minute); newSeries != 0 || replaced != 4 {
+		t.Errorf("after the interval: %d new, %d replaced, want 0, 4", newSeries, replaced)
+	}
+}
+
+func TestSeriesChurnPercentage(t *testing.T) {
+	c := NewSeriesChurn(config.ChurnConfig{Percentage: 0.0001, Every: "1m", Attributes: []string{"k8s.pod.name"}})
+	tmpl := podGauge("cpu", "api-0")
+	sendChurn(c, tmpl, 0)
+	if pod, _, _, replaced := sendChurn(c, tmpl, 5*time.Minute); pod != "api-0" || replaced != 0 {
+		t.Errorf("rarely churned series replaced: %q", pod)
+	}
+
+	if NewSeriesChurn(config.ChurnConfig{}) != nil {
+		t.Error("churn without a percentage should be disabled")
+	}
+}
+
+func TestChurnValue(t *testing.T) {
+	for value, check := range map[string]func(string) bool{
+		"3f2a9c0d1e4b5a6f": func(v string) bool { return len(v) == 16 && isHexID(v) },
+		"123e4567-e89b-12d3-a456-426614174000": func(v string) bool {
+			return len(v) == 36 && isHexID(v) && v[8] == '-'
+		},
+		"worker-3":  func(v string) bool { return strings.HasPrefix(v, "worker-") && len(v) == len("worker-3") },
+		"scheduler": func(v string) bool { return strings.HasPrefix(v, "scheduler-") && len(v) == len("scheduler-")+5 },
+	} {
+		got := churnValue(value, 1)
+		if got == value || !check(got) {
+			t.Errorf("churnValue(%q) = %q", value, got)
+		}
+		if churnValue(value, 1) != got {
+			t.Errorf("churnValue(%q) is not deterministic", value)
+		}
+		if churnValue(value, 2) == got {
+			t.Errorf("churnValue(%q) repeats across generations", value)
+		}
+	}
+}
+
+func TestSeriesChurnRestartsCumulativeSeries(t *testing.T) {
+	c := NewSeriesChurn(config.ChurnConfig{Percentage: 100, Every: "10m", Attributes: []string{"k8s.pod.name"}})
+	s := NewSeriesTracker(config.CumulativeConfig{Enabled: true, Rate: 1})
+	s.UseChurn(c)
+	tmplPoint := &otlpmetrics.NumberDataPoint{
+		Attributes: []*commonpb.KeyValue{strAttr("k8s.pod.name", "api-7d9f8b6c5-x2k4p")},
+		Value:      &otlpmetrics.NumberDataPoint_AsInt{AsInt: 100},
+	}
+	tmpl := &otlpmetrics.Metric{Name: "requests", Data: &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+		AggregationTemporality: cumulative,
+		IsMonotonic:            true,
+		DataPoints:             []*otlpmetrics.NumberDataPoint{tmplPoint},
+	}}}
+
+	// send returns this send's point after the tracker and churn.
+	send := func(now time.Duration) *otlpmetrics.NumberDataPoint {
+		out := &otlpmetrics.Metric{Name: tmpl.Name, Data: tmpl.Data}
+		s.Apply(nil, out, uint64(now))
+		c.Apply(out)
+		return out.GetSum().DataPoints[0]
+	}
+
+	send(0)
+	if dp := send(5 * time.Minute); dp.GetAsInt() != 200 {
+		t.Fatalf("before churn: value %d, want 200", dp.GetAsInt())
+	}
+	dp := send(11 * time.Minute)
+	if dp.Attributes[0].Value.GetStringValue() == "api-7d9f8b6c5-x2k4p" {
+		t.Fatal("series not churned")
+	}
+	if dp.GetAsInt() != 100 || dp.StartTimeUnixNano != uint64(10*time.Minute) {
+		t.Errorf("new pod's series: value %d from %v, want 100 from 10m", dp.GetAsInt(), time.Duration(dp.StartTimeUnixNano))
+	}
+	if dp := send(15 * time.Minute); dp.GetAsInt() != 200 || dp.StartTimeUnixNano != uint64(10*time.Minute) {
+		t.Errorf("new pod's next send: value %d from %v, want 200 from 10m", dp.GetAsInt(), time.Duration(dp.StartTimeUnixNano))
+	}
+}
@@ -0,0 +1,191 @@
+package transformer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlpcollectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	otlpcollectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	otlpcollectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// IdentityRewriter rewrites identifying attributes so that each sender
+// replica presents itself as distinct services or hosts. Identity is fixed for
+// the life of the process, so it rewrites the loaded templates once rather
+// than every replay. A nil *IdentityRewriter rewrites nothing.
+type IdentityRewriter struct {
+	rules    map[string]config.IdentityAttribute
+	replica  string
+	hostname string
+}
+
+// NewIdentityRewriter creates a rewriter for the configured attributes, or
+// returns nil when there are none.
+func NewIdentityRewriter(cfg config.IdentityConfig) (*IdentityRewriter, error) {
+	if len(cfg.Attributes) == 0 {
+		return nil, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname: %w", err)
+	}
+	r := &IdentityRewriter{
+		rules:    make(map[string]config.IdentityAttribute, len(cfg.Attributes)),
+		replica:  cfg.Replica,
+		hostname: hostname,
+	}
+	if r.replica == "" {
+		r.replica = hostname
+	}
+	for _, a := range cfg.Attributes {
+		r.rules[a.Key] = a
+	}
+	return r, nil
+}
+
+// Replica returns the value templates see as {{replica}}.
+func (r *IdentityRewriter) Replica() string {
+	return r.replica
+}
+
+// RewriteTraces rewrites resource and span attributes in a trace template.
+func (r *IdentityRewriter) RewriteTraces(req *otlpcollectortrace.ExportTraceServiceRequest) {
+	if r == nil || req == nil {
+		return
+	}
+	for _, rs := range req.ResourceSpans {
+		service := resourceService(rs.Resource.GetAttributes())
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				// Spans name their own service when a resource covers
+				// several.
+				spanService := service
+				if s := stringAttr(span.Attributes, "service.name"); s != "" {
+					spanService = s
+				}
+				r.rewrite(span.Attributes, spanService)
+			}
+		}
+		if rs.Resource != nil {
+			r.rewrite(rs.Resource.Attributes, service)
+		}
+	}
+}
+
+// RewriteMetrics rewrites resource and data point attributes in a metrics
+// template.
+func (r *IdentityRewriter) RewriteMetrics(req *otlpcollectormetrics.ExportMetricsServiceRequest) {
+	if r == nil || req == nil {
+		return
+	}
+	for _, rm := range req.ResourceMetrics {
+		service := resourceService(rm.Resource.GetAttributes())
+		for _, sm := range rm.ScopeMetrics {
+			for _, metric := range sm.Metrics {
+				switch data := metric.Data.(type) {
+				case *otlpmetrics.Metric_Gauge:
+					for _, dp := range data.Gauge.DataPoints {
+						r.rewrite(dp.Attributes, service)
+					}
+				case *otlpmetrics.Metric_Sum:
+					for _, dp := range data.Sum.DataPoints {
+						r.rewrite(dp.Attributes, service)
+					}
+				case *otlpmetrics.Metric_Histogram:
+					for _, dp := range data.Histogram.DataPoints {
+						r.rewrite(dp.Attributes, service)
+					}
+				case *otlpmetrics.Metric_ExponentialHistogram:
+					for _, dp := range data.ExponentialHistogram.DataPoints {
+						r.rewrite(dp.Attributes, service)
+					}
+				case *otlpmetrics.Metric_Summary:
+					for _, dp := range data.Summary.DataPoints {
+						r.rewrite(dp.Attributes, service)
+					}
+				}
+			}
+		}
+		if rm.Resource != nil {
+			r.rewrite(rm.Resource.Attributes, service)
+		}
+	}
+}
+
+// RewriteLogs rewrites resource and log record attributes in a logs template.
+func (r *IdentityRewriter) RewriteLogs(req *otlpcollectorlogs.ExportLogsServiceRequest) {
+	if r == nil || req == nil {
+		return
+	}
+	for _, rl := range req.ResourceLogs {
+		service := resourceService(rl.Resource.GetAttributes())
+		for _, sl := range rl.ScopeLogs {
+			for _, lr := range sl.LogRecords {
+				r.rewrite(lr.Attributes, service)
+			}
+		}
+		if rl.Resource != nil {
+			r.rewrite(rl.Resource.Attributes, service)
+		}
+	}
+}
+
+// rewrite renders new values for the configured string attributes in place.
+// service is the original service.name of the span or resource.
+func (r *IdentityRewriter) rewrite(attrs []*commonpb.KeyValue, service string) {
+	for _, kv := range attrs {
+		rule, ok := r.rules[kv.Key]
+		if !ok {
+			continue
+		}
+		sv, ok := kv.Value.GetValue().(*commonpb.AnyValue_StringValue)
+		if !ok {
+			continue
+		}
+		tmpl, ok := rule.Map[sv.StringValue]
+		if !ok {
+			tmpl = rule.Template
+		}
+		if tmpl == "" {
+			continue
+		}
+		kv.Value = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{
+			StringValue: r.render(tmpl, sv.StringValue, service),
+		}}
+	}
+}
+
+// render fills in an identity template's placeholders.
+func (r *IdentityRewriter) render(tmpl, value, service string) string {
+	return config.IdentityPlaceholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		switch config.IdentityPlaceholder.FindStringSubmatch(m)[1] {
+		case "value":
+			return value
+		case "service":
+			return service
+		case "replica":
+			return r.replica
+		case "hostname":
+			return r.hostname
+		}
+		return m
+	})
+}
+
+// resourceService returns the service.name among resource attributes.
+func resourceService(attrs []*commonpb.KeyValue) string {
+	return stringAttr(attrs, "service.name")
+}
+
+// stringAttr returns the string value of key, or "".
+func stringAttr(attrs []*commonpb.KeyValue, key string) string {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value.GetStringValue()
+		}
+	}
+	return ""
+}
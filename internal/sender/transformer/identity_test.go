@@ -0,0 +1,106 @@
+package transformer
+
+import (
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlpcollectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	otlpcollectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	otlpcollectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlplogs "go.opentelemetry.io/proto/otlp/logs/v1"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func strKVs(kvs ...string) []*commonpb.KeyValue {
+	var attrs []*commonpb.KeyValue
+	for i := 0; i < len(kvs); i += 2 {
+		attrs = append(attrs, &commonpb.KeyValue{Key: kvs[i], Value: stringValue(kvs[i+1])})
+	}
+	return attrs
+}
+
+func TestIdentityRewriter(t *testing.T) {
+	r, err := NewIdentityRewriter(config.IdentityConfig{
+		Replica: "7",
+		Attributes: []config.IdentityAttribute{
+			{Key: "service.name", Template: "{{value}}-{{replica}}", Map: map[string]string{"api-gateway": "edge-{{ replica }}"}},
+			{Key: "host.name", Template: "{{service}}.{{replica}}"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traces := &otlpcollectortrace.ExportTraceServiceRequest{ResourceSpans: []*otlptrace.ResourceSpans{{
+		Resource: &resourcepb.Resource{Attributes: strKVs("service.name", "api-gateway", "host.name", "node-1")},
+		ScopeSpans: []*otlptrace.ScopeSpans{{Spans: []*otlptrace.Span{
+			{Attributes: strKVs("service.name", "api-gateway")},
+			{Attributes: strKVs("service.name", "order-service", "host.name", "node-2")},
+		}}},
+	}}}
+	r.RewriteTraces(traces)
+
+	rs := traces.ResourceSpans[0]
+	spans := rs.ScopeSpans[0].Spans
+	for _, c := range []struct {
+		attrs     []*commonpb.KeyValue
+		key, want string
+	}{
+		{rs.Resource.Attributes, "service.name", "edge-7"},
+		{rs.Resource.Attributes, "host.name", "api-gateway.7"},
+		{spans[0].Attributes, "service.name", "edge-7"},
+		{spans[1].Attributes, "service.name", "order-service-7"},
+		{spans[1].Attributes, "host.name", "order-service.7"},
+	} {
+		if got := stringAttr(c.attrs, c.key); got != c.want {
+			t.Errorf("%s = %q, want %q", c.key, got, c.want)
+		}
+	}
+
+	metrics := &otlpcollectormetrics.ExportMetricsServiceRequest{ResourceMetrics: []*otlpmetrics.ResourceMetrics{{
+		Resource: &resourcepb.Resource{Attributes: strKVs("service.name", "collector")},
+		ScopeMetrics: []*otlpmetrics.ScopeMetrics{{Metrics: []*otlpmetrics.Metric{{
+			Data: &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{DataPoints: []*otlpmetrics.NumberDataPoint{
+				{Attributes: strKVs("host.name", "node-1")},
+			}}},
+		}}}},
+	}}}
+	r.RewriteMetrics(metrics)
+	dp := metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].GetGauge().DataPoints[0]
+	if got := stringAttr(dp.Attributes, "host.name"); got != "collector.7" {
+		t.Errorf("data point host.name = %q, want collector.7", got)
+	}
+
+	logs := &otlpcollectorlogs.ExportLogsServiceRequest{ResourceLogs: []*otlplogs.ResourceLogs{{
+		Resource:  &resourcepb.Resource{Attributes: strKVs("service.name", "billing")},
+		ScopeLogs: []*otlplogs.ScopeLogs{{LogRecords: []*otlplogs.LogRecord{{Attributes: strKVs("user", "u1")}}}},
+	}}}
+	r.RewriteLogs(logs)
+	if got := stringAttr(logs.ResourceLogs[0].Resource.Attributes, "service.name"); got != "billing-7" {
+		t.Errorf("log resource service.name = %q, want billing-7", got)
+	}
+	if got := stringAttr(logs.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Attributes, "user"); got != "u1" {
+		t.Errorf("unconfigured attribute rewritten to %q", got)
+	}
+}
+
+func TestIdentityRewriterDefaults(t *testing.T) {
+	r, err := NewIdentityRewriter(config.IdentityConfig{})
+	if err != nil || r != nil {
+		t.Fatalf("NewIdentityRewriter without attributes = %v, %v; want nil, nil", r, err)
+	}
+	r.RewriteTraces(&otlpcollectortrace.ExportTraceServiceRequest{}) // nil-safe
+
+	r, err = NewIdentityRewriter(config.IdentityConfig{
+		Attributes: []config.IdentityAttribute{{Key: "service.name", Template: "{{value}}@{{replica}}"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Replica() == "" || r.Replica() != r.hostname {
+		t.Errorf("replica = %q, want the hostname %q", r.Replica(), r.hostname)
+	}
+}
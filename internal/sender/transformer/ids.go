@@ -1,7 +1,8 @@
 package transformer
 
 import (
-	"crypto/rand"
+	"encoding/binary"
+	"hash/maphash"
 
 	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
 )
@@ -14,55 +15,84 @@ func NewIDRegenerator() *IDRegenerator {
 	return &IDRegenerator{}
 }
 
+// Replay maps template IDs to fresh IDs for one pass over the templates.
+// Within a replay the same template ID always maps to the same new ID, so
+// span links to other traces still resolve after every trace is regenerated;
+// a new replay gives every trace new IDs.
+type Replay struct {
+	traceSeed maphash.Seed
+	spanSeed  maphash.Seed
+}
+
+// NewReplay starts a new pass over the templates.
+func (r *IDRegenerator) NewReplay() *Replay {
+	return &Replay{traceSeed: maphash.MakeSeed(), spanSeed: maphash.MakeSeed()}
+}
+
 // RegenerateTraceIDs regenerates IDs for all spans in a trace
 // This preserves parent-child relationships while ensuring uniqueness
 func (r *IDRegenerator) RegenerateTraceIDs(spans []*otlptrace.Span) {
+	r.NewReplay().RegenerateTraceIDs(spans)
+}
+
+// RegenerateTraceIDs regenerates IDs for all spans in a trace, remapping
+// parents within the trace and span links into any trace of this replay.
+func (rp *Replay) RegenerateTraceIDs(spans []*otlptrace.Span) {
 	if len(spans) == 0 {
 		return
 	}
 
-	// Generate new trace ID for the entire trace
-	newTraceID := generateTraceID()
+	oldTraceID := spans[0].TraceId
+	newTraceID := rp.TraceID(oldTraceID)
 
-	// Map old span IDs to new span IDs
-	idMap := make(map[string][]byte)
-
-	// First pass: generate new span IDs for all spans
+	// Only parents inside this trace are remapped; a phantom parent (one
+	// never emitted) is kept verbatim.
+	inTrace := make(map[string]bool, len(spans))
 	for _, span := range spans {
-		oldSpanID := string(span.SpanId)
-		newSpanID := generateSpanID()
-		idMap[oldSpanID] = newSpanID
+		inTrace[string(span.SpanId)] = true
 	}
 
-	// Second pass: update trace IDs, span IDs, and parent span IDs
 	for _, span := range spans {
-		// Update trace ID
 		span.TraceId = newTraceID
+		span.SpanId = rp.SpanID(oldTraceID, span.SpanId)
 
-		// Update span ID
-		oldSpanID := string(span.SpanId)
-		span.SpanId = idMap[oldSpanID]
+		if len(span.ParentSpanId) > 0 && inTrace[string(span.ParentSpanId)] {
+			span.ParentSpanId = rp.SpanID(oldTraceID, span.ParentSpanId)
+		}
 
-		// Update parent span ID if it exists
-		if len(span.ParentSpanId) > 0 {
-			oldParentID := string(span.ParentSpanId)
-			if newParentID, ok := idMap[oldParentID]; ok {
-				span.ParentSpanId = newParentID
+		if len(span.Links) > 0 {
+			// Links are shared with the template, so replace rather than
+			// modify them.
+			links := make([]*otlptrace.Span_Link, len(span.Links))
+			for i, l := range span.Links {
+				links[i] = &otlptrace.Span_Link{
+					TraceId:                rp.TraceID(l.TraceId),
+					SpanId:                 rp.SpanID(l.TraceId, l.SpanId),
+					TraceState:             l.TraceState,
+					Attributes:             l.Attributes,
+					DroppedAttributesCount: l.DroppedAttributesCount,
+					Flags:                  l.Flags,
+				}
 			}
+			span.Links = links
 		}
 	}
 }
 
-// generateTraceID generates a random 16-byte trace ID
-func generateTraceID() []byte {
+// TraceID returns the 16-byte ID this replay gives a template trace ID.
+func (rp *Replay) TraceID(old []byte) []byte {
 	id := make([]byte, 16)
-	rand.Read(id)
+	binary.LittleEndian.PutUint64(id[:8], maphash.Bytes(rp.traceSeed, old))
+	binary.LittleEndian.PutUint64(id[8:], maphash.Bytes(rp.spanSeed, old))
 	return id
 }
 
-// generateSpanID generates a random 8-byte span ID
-func generateSpanID() []byte {
+// SpanID returns the 8-byte ID this replay gives a template span. Span IDs are
+// keyed by trace too, since templates only guarantee uniqueness per trace.
+func (rp *Replay) SpanID(oldTraceID, oldSpanID []byte) []byte {
+	key := make([]byte, 0, len(oldTraceID)+len(oldSpanID))
+	key = append(append(key, oldTraceID...), oldSpanID...)
 	id := make([]byte, 8)
-	rand.Read(id)
+	binary.LittleEndian.PutUint64(id, maphash.Bytes(rp.spanSeed, key))
 	return id
 }
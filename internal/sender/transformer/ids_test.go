@@ -0,0 +1,56 @@
+package transformer
+
+import (
+	"bytes"
+	"testing"
+
+	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func span(traceID, spanID, parentID byte, links ...*otlptrace.Span_Link) *otlptrace.Span {
+	s := &otlptrace.Span{
+		TraceId: bytes.Repeat([]byte{traceID}, 16),
+		SpanId:  bytes.Repeat([]byte{spanID}, 8),
+		Links:   links,
+	}
+	if parentID != 0 {
+		s.ParentSpanId = bytes.Repeat([]byte{parentID}, 8)
+	}
+	return s
+}
+
+// TestReplayRemapsLinks verifies a link into another trace follows that
+// trace's new IDs within a replay, without modifying the template link.
+func TestReplayRemapsLinks(t *testing.T) {
+	producer := []*otlptrace.Span{span(1, 1, 0), span(1, 2, 1)}
+	tmplLink := &otlptrace.Span_Link{TraceId: producer[1].TraceId, SpanId: producer[1].SpanId}
+	consumer := []*otlptrace.Span{span(2, 1, 9, tmplLink)}
+
+	replay := NewIDRegenerator().NewReplay()
+	replay.RegenerateTraceIDs(producer)
+	replay.RegenerateTraceIDs(consumer)
+
+	link := consumer[0].Links[0]
+	if !bytes.Equal(link.TraceId, producer[1].TraceId) || !bytes.Equal(link.SpanId, producer[1].SpanId) {
+		t.Fatalf("link %x/%x does not target producer span %x/%x", link.TraceId, link.SpanId, producer[1].TraceId, producer[1].SpanId)
+	}
+	if tmplLink.TraceId[0] != 1 || tmplLink.SpanId[0] != 2 {
+		t.Fatalf("template link was modified")
+	}
+	if !bytes.Equal(producer[1].ParentSpanId, producer[0].SpanId) {
+		t.Fatalf("parent not remapped")
+	}
+	if !bytes.Equal(consumer[0].ParentSpanId, bytes.Repeat([]byte{9}, 8)) {
+		t.Fatalf("phantom parent changed to %x", consumer[0].ParentSpanId)
+	}
+	// Same span ID in different traces must not collide.
+	if bytes.Equal(consumer[0].SpanId, producer[0].SpanId) {
+		t.Fatalf("span IDs collide across traces")
+	}
+
+	again := []*otlptrace.Span{span(1, 1, 0)}
+	NewIDRegenerator().NewReplay().RegenerateTraceIDs(again)
+	if bytes.Equal(again[0].TraceId, producer[0].TraceId) {
+		t.Fatalf("separate replays produced the same trace ID")
+	}
+}
@@ -0,0 +1,215 @@
+package transformer
+
+import (
+	"math"
+	"math/rand"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// ValuePatterns evaluates per-series value functions at send time, so gauges
+// follow diurnal cycles, trends, random walks, noise and scheduled anomalies
+// instead of replaying their template value. Time is the send time the
+// timestamp injector picks, so patterns follow the backfill clock too. A nil
+// *ValuePatterns changes nothing.
+type ValuePatterns struct {
+	patterns []*valuePattern
+
+	mu      sync.Mutex
+	started bool
+	origin  uint64                   // first send, in Unix nanoseconds
+	matches map[string]*valuePattern // metric name → first matching pattern
+	series  map[*otlpmetrics.NumberDataPoint]*patternSeries
+}
+
+type valuePattern struct {
+	config.ValuePattern
+	period, peak time.Duration
+	anomalies    []valueAnomaly
+}
+
+type valueAnomaly struct {
+	kind                   string
+	start, duration, every time.Duration
+	magnitude              float64
+}
+
+// patternSeries is one series' state. pattern is nil when the series was not
+// selected.
+type patternSeries struct {
+	pattern *valuePattern
+	walk    float64
+	held    []float64 // per anomaly: value held by an active flatline, or NaN
+}
+
+// NewValuePatterns creates the pattern evaluator, or returns nil when no
+// pattern is configured.
+func NewValuePatterns(patterns []config.ValuePattern) *ValuePatterns {
+	if len(patterns) == 0 {
+		return nil
+	}
+	v := &ValuePatterns{
+		matches: make(map[string]*valuePattern),
+		series:  make(map[*otlpmetrics.NumberDataPoint]*patternSeries),
+	}
+	for _, p := range patterns {
+		vp := &valuePattern{
+			ValuePattern: p,
+			period:       p.Diurnal.GetPeriod(),
+			peak:         p.Diurnal.GetPeak(),
+		}
+		for _, a := range p.Anomalies {
+			vp.anomalies = append(vp.anomalies, valueAnomaly{
+				kind:      a.Type,
+				start:     a.GetStart(),
+				duration:  a.GetDuration(),
+				every:     a.GetEvery(),
+				magnitude: a.Magnitude,
+			})
+		}
+		v.patterns = append(v.patterns, vp)
+	}
+	return v
+}
+
+// Apply replaces a gauge's data with this send's values, sent at now.
+// metric.Data must still hold the template points, which are shared by every
+// send and never modified.
+func (v *ValuePatterns) Apply(metric *otlpmetrics.Metric, now uint64) {
+	if v == nil {
+		return
+	}
+	gauge, ok := metric.Data.(*otlpmetrics.Metric_Gauge)
+	if !ok {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	pattern := v.match(metric.Name)
+	if pattern == nil {
+		return
+	}
+	out := &otlpmetrics.Gauge{DataPoints: make([]*otlpmetrics.NumberDataPoint, len(gauge.Gauge.DataPoints))}
+	for i, dp := range gauge.Gauge.DataPoints {
+		out.DataPoints[i] = v.next(pattern, dp, now)
+	}
+	metric.Data = &otlpmetrics.Metric_Gauge{Gauge: out}
+}
+
+// match returns the first pattern whose globs match name, or nil.
+func (v *ValuePatterns) match(name string) *valuePattern {
+	if p, ok := v.matches[name]; ok {
+		return p
+	}
+	var match *valuePattern
+	for _, p := range v.patterns {
+		if len(p.Metrics) == 0 {
+			match = p
+			break
+		}
+		for _, glob := range p.Metrics {
+			if ok, _ := path.Match(glob, name); ok {
+				match = p
+				break
+			}
+		}
+		if match != nil {
+			break
+		}
+	}
+	v.matches[name] = match
+	return match
+}
+
+// next returns this send's point for a gauge series.
+func (v *ValuePatterns) next(pattern *valuePattern, tmpl *otlpmetrics.NumberDataPoint, now uint64) *otlpmetrics.NumberDataPoint {
+	st, ok := v.series[tmpl]
+	if !ok {
+		st = &patternSeries{}
+		if chance(pattern.Percentage) {
+			st.pattern = pattern
+			st.held = make([]float64, len(pattern.anomalies))
+			for i := range st.held {
+				st.held[i] = math.NaN()
+			}
+		}
+		v.series[tmpl] = st
+	}
+	if st.pattern == nil {
+		return cloneNumberPoint(tmpl, tmpl.StartTimeUnixNano, now)
+	}
+
+	if !v.started {
+		v.started, v.origin = true, now
+	}
+	elapsed := time.Duration(max(int64(now)-int64(v.origin), 0))
+
+	base := tmpl.GetAsDouble()
+	if _, ok := tmpl.Value.(*otlpmetrics.NumberDataPoint_AsInt); ok {
+		base = float64(tmpl.GetAsInt())
+	}
+	value := base * max(st.factor(now, elapsed), 0)
+
+	for i, a := range pattern.anomalies {
+		if !a.active(elapsed) {
+			st.held[i] = math.NaN()
+			continue
+		}
+		switch a.kind {
+		case "spike":
+			value *= a.magnitude
+		case "step":
+			value += a.magnitude * base
+		case "flatline":
+			if math.IsNaN(st.held[i]) {
+				st.held[i] = value
+			}
+			value = st.held[i]
+		}
+	}
+
+	dp := cloneNumberPoint(tmpl, tmpl.StartTimeUnixNano, now)
+	if _, ok := tmpl.Value.(*otlpmetrics.NumberDataPoint_AsInt); ok {
+		dp.Value = &otlpmetrics.NumberDataPoint_AsInt{AsInt: int64(math.Round(value))}
+	} else {
+		dp.Value = &otlpmetrics.NumberDataPoint_AsDouble{AsDouble: value}
+	}
+	return dp
+}
+
+// factor is the multiple of the template value the series' continuous
+// components give at time now, elapsed after the first send.
+func (s *patternSeries) factor(now uint64, elapsed time.Duration) float64 {
+	p := s.pattern
+	f := 1.0
+	if p.Diurnal.Amplitude > 0 && p.period > 0 {
+		offset := time.Duration(now%uint64(p.period)) - p.peak
+		f += p.Diurnal.Amplitude * math.Cos(2*math.Pi*float64(offset)/float64(p.period))
+	}
+	f += p.Trend.PerHour * elapsed.Hours()
+	if p.RandomWalk.Step > 0 {
+		s.walk += rand.NormFloat64() * p.RandomWalk.Step
+	}
+	f += s.walk
+	if p.Noise.StdDev > 0 {
+		f += rand.NormFloat64() * p.Noise.StdDev
+	}
+	return f
+}
+
+// active reports whether the anomaly applies elapsed after the first send.
+func (a valueAnomaly) active(elapsed time.Duration) bool {
+	if elapsed < a.start {
+		return false
+	}
+	since := elapsed - a.start
+	if a.every > 0 {
+		since %= a.every
+	}
+	return a.duration == 0 || since < a.duration
+}
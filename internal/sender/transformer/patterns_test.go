@@ -0,0 +1,97 @@
+package transformer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+func gaugeTemplate(name string, value float64) *otlpmetrics.Metric {
+	return &otlpmetrics.Metric{Name: name, Data: &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{
+		DataPoints: []*otlpmetrics.NumberDataPoint{{Value: &otlpmetrics.NumberDataPoint_AsDouble{AsDouble: value}}},
+	}}}
+}
+
+// sendGauge returns the value of a send at now.
+func sendGauge(v *ValuePatterns, tmpl *otlpmetrics.Metric, now time.Duration) float64 {
+	out := &otlpmetrics.Metric{Name: tmpl.Name, Data: tmpl.Data}
+	v.Apply(out, uint64(now))
+	return out.GetGauge().DataPoints[0].GetAsDouble()
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestValuePatternsDiurnalAndTrend(t *testing.T) {
+	v := NewValuePatterns([]config.ValuePattern{{
+		Percentage: 100,
+		Diurnal:    config.DiurnalPattern{Amplitude: 0.5, Period: "24h", Peak: "12h"},
+		Trend:      config.TrendPattern{PerHour: 0.1},
+	}})
+	tmpl := gaugeTemplate("cpu", 100)
+
+	day := 1000 * 24 * time.Hour
+	if got := sendGauge(v, tmpl, day+12*time.Hour); !near(got, 150) {
+		t.Errorf("at peak: %v, want 150", got)
+	}
+	// Six hours later the cycle is at its midpoint and the trend adds 60%.
+	if got := sendGauge(v, tmpl, day+18*time.Hour); !near(got, 160) {
+		t.Errorf("six hours on: %v, want 160", got)
+	}
+	if got := tmpl.GetGauge().DataPoints[0].GetAsDouble(); got != 100 {
+		t.Errorf("template point modified to %v", got)
+	}
+}
+
+func TestValuePatternsAnomalies(t *testing.T) {
+	v := NewValuePatterns([]config.ValuePattern{{
+		Percentage: 100,
+		Trend:      config.TrendPattern{PerHour: 1},
+		Anomalies: []config.ValueAnomaly{
+			{Type: "spike", Start: "1h", Duration: "10m", Every: "2h", Magnitude: 3},
+			{Type: "flatline", Start: "4h30m", Duration: "1h"},
+		},
+	}})
+	tmpl := gaugeTemplate("latency", 10)
+
+	for _, tt := range []struct {
+		at   time.Duration
+		want float64
+	}{
+		{0, 10},
+		{time.Hour + 5*time.Minute, 10 * (1 + 65.0/60) * 3},  // spike
+		{time.Hour + 20*time.Minute, 10 * (1 + 80.0/60)},     // spike over
+		{3*time.Hour + time.Minute, 10 * (1 + 181.0/60) * 3}, // repeats every 2h
+		{4*time.Hour + 30*time.Minute, 55},                   // flatline begins
+		{5 * time.Hour, 55},                                  // held
+		{6 * time.Hour, 70},                                  // released
+	} {
+		if got := sendGauge(v, tmpl, tt.at); !near(got, tt.want) {
+			t.Errorf("at %v: %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestValuePatternsSelection(t *testing.T) {
+	v := NewValuePatterns([]config.ValuePattern{
+		{Metrics: []string{"http.*"}, Percentage: 100, Anomalies: []config.ValueAnomaly{{Type: "step", Magnitude: -0.5}}},
+		{Metrics: []string{"disk.*"}, Percentage: 0, Anomalies: []config.ValueAnomaly{{Type: "spike", Magnitude: 2}}},
+	})
+
+	if got := sendGauge(v, gaugeTemplate("http.latency", 10), time.Hour); !near(got, 5) {
+		t.Errorf("matched series: %v, want 5", got)
+	}
+	if got := sendGauge(v, gaugeTemplate("disk.usage", 10), time.Hour); got != 10 {
+		t.Errorf("unselected series: %v, want 10", got)
+	}
+
+	other := gaugeTemplate("cpu", 10)
+	data := other.Data
+	out := &otlpmetrics.Metric{Name: other.Name, Data: data}
+	v.Apply(out, 0)
+	if out.Data != data {
+		t.Error("unmatched metric replaced")
+	}
+}
@@ -0,0 +1,331 @@
+package transformer
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// SeriesTracker keeps per-series state across sends. Delta sums and
+// histograms get windows that start at the series' previous send. With
+// cumulative tracking enabled, cumulative monotonic sums and histograms grow
+// from one send to the next and keep a stable start time instead of replaying
+// the template value. Series are keyed by their template data point, which
+// stays fixed for the life of the sender. With UseChurn, a cumulative series
+// whose pod or container churn replaces restarts from its template value at
+// the start of the new generation, like the new series it becomes. A nil
+// *SeriesTracker changes nothing.
+type SeriesTracker struct {
+	cumulative bool
+	rate       float64
+	resetPct   float64
+
+	churn *SeriesChurn
+
+	mu         sync.Mutex
+	sums       map[*otlpmetrics.NumberDataPoint]*sumState
+	histograms map[*otlpmetrics.HistogramDataPoint]*histogramState
+	deltas     map[any]uint64 // template point → previous send time
+	interval   uint64         // latest gap between two sends of a delta series
+}
+
+// defaultDeltaInterval is the first window of delta series sent before any
+// series has been sent twice: the OpenTelemetry SDK's default export
+// interval.
+const defaultDeltaInterval = 60 * time.Second
+
+type sumState struct {
+	start uint64
+	value float64
+}
+
+type histogramState struct {
+	start   uint64
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+// NewSeriesTracker creates a tracker. Delta series are always tracked;
+// cumulative ones only when cfg is enabled.
+func NewSeriesTracker(cfg config.CumulativeConfig) *SeriesTracker {
+	return &SeriesTracker{
+		cumulative: cfg.Enabled,
+		rate:       cfg.Rate,
+		resetPct:   cfg.ResetPercentage,
+		sums:       make(map[*otlpmetrics.NumberDataPoint]*sumState),
+		histograms: make(map[*otlpmetrics.HistogramDataPoint]*histogramState),
+		deltas:     make(map[any]uint64),
+		interval:   uint64(defaultDeltaInterval),
+	}
+}
+
+// UseChurn restarts cumulative series when c starts a new generation of
+// them.
+func (s *SeriesTracker) UseChurn(c *SeriesChurn) {
+	if s != nil {
+		s.churn = c
+	}
+}
+
+// Apply replaces a tracked sum's or histogram's data with new points sent at
+// now. metric.Data must still hold the template points, which are shared by
+// every send and never modified. resource is the metric's template resource,
+// which churn may replace (nil for none).
+func (s *SeriesTracker) Apply(resource *resourcepb.Resource, metric *otlpmetrics.Metric, now uint64) {
+	if s == nil {
+		return
+	}
+	switch data := metric.Data.(type) {
+	case *otlpmetrics.Metric_Sum:
+		delta := data.Sum.AggregationTemporality == otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+		if !delta && !s.cumulative {
+			return
+		}
+		sum := &otlpmetrics.Sum{
+			AggregationTemporality: data.Sum.AggregationTemporality,
+			IsMonotonic:            data.Sum.IsMonotonic,
+			DataPoints:             make([]*otlpmetrics.NumberDataPoint, len(data.Sum.DataPoints)),
+		}
+		s.mu.Lock()
+		for i, dp := range data.Sum.DataPoints {
+			if delta {
+				sum.DataPoints[i] = cloneNumberPoint(dp, s.deltaStart(dp, now), now)
+			} else {
+				sum.DataPoints[i] = s.nextSum(dp, data.Sum.IsMonotonic, s.churn.Born(resource, dp.Attributes, now), now)
+			}
+		}
+		s.mu.Unlock()
+		metric.Data = &otlpmetrics.Metric_Sum{Sum: sum}
+
+	case *otlpmetrics.Metric_Histogram:
+		delta := data.Histogram.AggregationTemporality == otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+		if !delta && !s.cumulative {
+			return
+		}
+		hist := &otlpmetrics.Histogram{
+			AggregationTemporality: data.Histogram.AggregationTemporality,
+			DataPoints:             make([]*otlpmetrics.HistogramDataPoint, len(data.Histogram.DataPoints)),
+		}
+		s.mu.Lock()
+		for i, dp := range data.Histogram.DataPoints {
+			if delta {
+				hist.DataPoints[i] = cloneHistogramPoint(dp, s.deltaStart(dp, now), now)
+			} else {
+				hist.DataPoints[i] = s.nextHistogram(dp, s.churn.Born(resource, dp.Attributes, now), now)
+			}
+		}
+		s.mu.Unlock()
+		metric.Data = &otlpmetrics.Metric_Histogram{Histogram: hist}
+
+	case *otlpmetrics.Metric_ExponentialHistogram:
+		// Only delta windows are tracked; cumulative exponential
+		// histograms replay their template counts.
+		if data.ExponentialHistogram.AggregationTemporality != otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+			return
+		}
+		hist := &otlpmetrics.ExponentialHistogram{
+			AggregationTemporality: data.ExponentialHistogram.AggregationTemporality,
+			DataPoints:             make([]*otlpmetrics.ExponentialHistogramDataPoint, len(data.ExponentialHistogram.DataPoints)),
+		}
+		s.mu.Lock()
+		for i, dp := range data.ExponentialHistogram.DataPoints {
+			hist.DataPoints[i] = cloneExponentialPoint(dp, s.deltaStart(dp, now), now)
+		}
+		s.mu.Unlock()
+		metric.Data = &otlpmetrics.Metric_ExponentialHistogram{ExponentialHistogram: hist}
+	}
+}
+
+// deltaStart returns the start of a delta series' window ending at now: its
+// previous send, or one send interval before now on the first send, as if
+// the series had been reporting all along.
+func (s *SeriesTracker) deltaStart(tmpl any, now uint64) uint64 {
+	start, ok := s.deltas[tmpl]
+	switch {
+	case !ok:
+		start = now - min(now, s.interval)
+	case start > now:
+		// Concurrent workers can send a series out of order; never let
+		// a window end before it starts.
+		start = now
+	case start < now:
+		s.interval = now - start
+	}
+	s.deltas[tmpl] = max(now, s.deltas[tmpl])
+	return start
+}
+
+// nextSum returns this send's point for a sum series. A series starts at its
+// template value; a monotonic one then grows by rate × the template value per
+// send, and a non-monotonic one keeps the template value. A series that
+// started before born, its churn generation, starts over at born.
+func (s *SeriesTracker) nextSum(tmpl *otlpmetrics.NumberDataPoint, monotonic bool, born, now uint64) *otlpmetrics.NumberDataPoint {
+	base := tmpl.GetAsDouble()
+	if _, ok := tmpl.Value.(*otlpmetrics.NumberDataPoint_AsInt); ok {
+		base = float64(tmpl.GetAsInt())
+	}
+
+	st, ok := s.sums[tmpl]
+	switch {
+	case !ok:
+		st = &sumState{start: now, value: base}
+		s.sums[tmpl] = st
+	case st.start < born:
+		st.start, st.value = born, base
+	case !monotonic:
+	case s.reset():
+		st.start, st.value = now, 0
+	default:
+		st.value += base * s.rate
+	}
+
+	dp := cloneNumberPoint(tmpl, st.start, now)
+	if _, ok := tmpl.Value.(*otlpmetrics.NumberDataPoint_AsInt); ok {
+		dp.Value = &otlpmetrics.NumberDataPoint_AsInt{AsInt: int64(math.Floor(st.value))}
+	} else {
+		dp.Value = &otlpmetrics.NumberDataPoint_AsDouble{AsDouble: st.value}
+	}
+	return dp
+}
+
+// cloneNumberPoint copies a template point with the given start and time.
+func cloneNumberPoint(tmpl *otlpmetrics.NumberDataPoint, start, now uint64) *otlpmetrics.NumberDataPoint {
+	return &otlpmetrics.NumberDataPoint{
+		Attributes:        tmpl.Attributes, // Attributes are immutable
+		StartTimeUnixNano: start,
+		TimeUnixNano:      now,
+		Value:             tmpl.Value,
+		Exemplars:         tmpl.Exemplars,
+		Flags:             tmpl.Flags,
+	}
+}
+
+// cloneExponentialPoint copies a template point with the given start and
+// time.
+func cloneExponentialPoint(tmpl *otlpmetrics.ExponentialHistogramDataPoint, start, now uint64) *otlpmetrics.ExponentialHistogramDataPoint {
+	return &otlpmetrics.ExponentialHistogramDataPoint{
+		Attributes:        tmpl.Attributes, // Attributes are immutable
+		StartTimeUnixNano: start,
+		TimeUnixNano:      now,
+		Count:             tmpl.Count,
+		Sum:               tmpl.Sum,
+		Scale:             tmpl.Scale,
+		ZeroCount:         tmpl.ZeroCount,
+		Positive:          tmpl.Positive,
+		Negative:          tmpl.Negative,
+		Flags:             tmpl.Flags,
+		Exemplars:         tmpl.Exemplars,
+		Min:               tmpl.Min,
+		Max:               tmpl.Max,
+		ZeroThreshold:     tmpl.ZeroThreshold,
+	}
+}
+
+// cloneHistogramPoint copies a template point with the given start and time.
+func cloneHistogramPoint(tmpl *otlpmetrics.HistogramDataPoint, start, now uint64) *otlpmetrics.HistogramDataPoint {
+	return &otlpmetrics.HistogramDataPoint{
+		Attributes:        tmpl.Attributes, // Attributes are immutable
+		StartTimeUnixNano: start,
+		TimeUnixNano:      now,
+		Count:             tmpl.Count,
+		Sum:               tmpl.Sum,
+		BucketCounts:      tmpl.BucketCounts,
+		ExplicitBounds:    tmpl.ExplicitBounds,
+		Exemplars:         tmpl.Exemplars,
+		Flags:             tmpl.Flags,
+		Min:               tmpl.Min,
+		Max:               tmpl.Max,
+	}
+}
+
+// cloneSummaryPoint copies a template point with the given start and time.
+func cloneSummaryPoint(tmpl *otlpmetrics.SummaryDataPoint, start, now uint64) *otlpmetrics.SummaryDataPoint {
+	return &otlpmetrics.SummaryDataPoint{
+		Attributes:        tmpl.Attributes, // Attributes are immutable
+		StartTimeUnixNano: start,
+		TimeUnixNano:      now,
+		Count:             tmpl.Count,
+		Sum:               tmpl.Sum,
+		QuantileValues:    tmpl.QuantileValues,
+		Flags:             tmpl.Flags,
+	}
+}
+
+// nextHistogram returns this send's point for a histogram series. A series
+// starts at its template counts and then adds rate × each template bucket
+// count per send, with the sum growing at the template's mean. A series
+// that started before born, its churn generation, starts over at born.
+func (s *SeriesTracker) nextHistogram(tmpl *otlpmetrics.HistogramDataPoint, born, now uint64) *otlpmetrics.HistogramDataPoint {
+	st, ok := s.histograms[tmpl]
+	switch {
+	case !ok, st.start < born:
+		start := now
+		if ok {
+			start = born
+		}
+		st = &histogramState{
+			start:   start,
+			count:   tmpl.Count,
+			sum:     tmpl.GetSum(),
+			buckets: append([]uint64(nil), tmpl.BucketCounts...),
+		}
+		s.histograms[tmpl] = st
+	default:
+		if s.reset() {
+			st.start, st.count, st.sum = now, 0, 0
+			clear(st.buckets)
+		}
+		var added uint64
+		for i, c := range tmpl.BucketCounts {
+			n := stochasticRound(float64(c) * s.rate)
+			st.buckets[i] += n
+			added += n
+		}
+		st.count += added
+		if tmpl.Count > 0 {
+			st.sum += tmpl.GetSum() / float64(tmpl.Count) * float64(added)
+		}
+	}
+
+	return histogramPoint(tmpl, st, now)
+}
+
+// histogramPoint builds a point sent at now from a template point and series
+// state.
+func histogramPoint(tmpl *otlpmetrics.HistogramDataPoint, st *histogramState, now uint64) *otlpmetrics.HistogramDataPoint {
+	sum := st.sum
+	return &otlpmetrics.HistogramDataPoint{
+		Attributes:        tmpl.Attributes, // Attributes are immutable
+		StartTimeUnixNano: st.start,
+		TimeUnixNano:      now,
+		Count:             st.count,
+		Sum:               &sum,
+		BucketCounts:      append([]uint64(nil), st.buckets...),
+		ExplicitBounds:    tmpl.ExplicitBounds,
+		Exemplars:         tmpl.Exemplars,
+		Flags:             tmpl.Flags,
+		Min:               tmpl.Min,
+		Max:               tmpl.Max,
+	}
+}
+
+// reset reports whether a series resets on this send.
+func (s *SeriesTracker) reset() bool {
+	return s.resetPct > 0 && chance(s.resetPct)
+}
+
+// stochasticRound rounds x down or up with probability given by its fraction,
+// so small rates still add counts on average.
+func stochasticRound(x float64) uint64 {
+	n := math.Floor(x)
+	if rand.Float64() < x-n {
+		n++
+	}
+	return uint64(n)
+}
@@ -0,0 +1,155 @@
+package transformer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+const cumulative = otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+
+// sendMetric simulates one send at now: the tracker builds this send's
+// points from the template's.
+func sendMetric(s *SeriesTracker, tmpl *otlpmetrics.Metric, now uint64) *otlpmetrics.Metric {
+	out := &otlpmetrics.Metric{Name: tmpl.Name, Data: tmpl.Data}
+	s.Apply(nil, out, now)
+	return out
+}
+
+func TestSeriesTrackerSums(t *testing.T) {
+	s := NewSeriesTracker(config.CumulativeConfig{Enabled: true, Rate: 0.5})
+	tmplPoint := &otlpmetrics.NumberDataPoint{Value: &otlpmetrics.NumberDataPoint_AsInt{AsInt: 100}}
+	tmpl := &otlpmetrics.Metric{Data: &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+		AggregationTemporality: cumulative,
+		IsMonotonic:            true,
+		DataPoints:             []*otlpmetrics.NumberDataPoint{tmplPoint},
+	}}}
+
+	for i, want := range []int64{100, 150, 200} {
+		now := uint64(1000 + i)
+		dp := sendMetric(s, tmpl, now).GetSum().DataPoints[0]
+		if dp.GetAsInt() != want {
+			t.Errorf("send %d: value %d, want %d", i, dp.GetAsInt(), want)
+		}
+		if dp.StartTimeUnixNano != 1000 || dp.TimeUnixNano != now {
+			t.Errorf("send %d: start %d time %d, want stable start 1000", i, dp.StartTimeUnixNano, dp.TimeUnixNano)
+		}
+	}
+	if tmplPoint.GetAsInt() != 100 {
+		t.Errorf("template point modified to %d", tmplPoint.GetAsInt())
+	}
+}
+
+func TestSeriesTrackerResets(t *testing.T) {
+	s := NewSeriesTracker(config.CumulativeConfig{Enabled: true, Rate: 1, ResetPercentage: 100})
+	tmpl := &otlpmetrics.Metric{Data: &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+		AggregationTemporality: cumulative,
+		IsMonotonic:            true,
+		DataPoints:             []*otlpmetrics.NumberDataPoint{{Value: &otlpmetrics.NumberDataPoint_AsDouble{AsDouble: 10}}},
+	}}}
+	sendMetric(s, tmpl, 1000)
+	dp := sendMetric(s, tmpl, 2000).GetSum().DataPoints[0]
+	if dp.GetAsDouble() != 0 || dp.StartTimeUnixNano != 2000 {
+		t.Errorf("after reset: value %v start %d, want 0 starting at 2000", dp.GetAsDouble(), dp.StartTimeUnixNano)
+	}
+}
+
+func TestSeriesTrackerHistograms(t *testing.T) {
+	s := NewSeriesTracker(config.CumulativeConfig{Enabled: true, Rate: 1})
+	sum := 30.0
+	tmpl := &otlpmetrics.Metric{Data: &otlpmetrics.Metric_Histogram{Histogram: &otlpmetrics.Histogram{
+		AggregationTemporality: cumulative,
+		DataPoints: []*otlpmetrics.HistogramDataPoint{{
+			Count: 3, Sum: &sum, BucketCounts: []uint64{1, 2}, ExplicitBounds: []float64{10},
+		}},
+	}}}
+
+	sendMetric(s, tmpl, 1000)
+	dp := sendMetric(s, tmpl, 2000).GetHistogram().DataPoints[0]
+	if dp.Count != 6 || dp.GetSum() != 60 || dp.BucketCounts[0] != 2 || dp.BucketCounts[1] != 4 {
+		t.Errorf("second send: count %d sum %v buckets %v, want 6, 60, [2 4]", dp.Count, dp.GetSum(), dp.BucketCounts)
+	}
+	if dp.StartTimeUnixNano != 1000 {
+		t.Errorf("start = %d, want 1000", dp.StartTimeUnixNano)
+	}
+	tp := tmpl.GetHistogram().DataPoints[0]
+	if tp.Count != 3 || tp.BucketCounts[1] != 2 {
+		t.Error("template histogram modified")
+	}
+}
+
+func TestSeriesTrackerLeavesOtherMetrics(t *testing.T) {
+	s := NewSeriesTracker(config.CumulativeConfig{Enabled: true, Rate: 1})
+	gauge := &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{}}
+	m := &otlpmetrics.Metric{Data: gauge}
+	s.Apply(nil, m, 1000)
+	if m.Data != gauge {
+		t.Error("gauge data replaced")
+	}
+
+	// Cumulative series are left alone unless cumulative tracking is on.
+	s = NewSeriesTracker(config.CumulativeConfig{})
+	sum := &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{AggregationTemporality: cumulative}}
+	m = &otlpmetrics.Metric{Data: sum}
+	s.Apply(nil, m, 1000)
+	if m.Data != sum {
+		t.Error("cumulative sum replaced with tracking disabled")
+	}
+}
+
+func TestSeriesTrackerDeltaWindows(t *testing.T) {
+	s := NewSeriesTracker(config.CumulativeConfig{})
+	sum := 30.0
+	tmpl := &otlpmetrics.Metric{Data: &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+		AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+		IsMonotonic:            true,
+		DataPoints:             []*otlpmetrics.NumberDataPoint{{Value: &otlpmetrics.NumberDataPoint_AsInt{AsInt: 7}}},
+	}}}
+	hist := &otlpmetrics.Metric{Data: &otlpmetrics.Metric_Histogram{Histogram: &otlpmetrics.Histogram{
+		AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+		DataPoints:             []*otlpmetrics.HistogramDataPoint{{Count: 3, Sum: &sum, BucketCounts: []uint64{1, 2}}},
+	}}}
+	exp := &otlpmetrics.Metric{Data: &otlpmetrics.Metric_ExponentialHistogram{ExponentialHistogram: &otlpmetrics.ExponentialHistogram{
+		AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+		DataPoints:             []*otlpmetrics.ExponentialHistogramDataPoint{{Count: 5, Scale: 3, ZeroCount: 1}},
+	}}}
+
+	// The first window covers one default export interval; later ones
+	// start at the previous send.
+	t0 := uint64(time.Hour)
+	sec := uint64(time.Second)
+	for i, want := range []uint64{t0 - 60*sec, t0, t0 + sec} {
+		now := t0 + uint64(i)*sec
+		dp := sendMetric(s, tmpl, now).GetSum().DataPoints[0]
+		if dp.StartTimeUnixNano != want || dp.TimeUnixNano != now || dp.GetAsInt() != 7 {
+			t.Errorf("sum send %d: start %d time %d value %d, want %d, %d, 7", i, dp.StartTimeUnixNano, dp.TimeUnixNano, dp.GetAsInt(), want, now)
+		}
+		hp := sendMetric(s, hist, now).GetHistogram().DataPoints[0]
+		if hp.StartTimeUnixNano != want || hp.Count != 3 {
+			t.Errorf("histogram send %d: start %d count %d, want %d, 3", i, hp.StartTimeUnixNano, hp.Count, want)
+		}
+		ep := sendMetric(s, exp, now).GetExponentialHistogram().DataPoints[0]
+		if ep.StartTimeUnixNano != want || ep.Count != 5 || ep.Scale != 3 {
+			t.Errorf("exponential send %d: start %d count %d scale %d, want %d, 5, 3", i, ep.StartTimeUnixNano, ep.Count, ep.Scale, want)
+		}
+	}
+
+	// A send that arrives late never gets a window ending before it starts.
+	late := t0 + sec + sec/2
+	dp := sendMetric(s, tmpl, late).GetSum().DataPoints[0]
+	if dp.StartTimeUnixNano != late {
+		t.Errorf("late send: start %d, want %d", dp.StartTimeUnixNano, late)
+	}
+
+	// A series first sent once others have repeated covers their interval.
+	fresh := &otlpmetrics.Metric{Data: &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+		AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+		DataPoints:             []*otlpmetrics.NumberDataPoint{{Value: &otlpmetrics.NumberDataPoint_AsInt{AsInt: 1}}},
+	}}}
+	now := t0 + 3*sec
+	if dp := sendMetric(s, fresh, now).GetSum().DataPoints[0]; dp.StartTimeUnixNano != now-sec {
+		t.Errorf("new series: start %d, want %d", dp.StartTimeUnixNano, now-sec)
+	}
+}
@@ -14,6 +14,7 @@ import (
 type TimestampInjector struct {
 	jitterMs   int
 	backdateMs int
+	backfill   *BackfillClock
 }
 
 // NewTimestampInjector creates a new timestamp injector
@@ -24,6 +25,24 @@ func NewTimestampInjector(jitterMs, backdateMs int) *TimestampInjector {
 	}
 }
 
+// SetBackfill makes the injector take its current time from a backfill clock
+// instead of the wall clock; backdate_ms is then ignored.
+func (t *TimestampInjector) SetBackfill(b *BackfillClock) {
+	t.backfill = b
+}
+
+// now returns the time new telemetry is anchored to.
+func (t *TimestampInjector) now() time.Time {
+	if t.backfill != nil {
+		return t.backfill.Now()
+	}
+	now := time.Now()
+	if t.backdateMs > 0 {
+		now = now.Add(-time.Duration(t.backdateMs) * time.Millisecond)
+	}
+	return now
+}
+
 // InjectSpanTimestamps adds timestamps to spans while preserving relative timing
 func (t *TimestampInjector) InjectSpanTimestamps(spans []*otlptrace.Span) {
 	if len(spans) == 0 {
@@ -31,16 +50,13 @@ func (t *TimestampInjector) InjectSpanTimestamps(spans []*otlptrace.Span) {
 	}
 
 	// Get current time with optional backdate
-	now := time.Now()
-	if t.backdateMs > 0 {
-		now = now.Add(-time.Duration(t.backdateMs) * time.Millisecond)
-	}
+	now := t.now()
 
 	// Extract template timing information from first span to establish trace start
 	// Find the span with the earliest start offset (root span)
 	var rootSpan *otlptrace.Span
 	minOffset := int64(0)
-	maxDuration := int64(0)
+	maxEnd := int64(0)
 
 	for _, span := range spans {
 		// Extract template metadata
@@ -51,13 +67,16 @@ func (t *TimestampInjector) InjectSpanTimestamps(spans []*otlptrace.Span) {
 			rootSpan = span
 		}
 
-		if duration > maxDuration {
-			maxDuration = duration
+		// The trace ends with its last span, which isn't always the root:
+		// fire-and-forget children can outlive their parent.
+		if end := startOffset + duration; end > maxEnd {
+			maxEnd = end
 		}
 	}
 
-	// Calculate trace start time (now - total trace duration)
-	traceStartNano := now.UnixNano() - maxDuration
+	// Calculate trace start time (now - total trace duration), so no span
+	// ends in the future
+	traceStartNano := now.UnixNano() - maxEnd
 
 	// Apply timestamps to all spans
 	for _, span := range spans {
@@ -74,18 +93,36 @@ func (t *TimestampInjector) InjectSpanTimestamps(spans []*otlptrace.Span) {
 
 		span.StartTimeUnixNano = uint64(spanStartNano)
 		span.EndTimeUnixNano = uint64(spanStartNano + duration)
+		span.Events = rebaseEvents(span.Events, spanStartNano)
 
 		// Remove template metadata attributes
 		span.Attributes = t.removeTemplateAttributes(span.Attributes)
 	}
 }
 
-// InjectMetricTimestamps adds timestamps to metric data points
-func (t *TimestampInjector) InjectMetricTimestamps(metric *otlpmetrics.Metric) {
-	now := time.Now()
-	if t.backdateMs > 0 {
-		now = now.Add(-time.Duration(t.backdateMs) * time.Millisecond)
+// rebaseEvents returns copies of a span's events with their template times
+// (offsets from the span start) turned into absolute times. Template events
+// are shared by every clone of the span, so they are never modified in place.
+func rebaseEvents(events []*otlptrace.Span_Event, spanStartNano int64) []*otlptrace.Span_Event {
+	if len(events) == 0 {
+		return events
 	}
+	rebased := make([]*otlptrace.Span_Event, len(events))
+	for i, e := range events {
+		rebased[i] = &otlptrace.Span_Event{
+			TimeUnixNano:           uint64(spanStartNano) + e.TimeUnixNano,
+			Name:                   e.Name,
+			Attributes:             e.Attributes, // Attributes are immutable
+			DroppedAttributesCount: e.DroppedAttributesCount,
+		}
+	}
+	return rebased
+}
+
+// MetricTime returns the time a metric send is stamped with: the current
+// (or backfill) time plus jitter.
+func (t *TimestampInjector) MetricTime() uint64 {
+	now := t.now()
 
 	// Add jitter
 	if t.jitterMs > 0 {
@@ -93,35 +130,65 @@ func (t *TimestampInjector) InjectMetricTimestamps(metric *otlpmetrics.Metric) {
 		now = now.Add(time.Duration(jitter) * time.Millisecond)
 	}
 
-	nowNano := uint64(now.UnixNano())
+	return uint64(now.UnixNano())
+}
 
+// InjectMetricTimestamps replaces metric's data points with copies stamped
+// with now. The template points are shared by every send, so they are never
+// modified. Sums, histograms and summaries also start at now; gauges keep
+// their template start time.
+func (t *TimestampInjector) InjectMetricTimestamps(metric *otlpmetrics.Metric, now uint64) {
 	switch data := metric.Data.(type) {
 	case *otlpmetrics.Metric_Gauge:
-		for _, dp := range data.Gauge.DataPoints {
-			dp.TimeUnixNano = nowNano
+		gauge := &otlpmetrics.Gauge{DataPoints: make([]*otlpmetrics.NumberDataPoint, len(data.Gauge.DataPoints))}
+		for i, dp := range data.Gauge.DataPoints {
+			gauge.DataPoints[i] = cloneNumberPoint(dp, dp.StartTimeUnixNano, now)
 		}
+		metric.Data = &otlpmetrics.Metric_Gauge{Gauge: gauge}
 
 	case *otlpmetrics.Metric_Sum:
-		for _, dp := range data.Sum.DataPoints {
-			dp.TimeUnixNano = nowNano
-			// For cumulative sums, also set start time
-			dp.StartTimeUnixNano = nowNano
+		sum := &otlpmetrics.Sum{
+			AggregationTemporality: data.Sum.AggregationTemporality,
+			IsMonotonic:            data.Sum.IsMonotonic,
+			DataPoints:             make([]*otlpmetrics.NumberDataPoint, len(data.Sum.DataPoints)),
+		}
+		for i, dp := range data.Sum.DataPoints {
+			sum.DataPoints[i] = cloneNumberPoint(dp, now, now)
 		}
+		metric.Data = &otlpmetrics.Metric_Sum{Sum: sum}
 
 	case *otlpmetrics.Metric_Histogram:
-		for _, dp := range data.Histogram.DataPoints {
-			dp.TimeUnixNano = nowNano
-			dp.StartTimeUnixNano = nowNano
+		hist := &otlpmetrics.Histogram{
+			AggregationTemporality: data.Histogram.AggregationTemporality,
+			DataPoints:             make([]*otlpmetrics.HistogramDataPoint, len(data.Histogram.DataPoints)),
+		}
+		for i, dp := range data.Histogram.DataPoints {
+			hist.DataPoints[i] = cloneHistogramPoint(dp, now, now)
+		}
+		metric.Data = &otlpmetrics.Metric_Histogram{Histogram: hist}
+
+	case *otlpmetrics.Metric_ExponentialHistogram:
+		hist := &otlpmetrics.ExponentialHistogram{
+			AggregationTemporality: data.ExponentialHistogram.AggregationTemporality,
+			DataPoints:             make([]*otlpmetrics.ExponentialHistogramDataPoint, len(data.ExponentialHistogram.DataPoints)),
+		}
+		for i, dp := range data.ExponentialHistogram.DataPoints {
+			hist.DataPoints[i] = cloneExponentialPoint(dp, now, now)
+		}
+		metric.Data = &otlpmetrics.Metric_ExponentialHistogram{ExponentialHistogram: hist}
+
+	case *otlpmetrics.Metric_Summary:
+		summary := &otlpmetrics.Summary{DataPoints: make([]*otlpmetrics.SummaryDataPoint, len(data.Summary.DataPoints))}
+		for i, dp := range data.Summary.DataPoints {
+			summary.DataPoints[i] = cloneSummaryPoint(dp, now, now)
 		}
+		metric.Data = &otlpmetrics.Metric_Summary{Summary: summary}
 	}
 }
 
 // InjectLogTimestamps adds timestamps to log records
 func (t *TimestampInjector) InjectLogTimestamps(logs []*otlplogs.LogRecord) {
-	now := time.Now()
-	if t.backdateMs > 0 {
-		now = now.Add(-time.Duration(t.backdateMs) * time.Millisecond)
-	}
+	now := t.now()
 
 	for _, log := range logs {
 		// Add jitter for each log
@@ -2,8 +2,10 @@ package transformer
 
 import (
 	"testing"
+	"time"
 
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
 	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
@@ -60,3 +62,104 @@ func TestInjectStripsTemplateAttrs(t *testing.T) {
 		t.Errorf("timestamps not injected: start=%d end=%d", span.StartTimeUnixNano, span.EndTimeUnixNano)
 	}
 }
+
+// TestInjectSpanTimestampsAsyncChild verifies a child that outlives its parent
+// (async fan-out) still ends no later than now, and relative timing is kept.
+func TestInjectSpanTimestampsAsyncChild(t *testing.T) {
+	inj := NewTimestampInjector(0, 0)
+
+	root := &otlptrace.Span{Attributes: []*commonpb.KeyValue{
+		intAttr("_template.start_offset_nanos", 0),
+		intAttr("_template.duration_nanos", 10_000_000),
+	}}
+	async := &otlptrace.Span{Attributes: []*commonpb.KeyValue{
+		intAttr("_template.start_offset_nanos", 5_000_000),
+		intAttr("_template.duration_nanos", 50_000_000),
+	}}
+
+	before := uint64(time.Now().UnixNano())
+	inj.InjectSpanTimestamps([]*otlptrace.Span{root, async})
+	after := uint64(time.Now().UnixNano())
+
+	if async.EndTimeUnixNano < before || async.EndTimeUnixNano > after {
+		t.Errorf("last span should end at send time: end=%d, send window [%d, %d]", async.EndTimeUnixNano, before, after)
+	}
+	if got := async.StartTimeUnixNano - root.StartTimeUnixNano; got != 5_000_000 {
+		t.Errorf("async child starts %dns after root, want 5ms", got)
+	}
+	if async.EndTimeUnixNano <= root.EndTimeUnixNano {
+		t.Errorf("async child should outlive the root")
+	}
+}
+
+// TestInjectSpanTimestampsRebasesEvents verifies event offsets become absolute
+// times within the span without modifying the shared template events.
+func TestInjectSpanTimestampsRebasesEvents(t *testing.T) {
+	inj := NewTimestampInjector(0, 0)
+
+	template := &otlptrace.Span_Event{TimeUnixNano: 4_000_000, Name: "exception"}
+	span := &otlptrace.Span{
+		Attributes: []*commonpb.KeyValue{
+			intAttr("_template.start_offset_nanos", 0),
+			intAttr("_template.duration_nanos", 5_000_000),
+		},
+		Events: []*otlptrace.Span_Event{template},
+	}
+
+	inj.InjectSpanTimestamps([]*otlptrace.Span{span})
+
+	if got := span.Events[0].TimeUnixNano - span.StartTimeUnixNano; got != 4_000_000 {
+		t.Errorf("event at %dns into the span, want 4ms", got)
+	}
+	if template.TimeUnixNano != 4_000_000 {
+		t.Errorf("template event was modified: %d", template.TimeUnixNano)
+	}
+}
+
+func TestInjectMetricTimestampsAllTypes(t *testing.T) {
+	inj := NewTimestampInjector(0, 0)
+	now := inj.MetricTime()
+
+	gauge := &otlpmetrics.NumberDataPoint{StartTimeUnixNano: 5}
+	sum := &otlpmetrics.NumberDataPoint{}
+	hist := &otlpmetrics.HistogramDataPoint{}
+	exp := &otlpmetrics.ExponentialHistogramDataPoint{}
+	summary := &otlpmetrics.SummaryDataPoint{}
+	metrics := []*otlpmetrics.Metric{
+		{Data: &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{DataPoints: []*otlpmetrics.NumberDataPoint{gauge}}}},
+		{Data: &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{DataPoints: []*otlpmetrics.NumberDataPoint{sum}}}},
+		{Data: &otlpmetrics.Metric_Histogram{Histogram: &otlpmetrics.Histogram{DataPoints: []*otlpmetrics.HistogramDataPoint{hist}}}},
+		{Data: &otlpmetrics.Metric_ExponentialHistogram{ExponentialHistogram: &otlpmetrics.ExponentialHistogram{
+			DataPoints: []*otlpmetrics.ExponentialHistogramDataPoint{exp},
+		}}},
+		{Data: &otlpmetrics.Metric_Summary{Summary: &otlpmetrics.Summary{
+			DataPoints: []*otlpmetrics.SummaryDataPoint{summary},
+		}}},
+	}
+	for _, m := range metrics {
+		inj.InjectMetricTimestamps(m, now)
+	}
+
+	// The sent points are stamped copies; the template points are untouched.
+	for _, tmpl := range []interface{ GetTimeUnixNano() uint64 }{gauge, sum, hist, exp, summary} {
+		if tmpl.GetTimeUnixNano() != 0 {
+			t.Errorf("template point %T was stamped", tmpl)
+		}
+	}
+	if dp := metrics[0].GetGauge().DataPoints[0]; dp.TimeUnixNano != now || dp.StartTimeUnixNano != 5 {
+		t.Errorf("gauge: start %d time %d, want 5, %d", dp.StartTimeUnixNano, dp.TimeUnixNano, now)
+	}
+	for i, dp := range []interface {
+		GetStartTimeUnixNano() uint64
+		GetTimeUnixNano() uint64
+	}{
+		metrics[1].GetSum().DataPoints[0],
+		metrics[2].GetHistogram().DataPoints[0],
+		metrics[3].GetExponentialHistogram().DataPoints[0],
+		metrics[4].GetSummary().DataPoints[0],
+	} {
+		if dp.GetTimeUnixNano() != now || dp.GetStartTimeUnixNano() != now {
+			t.Errorf("metric %d: start %d time %d, want both %d", i+1, dp.GetStartTimeUnixNano(), dp.GetTimeUnixNano(), now)
+		}
+	}
+}
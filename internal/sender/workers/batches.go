@@ -0,0 +1,246 @@
+package workers
+
+import (
+	otlpcollectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	otlpcollectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	otlplogs "go.opentelemetry.io/proto/otlp/logs/v1"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxBatchBytes caps the estimated size of a metrics or logs request, like
+// the 10,000-span limit for traces: batches close early rather than exceed
+// it, keeping requests under gRPC's default 4 MiB receive limit with room
+// for resources and attributes added at send time.
+const maxBatchBytes = 3 << 20
+
+// sendTimeBytes approximates what each data point or log record grows by at
+// send time: the injected timestamps the template leaves unset.
+const sendTimeBytes = 24
+
+// metricBatches pages through the metrics template in requests of up to size
+// data points (no count limit when size <= 0) and about maxBytes bytes. A
+// metric with more points than fit in a batch is split across batches; each
+// part keeps the metric's name and temporality. The batches share the
+// template's data points; sends work on cloneMetricBatch copies. The byte
+// estimate includes the resource and scope each batch repeats, which
+// dominate when resources are per entity and hold few points.
+func metricBatches(src *otlpcollectormetrics.ExportMetricsServiceRequest, size, maxBytes int) []*otlpcollectormetrics.ExportMetricsServiceRequest {
+	var (
+		batches []*otlpcollectormetrics.ExportMetricsServiceRequest
+		current *otlpcollectormetrics.ExportMetricsServiceRequest
+		points  int
+		bytes   int
+
+		// Source of the current batch's last resource and scope.
+		lastRM *otlpmetrics.ResourceMetrics
+		lastSM *otlpmetrics.ScopeMetrics
+	)
+
+	full := func() bool {
+		return points > 0 && ((size > 0 && points >= size) || bytes >= maxBytes)
+	}
+
+	// scope returns the current batch's ScopeMetrics for srcSM in srcRM,
+	// starting a new batch when the current one is full.
+	scope := func(srcRM *otlpmetrics.ResourceMetrics, srcSM *otlpmetrics.ScopeMetrics) *otlpmetrics.ScopeMetrics {
+		if current == nil || full() {
+			current = &otlpcollectormetrics.ExportMetricsServiceRequest{}
+			batches = append(batches, current)
+			points, bytes = 0, 0
+			lastRM, lastSM = nil, nil
+		}
+		if srcRM != lastRM {
+			current.ResourceMetrics = append(current.ResourceMetrics, &otlpmetrics.ResourceMetrics{
+				Resource:  srcRM.Resource, // Resource is immutable
+				SchemaUrl: srcRM.SchemaUrl,
+			})
+			bytes += proto.Size(srcRM.Resource) + len(srcRM.SchemaUrl)
+			lastRM, lastSM = srcRM, nil
+		}
+		rm := current.ResourceMetrics[len(current.ResourceMetrics)-1]
+		if srcSM != lastSM {
+			rm.ScopeMetrics = append(rm.ScopeMetrics, &otlpmetrics.ScopeMetrics{
+				Scope:     srcSM.Scope, // Scope is immutable
+				SchemaUrl: srcSM.SchemaUrl,
+			})
+			bytes += proto.Size(srcSM.Scope) + len(srcSM.SchemaUrl)
+			lastSM = srcSM
+		}
+		return rm.ScopeMetrics[len(rm.ScopeMetrics)-1]
+	}
+
+	for _, rm := range src.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			for _, metric := range sm.Metrics {
+				total := countMetricDataPoints(metric)
+				lo := 0
+				for {
+					target := scope(rm, sm)
+					bytes += len(metric.Name) + len(metric.Description) + len(metric.Unit)
+					hi := lo
+					for hi < total && !full() {
+						bytes += metricPointSize(metric, hi) + sendTimeBytes
+						points++
+						hi++
+					}
+					target.Metrics = append(target.Metrics, sliceMetric(metric, lo, hi))
+					lo = hi
+					if lo >= total {
+						break
+					}
+				}
+			}
+		}
+	}
+	return batches
+}
+
+// MetricBatchCount returns the number of requests one pass over the metrics
+// template takes at the given batch size.
+func MetricBatchCount(src *otlpcollectormetrics.ExportMetricsServiceRequest, size int) int {
+	return len(metricBatches(src, size, maxBatchBytes))
+}
+
+// metricPointSize returns the encoded size of metric's i-th data point.
+func metricPointSize(metric *otlpmetrics.Metric, i int) int {
+	switch data := metric.Data.(type) {
+	case *otlpmetrics.Metric_Gauge:
+		return proto.Size(data.Gauge.DataPoints[i])
+	case *otlpmetrics.Metric_Sum:
+		return proto.Size(data.Sum.DataPoints[i])
+	case *otlpmetrics.Metric_Histogram:
+		return proto.Size(data.Histogram.DataPoints[i])
+	case *otlpmetrics.Metric_ExponentialHistogram:
+		return proto.Size(data.ExponentialHistogram.DataPoints[i])
+	case *otlpmetrics.Metric_Summary:
+		return proto.Size(data.Summary.DataPoints[i])
+	default:
+		return 0
+	}
+}
+
+// sliceMetric returns a shell of metric holding its data points [lo, hi).
+func sliceMetric(metric *otlpmetrics.Metric, lo, hi int) *otlpmetrics.Metric {
+	part := &otlpmetrics.Metric{
+		Name:        metric.Name,
+		Description: metric.Description,
+		Unit:        metric.Unit,
+		Data:        metric.Data,
+	}
+	if lo == 0 && hi == countMetricDataPoints(metric) {
+		return part
+	}
+
+	switch data := metric.Data.(type) {
+	case *otlpmetrics.Metric_Gauge:
+		part.Data = &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{
+			DataPoints: data.Gauge.DataPoints[lo:hi:hi],
+		}}
+	case *otlpmetrics.Metric_Sum:
+		part.Data = &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+			AggregationTemporality: data.Sum.AggregationTemporality,
+			IsMonotonic:            data.Sum.IsMonotonic,
+			DataPoints:             data.Sum.DataPoints[lo:hi:hi],
+		}}
+	case *otlpmetrics.Metric_Histogram:
+		part.Data = &otlpmetrics.Metric_Histogram{Histogram: &otlpmetrics.Histogram{
+			AggregationTemporality: data.Histogram.AggregationTemporality,
+			DataPoints:             data.Histogram.DataPoints[lo:hi:hi],
+		}}
+	case *otlpmetrics.Metric_ExponentialHistogram:
+		part.Data = &otlpmetrics.Metric_ExponentialHistogram{ExponentialHistogram: &otlpmetrics.ExponentialHistogram{
+			AggregationTemporality: data.ExponentialHistogram.AggregationTemporality,
+			DataPoints:             data.ExponentialHistogram.DataPoints[lo:hi:hi],
+		}}
+	case *otlpmetrics.Metric_Summary:
+		part.Data = &otlpmetrics.Metric_Summary{Summary: &otlpmetrics.Summary{
+			DataPoints: data.Summary.DataPoints[lo:hi:hi],
+		}}
+	}
+	return part
+}
+
+// cloneMetricBatch copies a batch's ResourceMetrics, ScopeMetrics and Metric
+// shells, so a send can replace a metric's data without touching the batch.
+// Data points are shared by every send, so they must never be modified.
+func cloneMetricBatch(src *otlpcollectormetrics.ExportMetricsServiceRequest) *otlpcollectormetrics.ExportMetricsServiceRequest {
+	resourceMetrics := make([]*otlpmetrics.ResourceMetrics, len(src.ResourceMetrics))
+	for i, rm := range src.ResourceMetrics {
+		scopeMetrics := make([]*otlpmetrics.ScopeMetrics, len(rm.ScopeMetrics))
+		for j, sm := range rm.ScopeMetrics {
+			metrics := make([]*otlpmetrics.Metric, len(sm.Metrics))
+			for k, metric := range sm.Metrics {
+				metrics[k] = &otlpmetrics.Metric{
+					Name:        metric.Name,
+					Description: metric.Description,
+					Unit:        metric.Unit,
+					Data:        metric.Data,
+				}
+			}
+			scopeMetrics[j] = &otlpmetrics.ScopeMetrics{Scope: sm.Scope, Metrics: metrics, SchemaUrl: sm.SchemaUrl}
+		}
+		resourceMetrics[i] = &otlpmetrics.ResourceMetrics{Resource: rm.Resource, ScopeMetrics: scopeMetrics, SchemaUrl: rm.SchemaUrl}
+	}
+	return &otlpcollectormetrics.ExportMetricsServiceRequest{ResourceMetrics: resourceMetrics}
+}
+
+// logBatches pages through the logs template in requests of up to size
+// records (no count limit when size <= 0) and about maxBytes bytes. The
+// batches share the template's records; sends work on cloneLogsRequest
+// copies. Like metricBatches, the byte estimate includes each batch's
+// resources and scopes.
+func logBatches(src *otlpcollectorlogs.ExportLogsServiceRequest, size, maxBytes int) []*otlpcollectorlogs.ExportLogsServiceRequest {
+	var (
+		batches []*otlpcollectorlogs.ExportLogsServiceRequest
+		current *otlpcollectorlogs.ExportLogsServiceRequest
+		records int
+		bytes   int
+
+		// Source of the current batch's last resource and scope.
+		lastRL *otlplogs.ResourceLogs
+		lastSL *otlplogs.ScopeLogs
+	)
+
+	for _, rl := range src.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			for _, record := range sl.LogRecords {
+				recordBytes := proto.Size(record) + sendTimeBytes
+				if current == nil || (records > 0 && ((size > 0 && records >= size) || bytes+recordBytes > maxBytes)) {
+					current = &otlpcollectorlogs.ExportLogsServiceRequest{}
+					batches = append(batches, current)
+					records, bytes = 0, 0
+					lastRL, lastSL = nil, nil
+				}
+				if rl != lastRL {
+					current.ResourceLogs = append(current.ResourceLogs, &otlplogs.ResourceLogs{
+						Resource:  rl.Resource, // Resource is immutable
+						SchemaUrl: rl.SchemaUrl,
+					})
+					bytes += proto.Size(rl.Resource) + len(rl.SchemaUrl)
+					lastRL, lastSL = rl, nil
+				}
+				target := current.ResourceLogs[len(current.ResourceLogs)-1]
+				if sl != lastSL {
+					target.ScopeLogs = append(target.ScopeLogs, &otlplogs.ScopeLogs{
+						Scope:     sl.Scope, // Scope is immutable
+						SchemaUrl: sl.SchemaUrl,
+					})
+					bytes += proto.Size(sl.Scope) + len(sl.SchemaUrl)
+					lastSL = sl
+				}
+				scope := target.ScopeLogs[len(target.ScopeLogs)-1]
+				scope.LogRecords = append(scope.LogRecords, record)
+				records++
+				bytes += recordBytes
+			}
+		}
+	}
+	return batches
+}
+
+// LogBatchCount returns the number of requests one pass over the logs
+// template takes at the given batch size.
+func LogBatchCount(src *otlpcollectorlogs.ExportLogsServiceRequest, size int) int {
+	return len(logBatches(src, size, maxBatchBytes))
+}
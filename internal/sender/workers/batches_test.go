@@ -0,0 +1,215 @@
+package workers
+
+import (
+	"strings"
+	"testing"
+
+	otlpcollectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	otlpcollectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlplogs "go.opentelemetry.io/proto/otlp/logs/v1"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func gaugeMetric(name string, points int) *otlpmetrics.Metric {
+	dps := make([]*otlpmetrics.NumberDataPoint, points)
+	for i := range dps {
+		dps[i] = &otlpmetrics.NumberDataPoint{}
+	}
+	return &otlpmetrics.Metric{Name: name, Data: &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{DataPoints: dps}}}
+}
+
+// TestMetricBatches verifies batches hold at most size points, split large
+// metrics, keep resources apart, and cover every template point exactly once.
+func TestMetricBatches(t *testing.T) {
+	sum := &otlpmetrics.Metric{Name: "sum", Data: &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+		AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+		IsMonotonic:            true,
+		DataPoints:             []*otlpmetrics.NumberDataPoint{{}, {}, {}, {}, {}},
+	}}}
+	src := &otlpcollectormetrics.ExportMetricsServiceRequest{ResourceMetrics: []*otlpmetrics.ResourceMetrics{
+		{ScopeMetrics: []*otlpmetrics.ScopeMetrics{{Metrics: []*otlpmetrics.Metric{gaugeMetric("a", 3), sum}}}},
+		{ScopeMetrics: []*otlpmetrics.ScopeMetrics{{Metrics: []*otlpmetrics.Metric{gaugeMetric("b", 2)}}}},
+	}}
+
+	batches := metricBatches(src, 4, maxBatchBytes)
+	if len(batches) != 3 {
+		t.Fatalf("got %d batches, want 3", len(batches))
+	}
+
+	seen := make(map[*otlpmetrics.NumberDataPoint]int)
+	for i, batch := range batches {
+		points := 0
+		for _, rm := range batch.ResourceMetrics {
+			for _, sm := range rm.ScopeMetrics {
+				for _, metric := range sm.Metrics {
+					points += countMetricDataPoints(metric)
+					switch data := metric.Data.(type) {
+					case *otlpmetrics.Metric_Gauge:
+						for _, dp := range data.Gauge.DataPoints {
+							seen[dp]++
+						}
+					case *otlpmetrics.Metric_Sum:
+						if data.Sum.AggregationTemporality != otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA || !data.Sum.IsMonotonic {
+							t.Errorf("batch %d: split sum lost its temporality or monotonicity", i)
+						}
+						for _, dp := range data.Sum.DataPoints {
+							seen[dp]++
+						}
+					}
+				}
+			}
+		}
+		if points > 4 {
+			t.Errorf("batch %d holds %d points, want at most 4", i, points)
+		}
+	}
+	if len(seen) != 10 {
+		t.Fatalf("batches cover %d distinct points, want 10", len(seen))
+	}
+	for _, n := range seen {
+		if n != 1 {
+			t.Fatal("a template point was sent more than once per pass")
+		}
+	}
+	// The first batch holds gauge a and the start of the sum; the last one
+	// only the second resource.
+	if got := len(batches[0].ResourceMetrics[0].ScopeMetrics[0].Metrics); got != 2 {
+		t.Errorf("first batch has %d metrics, want 2", got)
+	}
+	if got := batches[2].ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name; got != "b" {
+		t.Errorf("last batch holds %q, want b", got)
+	}
+
+	if got := MetricBatchCount(src, 0); got != 1 {
+		t.Errorf("MetricBatchCount(size 0) = %d, want 1", got)
+	}
+}
+
+// TestMetricBatchesLeaveTemplate verifies replacing a batch metric's data
+// doesn't touch the template.
+func TestMetricBatchesLeaveTemplate(t *testing.T) {
+	metric := gaugeMetric("a", 2)
+	src := &otlpcollectormetrics.ExportMetricsServiceRequest{ResourceMetrics: []*otlpmetrics.ResourceMetrics{
+		{ScopeMetrics: []*otlpmetrics.ScopeMetrics{{Metrics: []*otlpmetrics.Metric{metric}}}},
+	}}
+	data := metric.Data
+	for _, batch := range metricBatches(src, 1, maxBatchBytes) {
+		batch = cloneMetricBatch(batch)
+		batch.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Data = nil
+	}
+	if metric.Data != data || len(metric.GetGauge().DataPoints) != 2 {
+		t.Fatal("template metric was modified")
+	}
+}
+
+// TestMetricBatchesByteLimit verifies the size guard closes batches before
+// the count limit when points are large.
+func TestMetricBatchesByteLimit(t *testing.T) {
+	metric := gaugeMetric("big", 10)
+	for _, dp := range metric.GetGauge().DataPoints {
+		dp.Attributes = []*commonpb.KeyValue{strAttr("payload", strings.Repeat("x", 1000))}
+	}
+	src := &otlpcollectormetrics.ExportMetricsServiceRequest{ResourceMetrics: []*otlpmetrics.ResourceMetrics{
+		{ScopeMetrics: []*otlpmetrics.ScopeMetrics{{Metrics: []*otlpmetrics.Metric{metric}}}},
+	}}
+	batches := metricBatches(src, 1000, 3000)
+	if len(batches) != 4 {
+		t.Fatalf("got %d batches, want 4 (3 points of ~1KB per 3000 bytes)", len(batches))
+	}
+	for _, batch := range batches {
+		if size := proto.Size(batch); size > 4000 {
+			t.Errorf("batch is %d bytes, want about 3000", size)
+		}
+	}
+}
+
+// TestBatchesCountResources verifies the size guard counts the resource
+// each batch repeats, so many large resources with one small point or
+// record each still close batches near the limit.
+func TestBatchesCountResources(t *testing.T) {
+	resource := func() *resourcepb.Resource {
+		return &resourcepb.Resource{Attributes: []*commonpb.KeyValue{strAttr("k8s.pod.name", strings.Repeat("p", 500))}}
+	}
+	metrics := &otlpcollectormetrics.ExportMetricsServiceRequest{}
+	logs := &otlpcollectorlogs.ExportLogsServiceRequest{}
+	for i := 0; i < 20; i++ {
+		metrics.ResourceMetrics = append(metrics.ResourceMetrics, &otlpmetrics.ResourceMetrics{
+			Resource:     resource(),
+			ScopeMetrics: []*otlpmetrics.ScopeMetrics{{Metrics: []*otlpmetrics.Metric{gaugeMetric("up", 1)}}},
+		})
+		logs.ResourceLogs = append(logs.ResourceLogs, &otlplogs.ResourceLogs{
+			Resource:  resource(),
+			ScopeLogs: []*otlplogs.ScopeLogs{{LogRecords: []*otlplogs.LogRecord{{}}}},
+		})
+	}
+
+	for _, batch := range metricBatches(metrics, 1000, 3000) {
+		if size := proto.Size(batch); size > 4000 {
+			t.Errorf("metric batch is %d bytes, want about 3000", size)
+		}
+	}
+	for _, batch := range logBatches(logs, 1000, 3000) {
+		if size := proto.Size(batch); size > 4000 {
+			t.Errorf("log batch is %d bytes, want about 3000", size)
+		}
+	}
+}
+
+// TestLogBatches verifies log batches honor the record count and size
+// limits and keep every record exactly once.
+func TestLogBatches(t *testing.T) {
+	records := func(n int, body string) []*otlplogs.LogRecord {
+		out := make([]*otlplogs.LogRecord, n)
+		for i := range out {
+			out[i] = &otlplogs.LogRecord{Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}}}
+		}
+		return out
+	}
+	src := &otlpcollectorlogs.ExportLogsServiceRequest{ResourceLogs: []*otlplogs.ResourceLogs{
+		{ScopeLogs: []*otlplogs.ScopeLogs{{LogRecords: records(5, "short")}}},
+		{ScopeLogs: []*otlplogs.ScopeLogs{{LogRecords: records(2, "short")}}},
+	}}
+
+	batches := logBatches(src, 3, maxBatchBytes)
+	if len(batches) != 3 {
+		t.Fatalf("got %d batches, want 3", len(batches))
+	}
+	seen := make(map[*otlplogs.LogRecord]bool)
+	for i, batch := range batches {
+		n := 0
+		for _, rl := range batch.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				for _, record := range sl.LogRecords {
+					if seen[record] {
+						t.Fatal("a record was batched twice")
+					}
+					seen[record] = true
+					n++
+				}
+			}
+		}
+		if n > 3 {
+			t.Errorf("batch %d holds %d records, want at most 3", i, n)
+		}
+	}
+	if len(seen) != 7 {
+		t.Fatalf("batches cover %d records, want 7", len(seen))
+	}
+	// Records 4-5 of the first resource and both of the second share a batch.
+	if got := len(batches[1].ResourceLogs); got != 2 {
+		t.Errorf("second batch has %d resources, want 2", got)
+	}
+
+	big := &otlpcollectorlogs.ExportLogsServiceRequest{ResourceLogs: []*otlplogs.ResourceLogs{
+		{ScopeLogs: []*otlplogs.ScopeLogs{{LogRecords: records(6, strings.Repeat("x", 1000))}}},
+	}}
+	if got := len(logBatches(big, 100, 2500)); got != 3 {
+		t.Errorf("size guard: got %d batches, want 3", got)
+	}
+	if got := LogBatchCount(src, 0); got != 1 {
+		t.Errorf("LogBatchCount(size 0) = %d, want 1", got)
+	}
+}
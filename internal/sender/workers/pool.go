@@ -1,8 +1,11 @@
 package workers
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -14,27 +17,45 @@ import (
 	otlpcollectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
 	otlpcollectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	otlpcollectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
 	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
 	otlplogs "go.opentelemetry.io/proto/otlp/logs/v1"
 )
 
+// metricsExportSink is the subset of *exporter.MetricsExporter the pool
+// needs. Like traceExportSink, it lets metric sends be tested without a real
+// gRPC connection.
+type metricsExportSink interface {
+	Export(ctx context.Context, request *otlpcollectormetrics.ExportMetricsServiceRequest) error
+}
+
 // WorkerPool manages concurrent workers for sending telemetry
 // Workers are divided by signal type for realistic load patterns
 type WorkerPool struct {
 	numWorkers        int
 	templates         *loader.Templates
 	traceExporter     *exporter.TraceExporter
-	metricsExporter   *exporter.MetricsExporter
+	metricsExporter   metricsExportSink
 	logsExporter      *exporter.LogsExporter
 	timestampInjector *transformer.TimestampInjector
 	idRegenerator     *transformer.IDRegenerator
+	cardinality       *transformer.CardinalityInjector
+	anomalies         *transformer.ClockAnomalies
+	series            *transformer.SeriesTracker
+	patterns          *transformer.ValuePatterns
+	churn             *transformer.SeriesChurn
 	rateLimiter       *ratelimit.Limiter
 	reporter          *stats.Reporter
 	batchSizeTraces   int
 	batchSizeMetrics  int
 	batchSizeLogs     int
 
+	// metricPages and logPages are the template split into requests of at
+	// most the configured batch size and maxBatchBytes, computed once.
+	metricPages []*otlpcollectormetrics.ExportMetricsServiceRequest
+	logPages    []*otlpcollectorlogs.ExportLogsServiceRequest
+
 	// scheduler exports late spans (those carrying _template.emit_delay_ms)
 	// after the rest of their trace. nil when there is no trace exporter.
 	scheduler *deferredScheduler
@@ -60,6 +81,11 @@ func NewWorkerPool(
 	logsExporter *exporter.LogsExporter,
 	timestampInjector *transformer.TimestampInjector,
 	idRegenerator *transformer.IDRegenerator,
+	cardinality *transformer.CardinalityInjector,
+	anomalies *transformer.ClockAnomalies,
+	series *transformer.SeriesTracker,
+	patterns *transformer.ValuePatterns,
+	churn *transformer.SeriesChurn,
 	rateLimiter *ratelimit.Limiter,
 	reporter *stats.Reporter,
 	batchSizeTraces int,
@@ -71,10 +97,14 @@ func NewWorkerPool(
 		numWorkers:        numWorkers,
 		templates:         templates,
 		traceExporter:     traceExporter,
-		metricsExporter:   metricsExporter,
 		logsExporter:      logsExporter,
 		timestampInjector: timestampInjector,
 		idRegenerator:     idRegenerator,
+		cardinality:       cardinality,
+		anomalies:         anomalies,
+		series:            series,
+		patterns:          patterns,
+		churn:             churn,
 		rateLimiter:       rateLimiter,
 		reporter:          reporter,
 		batchSizeTraces:   batchSizeTraces,
@@ -82,6 +112,17 @@ func NewWorkerPool(
 		batchSizeLogs:     batchSizeLogs,
 	}
 
+	// Only set when present, so the nil check in Run still sees a nil sink.
+	if metricsExporter != nil {
+		pool.metricsExporter = metricsExporter
+	}
+	if templates.Metrics != nil {
+		pool.metricPages = metricBatches(templates.Metrics, batchSizeMetrics, maxBatchBytes)
+	}
+	if templates.Logs != nil {
+		pool.logPages = logBatches(templates.Logs, batchSizeLogs, maxBatchBytes)
+	}
+
 	// Only traces use deferred emission, so the scheduler needs a trace exporter.
 	if traceExporter != nil {
 		pool.scheduler = newDeferredScheduler(traceExporter, rateLimiter, reporter, deferredOpts.MaxPending, deferredOpts.DrainTimeout)
@@ -399,23 +440,37 @@ func (p *WorkerPool) sendTraces(ctx context.Context) error {
 
 	const maxSpansPerBatch = 10000 // Limit to prevent gRPC message size issues
 
-	totalResourceSpans := len(p.templates.Traces.ResourceSpans)
+	traces := traceGroups(p.templates.Traces.ResourceSpans)
 	currentBatch := make([]*otlptrace.ResourceSpans, 0, p.batchSizeTraces)
+	currentTraces := 0
 	currentSpanCount := 0
 
 	flush := func() error {
 		if len(currentBatch) == 0 {
 			return nil
 		}
-		if err := p.sendRawTraceBatch(ctx, currentBatch); err != nil {
+		if delay := p.anomalies.BatchDelayMs(); delay > 0 && p.scheduler != nil {
+			// Hold the batch back so later batches overtake it.
+			p.enqueueDeferred(deferredReq{
+				delayMs:   delay,
+				req:       &otlpcollectortrace.ExportTraceServiceRequest{ResourceSpans: slices.Clone(currentBatch)},
+				spanCount: currentSpanCount,
+			})
+			p.reporter.RecordOutOfOrderBatch()
+		} else if err := p.sendRawTraceBatch(ctx, currentBatch); err != nil {
 			return err
 		}
 		currentBatch = currentBatch[:0]
+		currentTraces = 0
 		currentSpanCount = 0
 		return nil
 	}
 
-	for i := 0; i < totalResourceSpans; i++ {
+	// One replay per pass, so links between traces resolve to the new IDs.
+	replay := p.idRegenerator.NewReplay()
+	attrs := p.cardinality.NewPass()
+
+	for i, trace := range traces {
 		// Check context periodically
 		if i%100 == 0 {
 			select {
@@ -427,7 +482,7 @@ func (p *WorkerPool) sendTraces(ctx context.Context) error {
 
 		// Transform the whole trace once, then partition into immediate and
 		// deferred (late) spans that share the regenerated trace ID.
-		immediate, deferred, immSpanCount := p.transformTrace(p.templates.Traces.ResourceSpans[i])
+		immediate, deferred, immSpanCount := p.transformTrace(trace, replay, attrs)
 
 		// Schedule any late spans (e.g. a delayed root) for later export.
 		for _, d := range deferred {
@@ -445,21 +500,24 @@ func (p *WorkerPool) sendTraces(ctx context.Context) error {
 			if err := flush(); err != nil {
 				return err
 			}
-			if err := p.sendLargeImmediate(ctx, immediate, maxSpansPerBatch); err != nil {
-				return err
+			for _, rs := range immediate {
+				if err := p.sendLargeImmediate(ctx, rs, maxSpansPerBatch); err != nil {
+					return err
+				}
 			}
 			continue
 		}
 
 		wouldExceedSpanLimit := currentSpanCount+immSpanCount > maxSpansPerBatch
-		wouldExceedTraceLimit := len(currentBatch) >= p.batchSizeTraces
+		wouldExceedTraceLimit := currentTraces >= p.batchSizeTraces
 		if len(currentBatch) > 0 && (wouldExceedSpanLimit || wouldExceedTraceLimit) {
 			if err := flush(); err != nil {
 				return err
 			}
 		}
 
-		currentBatch = append(currentBatch, immediate)
+		currentBatch = append(currentBatch, immediate...)
+		currentTraces++
 		currentSpanCount += immSpanCount
 	}
 
@@ -467,23 +525,65 @@ func (p *WorkerPool) sendTraces(ctx context.Context) error {
 	return flush()
 }
 
-// transformTrace clones a single trace's ResourceSpans, regenerates its IDs and
+// traceGroups splits template ResourceSpans into traces. The generator
+// writes a trace either as one ResourceSpans or, with per-service resources,
+// as consecutive ResourceSpans sharing the trace ID, so a trace is a run of
+// ResourceSpans whose spans carry the same template trace ID.
+func traceGroups(resourceSpans []*otlptrace.ResourceSpans) [][]*otlptrace.ResourceSpans {
+	var groups [][]*otlptrace.ResourceSpans
+	var prevID []byte
+	for _, rs := range resourceSpans {
+		id := firstTraceID(rs)
+		if len(groups) > 0 && id != nil && bytes.Equal(id, prevID) {
+			groups[len(groups)-1] = append(groups[len(groups)-1], rs)
+			continue
+		}
+		groups = append(groups, []*otlptrace.ResourceSpans{rs})
+		prevID = id
+	}
+	return groups
+}
+
+// TraceCount returns the number of traces in the template ResourceSpans.
+func TraceCount(resourceSpans []*otlptrace.ResourceSpans) int {
+	return len(traceGroups(resourceSpans))
+}
+
+// firstTraceID returns the trace ID of the first span in rs, or nil.
+func firstTraceID(rs *otlptrace.ResourceSpans) []byte {
+	for _, ss := range rs.ScopeSpans {
+		if len(ss.Spans) > 0 {
+			return ss.Spans[0].TraceId
+		}
+	}
+	return nil
+}
+
+// transformTrace clones a single trace's ResourceSpans, regenerates its IDs,
+// rewrites attributes for send-time cardinality (attrs may be nil) and
 // injects timestamps over ALL of its spans at once (so the trace ID and span
-// IDs stay consistent), then partitions the result into an immediate
-// ResourceSpans and zero or more deferred payloads keyed by emit delay.
+// IDs stay consistent, even across per-service resources), then partitions the
+// result into immediate ResourceSpans and zero or more deferred payloads keyed
+// by emit delay.
 //
-// Note: the generator writes each trace as one ResourceSpans (usually with a
-// single ScopeSpans); this function does not split a trace's spans across the
+// Note: this function does not split a trace's spans across the
 // ID-regeneration pass, which is what keeps a phantom/late root correct.
-func (p *WorkerPool) transformTrace(rs *otlptrace.ResourceSpans) (immediate *otlptrace.ResourceSpans, deferred []deferredReq, immSpanCount int) {
-	clone := cloneTraceBatch([]*otlptrace.ResourceSpans{rs}).ResourceSpans[0]
+func (p *WorkerPool) transformTrace(trace []*otlptrace.ResourceSpans, replay *transformer.Replay, attrs *transformer.CardinalityPass) (immediate []*otlptrace.ResourceSpans, deferred []deferredReq, immSpanCount int) {
+	clones := cloneTraceBatch(trace).ResourceSpans
 
 	// One-shot ID regeneration across every span in the trace.
 	var allSpans []*otlptrace.Span
-	for _, ss := range clone.ScopeSpans {
-		allSpans = append(allSpans, ss.Spans...)
+	var services []string // only needed for anomalies
+	for _, rs := range clones {
+		for _, ss := range rs.ScopeSpans {
+			allSpans = append(allSpans, ss.Spans...)
+			if p.anomalies != nil {
+				services = append(services, spanServices(rs, ss)...)
+			}
+		}
 	}
-	p.idRegenerator.RegenerateTraceIDs(allSpans)
+	replay.RegenerateTraceIDs(allSpans)
+	attrs.RewriteSpans(allSpans)
 
 	// Capture emit delays BEFORE timestamp injection strips the template attr.
 	delays := make(map[*otlptrace.Span]int64)
@@ -493,54 +593,99 @@ func (p *WorkerPool) transformTrace(rs *otlptrace.ResourceSpans) (immediate *otl
 		}
 	}
 	p.timestampInjector.InjectSpanTimestamps(allSpans)
-
-	immediate = &otlptrace.ResourceSpans{
-		Resource:  clone.Resource,
-		SchemaUrl: clone.SchemaUrl,
+	if p.anomalies != nil {
+		counts := p.anomalies.Apply(allSpans, services)
+		p.reporter.RecordTimestampAnomalies(counts.SkewedSpans, counts.ChildBeforeParent, counts.FutureTraces)
 	}
 
-	for _, ss := range clone.ScopeSpans {
-		var immSpans []*otlptrace.Span
-		byDelay := make(map[int64][]*otlptrace.Span)
-		for _, sp := range ss.Spans {
-			if d, ok := delays[sp]; ok {
-				byDelay[d] = append(byDelay[d], sp)
-			} else {
-				immSpans = append(immSpans, sp)
+	deferredByDelay := make(map[int64]*deferredReq)
+	for _, rs := range clones {
+		imm := &otlptrace.ResourceSpans{
+			Resource:  rs.Resource,
+			SchemaUrl: rs.SchemaUrl,
+		}
+		late := make(map[int64]*otlptrace.ResourceSpans)
+
+		for _, ss := range rs.ScopeSpans {
+			var immSpans []*otlptrace.Span
+			byDelay := make(map[int64][]*otlptrace.Span)
+			for _, sp := range ss.Spans {
+				if d, ok := delays[sp]; ok {
+					byDelay[d] = append(byDelay[d], sp)
+				} else {
+					immSpans = append(immSpans, sp)
+				}
+			}
+
+			if len(immSpans) > 0 {
+				imm.ScopeSpans = append(imm.ScopeSpans, &otlptrace.ScopeSpans{
+					Scope:     ss.Scope,
+					SchemaUrl: ss.SchemaUrl,
+					Spans:     immSpans,
+				})
+				immSpanCount += len(immSpans)
+			}
+
+			for d, spans := range byDelay {
+				lrs, ok := late[d]
+				if !ok {
+					lrs = &otlptrace.ResourceSpans{Resource: rs.Resource, SchemaUrl: rs.SchemaUrl}
+					late[d] = lrs
+				}
+				lrs.ScopeSpans = append(lrs.ScopeSpans, &otlptrace.ScopeSpans{
+					Scope: ss.Scope, SchemaUrl: ss.SchemaUrl, Spans: spans,
+				})
+
+				dr, ok := deferredByDelay[d]
+				if !ok {
+					dr = &deferredReq{delayMs: d, req: &otlpcollectortrace.ExportTraceServiceRequest{}}
+					deferredByDelay[d] = dr
+				}
+				dr.spanCount += len(spans)
 			}
 		}
 
-		if len(immSpans) > 0 {
-			immediate.ScopeSpans = append(immediate.ScopeSpans, &otlptrace.ScopeSpans{
-				Scope:     ss.Scope,
-				SchemaUrl: ss.SchemaUrl,
-				Spans:     immSpans,
-			})
-			immSpanCount += len(immSpans)
-		}
-
-		for d, spans := range byDelay {
-			deferred = append(deferred, deferredReq{
-				delayMs:   d,
-				spanCount: len(spans),
-				req: &otlpcollectortrace.ExportTraceServiceRequest{
-					ResourceSpans: []*otlptrace.ResourceSpans{
-						{
-							Resource:  clone.Resource,
-							SchemaUrl: clone.SchemaUrl,
-							ScopeSpans: []*otlptrace.ScopeSpans{
-								{Scope: ss.Scope, SchemaUrl: ss.SchemaUrl, Spans: spans},
-							},
-						},
-					},
-				},
-			})
+		if len(imm.ScopeSpans) > 0 {
+			immediate = append(immediate, imm)
+		}
+		for d, lrs := range late {
+			dr := deferredByDelay[d]
+			dr.req.ResourceSpans = append(dr.req.ResourceSpans, lrs)
 		}
 	}
 
+	for _, dr := range deferredByDelay {
+		deferred = append(deferred, *dr)
+	}
+	slices.SortFunc(deferred, func(a, b deferredReq) int { return cmp.Compare(a.delayMs, b.delayMs) })
+
 	return immediate, deferred, immSpanCount
 }
 
+// spanServices returns the service.name of each span in ss: the span's own
+// attribute, or else its resource's.
+func spanServices(rs *otlptrace.ResourceSpans, ss *otlptrace.ScopeSpans) []string {
+	resourceService := stringAttribute(rs.Resource.GetAttributes(), "service.name")
+	services := make([]string, len(ss.Spans))
+	for i, span := range ss.Spans {
+		services[i] = resourceService
+		if s := stringAttribute(span.Attributes, "service.name"); s != "" {
+			services[i] = s
+		}
+	}
+	return services
+}
+
+// stringAttribute returns the string value of key, or "".
+func stringAttribute(attrs []*commonpb.KeyValue, key string) string {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value.GetStringValue()
+		}
+	}
+	return ""
+}
+
 // enqueueDeferred schedules a late payload for export at now+delay.
 func (p *WorkerPool) enqueueDeferred(d deferredReq) {
 	if p.scheduler == nil {
@@ -616,21 +761,43 @@ func (p *WorkerPool) sendRawTraceBatch(ctx context.Context, batchResourceSpans [
 	return nil
 }
 
-// sendMetrics sends a batch of metrics
+// sendMetrics pages through every metric series once, in batches of up to
+// batchSizeMetrics data points and maxBatchBytes.
 func (p *WorkerPool) sendMetrics(ctx context.Context) error {
-	// Deep copy the request
-	request := cloneMetricsRequest(p.templates.Metrics)
+	for _, page := range p.metricPages {
+		if err := p.sendMetricBatch(ctx, cloneMetricBatch(page)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Transform: inject timestamps
-	dataPointCount := 0
+// sendMetricBatch transforms, rate-limits and exports one batch of metrics.
+func (p *WorkerPool) sendMetricBatch(ctx context.Context, request *otlpcollectormetrics.ExportMetricsServiceRequest) error {
+	// Transform: advance series and gauge patterns, stamp the rest, then
+	// churn series and their resources. The batch shares its data points
+	// with the template and every other worker, so each step replaces
+	// points rather than modifying them.
+	dataPointCount, newSeries, replaced := 0, 0, 0
 	for _, rm := range request.ResourceMetrics {
 		for _, sm := range rm.ScopeMetrics {
 			for _, metric := range sm.Metrics {
-				p.timestampInjector.InjectMetricTimestamps(metric)
+				now := p.timestampInjector.MetricTime()
+				template := metric.Data
+				p.series.Apply(rm.Resource, metric, now)
+				p.patterns.Apply(metric, now)
+				if metric.Data == template {
+					p.timestampInjector.InjectMetricTimestamps(metric, now)
+				}
+				n, r := p.churn.Apply(metric)
+				newSeries, replaced = newSeries+n, replaced+r
 				dataPointCount += countMetricDataPoints(metric)
 			}
 		}
+		n, r := p.churn.ApplyResource(rm)
+		newSeries, replaced = newSeries+n, replaced+r
 	}
+	p.reporter.RecordSeries(newSeries, replaced)
 
 	// Rate limit
 	if err := p.rateLimiter.Wait(ctx, dataPointCount); err != nil {
@@ -646,15 +813,25 @@ func (p *WorkerPool) sendMetrics(ctx context.Context) error {
 	return nil
 }
 
-// sendLogs sends a batch of logs
+// sendLogs pages through every log record once, in batches of up to
+// batchSizeLogs records and maxBatchBytes.
 func (p *WorkerPool) sendLogs(ctx context.Context) error {
-	// Deep copy the request
-	request := cloneLogsRequest(p.templates.Logs)
+	attrs := p.cardinality.NewPass()
+	for _, page := range p.logPages {
+		if err := p.sendLogBatch(ctx, cloneLogsRequest(page), attrs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Transform: inject timestamps
+// sendLogBatch transforms, rate-limits and exports one batch of logs.
+func (p *WorkerPool) sendLogBatch(ctx context.Context, request *otlpcollectorlogs.ExportLogsServiceRequest, attrs *transformer.CardinalityPass) error {
+	// Transform: rewrite attributes and inject timestamps
 	logCount := 0
 	for _, rl := range request.ResourceLogs {
 		for _, sl := range rl.ScopeLogs {
+			attrs.RewriteLogs(sl.LogRecords)
 			p.timestampInjector.InjectLogTimestamps(sl.LogRecords)
 			logCount += len(sl.LogRecords)
 		}
@@ -779,46 +956,6 @@ func cloneTraceRequest(src *otlpcollectortrace.ExportTraceServiceRequest) *otlpc
 	}
 }
 
-func cloneMetricsRequest(src *otlpcollectormetrics.ExportMetricsServiceRequest) *otlpcollectormetrics.ExportMetricsServiceRequest {
-	if src == nil {
-		return nil
-	}
-
-	// Deep copy resource metrics
-	resourceMetrics := make([]*otlpmetrics.ResourceMetrics, len(src.ResourceMetrics))
-	for i, rm := range src.ResourceMetrics {
-		resourceMetrics[i] = &otlpmetrics.ResourceMetrics{
-			Resource: rm.Resource, // Resource is immutable
-			ScopeMetrics: make([]*otlpmetrics.ScopeMetrics, len(rm.ScopeMetrics)),
-			SchemaUrl: rm.SchemaUrl,
-		}
-
-		for j, sm := range rm.ScopeMetrics {
-			// Deep copy metrics array
-			metrics := make([]*otlpmetrics.Metric, len(sm.Metrics))
-			for k, metric := range sm.Metrics {
-				// Deep copy each metric - the data points will be copied by value
-				metrics[k] = &otlpmetrics.Metric{
-					Name:        metric.Name,
-					Description: metric.Description,
-					Unit:        metric.Unit,
-					Data:        metric.Data, // This contains the data points
-				}
-			}
-
-			resourceMetrics[i].ScopeMetrics[j] = &otlpmetrics.ScopeMetrics{
-				Scope:     sm.Scope, // Scope is immutable
-				Metrics:   metrics,
-				SchemaUrl: sm.SchemaUrl,
-			}
-		}
-	}
-
-	return &otlpcollectormetrics.ExportMetricsServiceRequest{
-		ResourceMetrics: resourceMetrics,
-	}
-}
-
 func cloneLogsRequest(src *otlpcollectorlogs.ExportLogsServiceRequest) *otlpcollectorlogs.ExportLogsServiceRequest {
 	if src == nil {
 		return nil
@@ -873,6 +1010,10 @@ func countMetricDataPoints(metric *otlpmetrics.Metric) int {
 		return len(data.Sum.DataPoints)
 	case *otlpmetrics.Metric_Histogram:
 		return len(data.Histogram.DataPoints)
+	case *otlpmetrics.Metric_ExponentialHistogram:
+		return len(data.ExponentialHistogram.DataPoints)
+	case *otlpmetrics.Metric_Summary:
+		return len(data.Summary.DataPoints)
 	default:
 		return 0
 	}
@@ -0,0 +1,135 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/honeycomb/telemetry-gen-and-send/internal/config"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/sender/ratelimit"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/sender/stats"
+	"github.com/honeycomb/telemetry-gen-and-send/internal/sender/transformer"
+	otlpcollectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// metricsSink marshals each request, as the gRPC exporter does, and checks
+// that every point of a metric carries that send's timestamp.
+type metricsSink struct {
+	mu   sync.Mutex
+	sent int
+	errs []error
+}
+
+func (s *metricsSink) Export(_ context.Context, req *otlpcollectormetrics.ExportMetricsServiceRequest) error {
+	if _, err := proto.Marshal(req); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent++
+	for _, rm := range req.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				var times []uint64
+				switch data := m.Data.(type) {
+				case *otlpmetrics.Metric_Gauge:
+					for _, dp := range data.Gauge.DataPoints {
+						times = append(times, dp.TimeUnixNano)
+					}
+				case *otlpmetrics.Metric_Sum:
+					for _, dp := range data.Sum.DataPoints {
+						times = append(times, dp.TimeUnixNano)
+					}
+				}
+				for _, ts := range times {
+					if ts == 0 || ts != times[0] {
+						s.errs = append(s.errs, fmt.Errorf("%s: point times %v", m.Name, times))
+						break
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// TestSendMetricsConcurrentWorkers runs two metrics workers over the same
+// page, the way Run does with more than one metrics worker. The page's
+// points are shared, so sends must stamp copies; run with -race.
+func TestSendMetricsConcurrentWorkers(t *testing.T) {
+	points := func() []*otlpmetrics.NumberDataPoint {
+		dps := make([]*otlpmetrics.NumberDataPoint, 20)
+		for i := range dps {
+			dps[i] = &otlpmetrics.NumberDataPoint{
+				Attributes: []*commonpb.KeyValue{strAttr("series", fmt.Sprint(i))},
+				Value:      &otlpmetrics.NumberDataPoint_AsInt{AsInt: 10},
+			}
+		}
+		return dps
+	}
+	tmpl := &otlpcollectormetrics.ExportMetricsServiceRequest{ResourceMetrics: []*otlpmetrics.ResourceMetrics{{
+		ScopeMetrics: []*otlpmetrics.ScopeMetrics{{Metrics: []*otlpmetrics.Metric{
+			{Name: "gauge", Data: &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{DataPoints: points()}}},
+			{Name: "cumulative", Data: &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+				AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+				DataPoints:             points(),
+			}}},
+			{Name: "delta", Data: &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+				AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+				IsMonotonic:            true,
+				DataPoints:             points(),
+			}}},
+		}}},
+	}}}
+
+	sink := &metricsSink{}
+	p := &WorkerPool{
+		metricsExporter:   sink,
+		timestampInjector: transformer.NewTimestampInjector(5, 0),
+		series:            transformer.NewSeriesTracker(config.CumulativeConfig{Enabled: true, Rate: 1}),
+		rateLimiter:       ratelimit.NewLimiter(0),
+		reporter:          stats.NewReporter(),
+		metricPages:       metricBatches(tmpl, 0, maxBatchBytes),
+	}
+	if len(p.metricPages) != 1 {
+		t.Fatalf("%d pages, want 1", len(p.metricPages))
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if err := p.sendMetrics(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if sink.sent != 100 {
+		t.Errorf("sent %d requests, want 100", sink.sent)
+	}
+	for _, err := range sink.errs {
+		t.Error(err)
+	}
+	for _, m := range tmpl.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		var dp *otlpmetrics.NumberDataPoint
+		if g := m.GetGauge(); g != nil {
+			dp = g.DataPoints[0]
+		} else {
+			dp = m.GetSum().DataPoints[0]
+		}
+		if dp.TimeUnixNano != 0 || dp.StartTimeUnixNano != 0 || dp.GetAsInt() != 10 {
+			t.Errorf("%s template point modified: %v", m.Name, dp)
+		}
+	}
+}
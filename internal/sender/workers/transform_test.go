@@ -12,6 +12,7 @@ import (
 	"github.com/honeycomb/telemetry-gen-and-send/internal/sender/transformer"
 	otlpcollectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	otlpmetrics "go.opentelemetry.io/proto/otlp/metrics/v1"
 	otlptrace "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
@@ -75,7 +76,7 @@ func TestTransformTraceLateRoot(t *testing.T) {
 	c2 := tmplSpan([]byte("child002"), []byte("root0001"), 200, 500_000, 0)
 	rs := oneTraceRS(root, c1, c2)
 
-	immediate, deferred, immCount := p.transformTrace(rs)
+	immediate, deferred, immCount := p.transformTrace([]*otlptrace.ResourceSpans{rs}, p.idRegenerator.NewReplay(), nil)
 
 	if immCount != 2 {
 		t.Fatalf("immediate span count = %d, want 2", immCount)
@@ -92,7 +93,7 @@ func TestTransformTraceLateRoot(t *testing.T) {
 
 	// Collect all emitted spans (immediate + deferred) and check a single trace ID.
 	var all []*otlptrace.Span
-	for _, ss := range immediate.ScopeSpans {
+	for _, ss := range immediate[0].ScopeSpans {
 		all = append(all, ss.Spans...)
 	}
 	for _, ss := range deferred[0].req.ResourceSpans[0].ScopeSpans {
@@ -126,7 +127,7 @@ func TestTransformTracePhantomParentPreserved(t *testing.T) {
 	c1 := tmplSpan([]byte("child001"), []byte("root0001"), 100, 500_000, 0)
 	rs := oneTraceRS(root, c1)
 
-	immediate, deferred, immCount := p.transformTrace(rs)
+	immediate, deferred, immCount := p.transformTrace([]*otlptrace.ResourceSpans{rs}, p.idRegenerator.NewReplay(), nil)
 	if len(deferred) != 0 {
 		t.Fatalf("deferred payloads = %d, want 0", len(deferred))
 	}
@@ -137,7 +138,7 @@ func TestTransformTracePhantomParentPreserved(t *testing.T) {
 	// Find the root (the span whose parent is not any emitted span's ID).
 	emitted := map[string]bool{}
 	var spans []*otlptrace.Span
-	for _, ss := range immediate.ScopeSpans {
+	for _, ss := range immediate[0].ScopeSpans {
 		spans = append(spans, ss.Spans...)
 	}
 	for _, s := range spans {
@@ -247,3 +248,60 @@ func TestDeferredSchedulerMaxPending(t *testing.T) {
 		t.Errorf("dropped spans = %d, want 3", got)
 	}
 }
+
+// TestTransformTraceAcrossResources verifies a trace written as per-service
+// ResourceSpans is grouped and transformed as one: one new trace ID, parents
+// remapped across resources and every resource kept.
+func TestTransformTraceAcrossResources(t *testing.T) {
+	p := newTestPool()
+
+	root := tmplSpan([]byte("root0001"), nil, 0, 1_000_000, 0)
+	child := tmplSpan([]byte("child001"), []byte("root0001"), 100, 500_000, 0)
+	other := tmplSpan([]byte("root0002"), nil, 0, 1_000_000, 0)
+	api, db := oneTraceRS(root), oneTraceRS(child)
+	next := &otlptrace.ResourceSpans{ScopeSpans: []*otlptrace.ScopeSpans{{Spans: []*otlptrace.Span{other}}}}
+	other.TraceId = []byte("other--trace-id!")
+
+	groups := traceGroups([]*otlptrace.ResourceSpans{api, db, next})
+	if len(groups) != 2 || len(groups[0]) != 2 {
+		t.Fatalf("trace groups = %d (first has %d resources), want 2 (2)", len(groups), len(groups[0]))
+	}
+
+	immediate, _, immCount := p.transformTrace(groups[0], p.idRegenerator.NewReplay(), nil)
+	if len(immediate) != 2 || immCount != 2 {
+		t.Fatalf("immediate resources = %d, spans = %d, want 2, 2", len(immediate), immCount)
+	}
+	newRoot, newChild := immediate[0].ScopeSpans[0].Spans[0], immediate[1].ScopeSpans[0].Spans[0]
+	if !bytes.Equal(newRoot.TraceId, newChild.TraceId) {
+		t.Errorf("resources got different trace IDs %x and %x", newRoot.TraceId, newChild.TraceId)
+	}
+	if !bytes.Equal(newChild.ParentSpanId, newRoot.SpanId) {
+		t.Errorf("child parent %x, want root %x", newChild.ParentSpanId, newRoot.SpanId)
+	}
+	if newChild.StartTimeUnixNano != newRoot.StartTimeUnixNano+100 {
+		t.Errorf("child starts %dns after root, want 100", newChild.StartTimeUnixNano-newRoot.StartTimeUnixNano)
+	}
+}
+
+func TestCountMetricDataPoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric *otlpmetrics.Metric
+		want   int
+	}{
+		{"gauge", &otlpmetrics.Metric{Data: &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{
+			DataPoints: make([]*otlpmetrics.NumberDataPoint, 2),
+		}}}, 2},
+		{"exponential histogram", &otlpmetrics.Metric{Data: &otlpmetrics.Metric_ExponentialHistogram{ExponentialHistogram: &otlpmetrics.ExponentialHistogram{
+			DataPoints: make([]*otlpmetrics.ExponentialHistogramDataPoint, 3),
+		}}}, 3},
+		{"summary", &otlpmetrics.Metric{Data: &otlpmetrics.Metric_Summary{Summary: &otlpmetrics.Summary{
+			DataPoints: make([]*otlpmetrics.SummaryDataPoint, 4),
+		}}}, 4},
+	}
+	for _, tt := range tests {
+		if got := countMetricDataPoints(tt.metric); got != tt.want {
+			t.Errorf("%s: countMetricDataPoints = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}